@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"brainbolt/internal/repository"
+	"brainbolt/internal/service"
+)
+
+// usage lists the CLI subcommands; with no arguments brainbolt runs the HTTP server.
+const usage = `Usage: brainbolt [command] [flags]
+
+Commands:
+  serve                   run the HTTP API (default)
+  calibrate               report question difficulty calibration (dry run unless -apply)
+  leaderboard rebuild     refill the Redis leaderboards from MySQL
+  leaderboard reconcile   report and repair Redis/MySQL leaderboard drift (-dry-run to only report)
+  questions import FILE   add questions from a file ("-" for stdin), all or nothing
+  questions export        write the question bank to stdout (-o FILE)
+                          formats: json, csv, opentdb, gift, moodlexml (-format)
+  questions served        show a question as served with a shuffle seed (-id, -seed)
+
+Run "brainbolt <command> -h" for command flags.
+`
+
+// runCommand executes a CLI subcommand and returns the process exit code.
+func runCommand(svc *services, args []string) int {
+	switch args[0] {
+	case "calibrate":
+		return runCalibrate(svc, args[1:])
+	case "leaderboard":
+		return runLeaderboard(svc, args[1:])
+	case "questions":
+		return runQuestions(svc, args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], usage)
+	return 2
+}
+
+// runCalibrate implements "brainbolt calibrate": prints the calibration report as JSON.
+func runCalibrate(svc *services, args []string) int {
+	opts := service.DefaultCalibrationOptions()
+	fs := flag.NewFlagSet("calibrate", flag.ContinueOnError)
+	fs.IntVar(&opts.MinAnswers, "min-answers", opts.MinAnswers, "minimum answers before a question can be flagged")
+	fs.Float64Var(&opts.Tolerance, "tolerance", opts.Tolerance, "allowed gap between observed and expected p-value")
+	fs.Float64Var(&opts.MinDiscrimination, "min-discrimination", opts.MinDiscrimination, "flag questions whose discrimination is below this")
+	fs.BoolVar(&opts.Apply, "apply", false, "re-bucket mis-labelled questions (default is a dry run)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	report, err := svc.calibration.Run(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "calibration failed: %v\n", err)
+		return 1
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write report: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "%d questions analysed, %d flagged, %d proposed re-buckets, %d applied\n",
+		len(report.Questions), report.Flagged, report.Proposed, report.Rebucketed)
+	return 0
+}
+
+// runLeaderboard implements "brainbolt leaderboard rebuild|reconcile": prints the result as JSON.
+func runLeaderboard(svc *services, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "leaderboard: missing subcommand (rebuild or reconcile)\n\n%s", usage)
+		return 2
+	}
+	var result interface{}
+	var err error
+	switch args[0] {
+	case "rebuild":
+		result, err = svc.leaderboard.Rebuild()
+	case "reconcile":
+		fs := flag.NewFlagSet("leaderboard reconcile", flag.ContinueOnError)
+		dryRun := fs.Bool("dry-run", false, "only report drift, do not repair Redis")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		var report *service.ReconcileReport
+		report, err = svc.leaderboard.Reconcile(!*dryRun)
+		if err == nil {
+			fmt.Fprintf(os.Stderr, "%d users checked, %d discrepancies\n", report.Users, report.Drift())
+		}
+		result = report
+	default:
+		fmt.Fprintf(os.Stderr, "unknown leaderboard subcommand %q\n\n%s", args[0], usage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "leaderboard %s failed: %v\n", args[0], err)
+		return 1
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write result: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runQuestions implements "brainbolt questions import|export".
+func runQuestions(svc *services, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "questions: missing subcommand (import, export or served)\n\n%s", usage)
+		return 2
+	}
+	switch args[0] {
+	case "import":
+		return runQuestionsImport(svc, args[1:])
+	case "export":
+		return runQuestionsExport(svc, args[1:])
+	case "served":
+		return runQuestionsServed(svc, args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "unknown questions subcommand %q\n\n%s", args[0], usage)
+	return 2
+}
+
+// importFormats picks the import format from the file extension when -format is not given.
+var importFormats = map[string]string{
+	".csv":  service.QuestionFormatCSV,
+	".gift": service.QuestionFormatGIFT,
+	".txt":  service.QuestionFormatGIFT,
+	".xml":  service.QuestionFormatMoodleXML,
+}
+
+// runQuestionsImport implements "brainbolt questions import": prints the import report as JSON
+// and fails if any question was invalid (nothing is imported then).
+func runQuestionsImport(svc *services, args []string) int {
+	fs := flag.NewFlagSet("questions import", flag.ContinueOnError)
+	format := fs.String("format", "", "json, csv, opentdb, gift or moodlexml (default: from the extension: .csv, .gift/.txt, .xml, else json)")
+	dryRun := fs.Bool("dry-run", false, "only validate and report, import nothing")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "questions import: expected one file argument\n")
+		return 2
+	}
+	path := fs.Arg(0)
+	if *format == "" {
+		*format = service.QuestionFormatJSON
+		if f, ok := importFormats[strings.ToLower(filepath.Ext(path))]; ok {
+			*format = f
+		}
+	}
+
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read %s: %v\n", path, err)
+		return 1
+	}
+
+	report, err := svc.questions.ImportQuestions(*format, data, *dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "questions import failed: %v\n", err)
+		return 1
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write report: %v\n", err)
+		return 1
+	}
+	for _, issue := range report.Skipped {
+		fmt.Fprintf(os.Stderr, "%s:%d: skipped: %s\n", path, issue.Line, issue.Message)
+	}
+	for _, issue := range report.Warnings {
+		fmt.Fprintf(os.Stderr, "%s:%d: warning: %s\n", path, issue.Line, issue.Message)
+	}
+	for _, issue := range report.Errors {
+		fmt.Fprintf(os.Stderr, "%s:%d: %s\n", path, issue.Line, issue.Message)
+	}
+	fmt.Fprintf(os.Stderr, "%d questions read, %d new, %d duplicates, %d skipped, %d errors, %d imported\n",
+		report.Total, report.New, len(report.Duplicates), len(report.Skipped), len(report.Errors), report.Imported)
+	if len(report.Errors) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runQuestionsExport implements "brainbolt questions export".
+func runQuestionsExport(svc *services, args []string) int {
+	fs := flag.NewFlagSet("questions export", flag.ContinueOnError)
+	format := fs.String("format", service.QuestionFormatJSON, "json, csv, opentdb, gift or moodlexml")
+	out := fs.String("o", "", "write to this file instead of stdout")
+	var filter repository.QuestionListFilter
+	fs.StringVar(&filter.Category, "category", "", "only export this category")
+	fs.IntVar(&filter.Difficulty, "difficulty", 0, "only export this difficulty level")
+	fs.BoolVar(&filter.IncludeRetired, "include-retired", false, "also export retired questions")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to create %s: %v\n", *out, err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	n, skipped, err := svc.questions.ExportQuestions(*format, w, filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "questions export failed: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "%d questions exported\n", n)
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "%d questions left out: %s cannot hold their type\n", skipped, *format)
+	}
+	return 0
+}
+
+// runQuestionsServed implements "brainbolt questions served": prints a question with its options
+// and answer letters in the order a shuffle seed (from question_issues or user_answers) served
+// them, as JSON.
+func runQuestionsServed(svc *services, args []string) int {
+	fs := flag.NewFlagSet("questions served", flag.ContinueOnError)
+	id := fs.Int("id", 0, "question id")
+	seed := fs.Int64("seed", 0, "shuffle seed of the serve (0 = stored order)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *id <= 0 {
+		fmt.Fprintln(os.Stderr, "questions served: -id is required")
+		return 2
+	}
+
+	q, err := svc.questions.GetServedQuestion(*id, *seed)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "questions served failed: %v\n", err)
+		return 1
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(q); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write question: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -3,11 +3,13 @@ package main
 import (
 	"fmt"
 	"log"
+	"os"
 	"time"
+	_ "time/tzdata" // LEADERBOARD_TIMEZONE must resolve in the alpine image, which ships no zoneinfo
 
+	"brainbolt/internal/config"
 	"brainbolt/internal/database"
 	"brainbolt/internal/handlers"
-	"brainbolt/internal/repository"
 	"brainbolt/internal/service"
 
 	"github.com/gofiber/fiber/v2"
@@ -17,22 +19,135 @@ import (
 )
 
 func main() {
-	// 1. Initialize our external connections
+	if len(os.Args) > 1 && (os.Args[1] == "-h" || os.Args[1] == "--help" || os.Args[1] == "help") {
+		fmt.Print(usage)
+		return
+	}
+
+	// 1. Load configuration and initialize our external connections
+	cfg := config.Load()
 	database.InitDatabases()
 
-	// 2. Initialize repos, services, and handlers
-	userRepo := repository.NewUserRepository(database.DB)
-	questionRepo := repository.NewQuestionRepository(database.DB)
-	leaderboardRepo := repository.NewLeaderboardRepository(database.RedisClient)
-	lastAnswerRepo := repository.NewLastAnswerRepository(database.RedisClient)
-	userCacheRepo := repository.NewUserCacheRepository(database.RedisClient)
+	// 2. Initialize repos and services
+	svc := newServices(cfg)
+
+	if len(os.Args) > 1 && os.Args[1] != "serve" {
+		os.Exit(runCommand(svc, os.Args[1:]))
+	}
+	serve(cfg, svc)
+}
+
+// serve runs the background jobs and the HTTP API until the process exits.
+func serve(cfg *config.Config, svc *services) {
+	quizHandlers := handlers.NewQuizHandlers(svc.user, svc.question, svc.answer, svc.leaderboard)
+	userHandlers := handlers.NewUserHandlers(svc.user, svc.answer, svc.rankHistory)
+	adminHandlers := handlers.NewAdminHandlers(svc.calibration)
+	seasonHandlers := handlers.NewSeasonHandlers(svc.season)
+	streamHandlers := handlers.NewStreamHandlers(svc.stream)
+	questionHandlers := handlers.NewQuestionHandlers(svc.questions)
+
+	// 3. Background maintenance: drop expired question-token ledger rows hourly
+	go func() {
+		for range time.Tick(time.Hour) {
+			if n, err := svc.question.PurgeExpiredIssues(); err != nil {
+				log.Printf("Failed to purge expired question issues: %v", err)
+			} else if n > 0 {
+				log.Printf("Purged %d expired question issues", n)
+			}
+		}
+	}()
+
+	// 3.1 Optional periodic calibration (dry run unless CALIBRATION_AUTO_APPLY is set)
+	if cfg.CalibrationInterval > 0 {
+		go func() {
+			for range time.Tick(cfg.CalibrationInterval) {
+				opts := service.DefaultCalibrationOptions()
+				opts.Apply = cfg.CalibrationAutoApply
+				report, err := svc.calibration.Run(opts)
+				if err != nil {
+					log.Printf("Calibration job failed: %v", err)
+					continue
+				}
+				log.Printf("Calibration job: %d questions analysed, %d flagged, %d proposed re-buckets, %d applied",
+					len(report.Questions), report.Flagged, report.Proposed, report.Rebucketed)
+			}
+		}()
+	}
 
-	userService := service.NewUserService(userRepo, userCacheRepo)
-	questionService := service.NewQuestionService(questionRepo, userRepo, userService)
-	answerService := service.NewAnswerService(userService, questionRepo, lastAnswerRepo, userRepo, leaderboardRepo, userCacheRepo)
-	leaderboardService := service.NewLeaderboardService(userRepo, leaderboardRepo)
+	// 3.2 Leaderboards: refill Redis if it came up empty, then reconcile it with MySQL periodically
+	if cfg.LeaderboardWarmUp {
+		go func() {
+			rebuilt, result, err := svc.leaderboard.WarmUp()
+			if err != nil {
+				log.Printf("Leaderboard warm-up failed: %v", err)
+			} else if rebuilt {
+				log.Printf("Leaderboard warm-up: rebuilt boards for %d users", result.Users)
+			}
+		}()
+	}
+	if cfg.LeaderboardReconcileInterval > 0 {
+		go func() {
+			for range time.Tick(cfg.LeaderboardReconcileInterval) {
+				report, err := svc.leaderboard.Reconcile(true)
+				if err != nil {
+					log.Printf("Leaderboard reconcile failed: %v", err)
+					continue
+				}
+				if report.Drift() > 0 {
+					log.Printf("Leaderboard reconcile: repaired drift for %d users (score missing %d, mismatched %d; streak missing %d, mismatched %d; "+
+						"accuracy missing %d, mismatched %d; current streak missing %d, mismatched %d; difficulty missing %d, mismatched %d; extra %d)",
+						report.Users, report.ScoreMissing, report.ScoreMismatched, report.StreakMissing, report.StreakMismatched,
+						report.AccuracyMissing, report.AccuracyMismatched, report.CurrentStreakMissing, report.CurrentStreakMismatched,
+						report.DifficultyMissing, report.DifficultyMismatched, report.Extra)
+				}
+			}
+		}()
+	}
 
-	quizHandlers := handlers.NewQuizHandlers(userService, questionService, answerService, leaderboardService)
+	// 3.3 Seasons: archive ended seasons and start the next one as soon as the boundary passes
+	go func() {
+		for ; ; time.Sleep(time.Minute) {
+			result, err := svc.season.Rollover(time.Now())
+			if err != nil {
+				log.Printf("Season rollover failed: %v", err)
+				continue
+			}
+			for _, season := range result.Archived {
+				log.Printf("Season rollover: archived season %d (%s)", season.ID, season.Name)
+			}
+			if result.Created != nil {
+				log.Printf("Season rollover: started season %d (%s)", result.Created.ID, result.Created.Name)
+			}
+		}
+	}()
+
+	// 3.4 Current streak board: decay the streaks of players who stopped answering, hourly
+	go func() {
+		for range time.Tick(time.Hour) {
+			if n, err := svc.user.DecayStreaks(); err != nil {
+				log.Printf("Streak decay failed: %v", err)
+			} else if n > 0 {
+				log.Printf("Decayed the streaks of %d users", n)
+			}
+		}
+	}()
+
+	// 3.5 Live leaderboard streams: refresh subscribers when any instance publishes an update
+	go svc.stream.Run()
+
+	// 3.6 Rank history: snapshot every user's score and ranks, dropping snapshots past retention
+	if cfg.RankSnapshotInterval > 0 {
+		go func() {
+			for range time.Tick(cfg.RankSnapshotInterval) {
+				taken, purged, err := svc.rankHistory.Snapshot(time.Now())
+				if err != nil {
+					log.Printf("Rank snapshot failed: %v", err)
+					continue
+				}
+				log.Printf("Rank snapshot: %d users recorded, %d old snapshots purged", taken, purged)
+			}
+		}()
+	}
 
 	// 4. Create a new Fiber instance
 	app := fiber.New(fiber.Config{
@@ -71,11 +186,40 @@ func main() {
 	api.Get("/next", quizHandlers.HandleNextQuestion)
 	api.Post("/answer", quizHandlers.HandleSubmitAnswer)
 	api.Get("/metrics", quizHandlers.HandleGetMetrics)
+	api.Get("/review", quizHandlers.HandleGetReview)
+	api.Get("/categories", quizHandlers.HandleListCategories)
 
 	leaderboard := app.Group("/v1/leaderboard")
 	leaderboard.Get("/score", quizHandlers.HandleGetScoreBoard)
 	leaderboard.Get("/streak", quizHandlers.HandleGetStreakBoard)
+	leaderboard.Get("/accuracy", quizHandlers.HandleGetAccuracyBoard)
+	leaderboard.Get("/current-streak", quizHandlers.HandleGetCurrentStreakBoard)
+	leaderboard.Get("/difficulty", quizHandlers.HandleGetDifficultyBoard)
+	leaderboard.Get("/stream", streamHandlers.HandleLeaderboardStream)
+	leaderboard.Get("/seasons", seasonHandlers.HandleListSeasons)
+	leaderboard.Get("/seasons/:id", seasonHandlers.HandleGetSeason)
+
+	users := app.Group("/v1/users")
+	users.Post("/", userHandlers.HandleCreateUser)
+	users.Get("/:id", userHandlers.HandleGetUser)
+	users.Patch("/:id", userHandlers.HandleRenameUser)
+	users.Delete("/:id", userHandlers.HandleDeleteUser)
+	users.Get("/:id/answers", userHandlers.HandleGetAnswerHistory)
+	users.Get("/:id/rank-history", userHandlers.HandleGetRankHistory)
+
+	admin := app.Group("/v1/admin", handlers.AdminAuthMiddleware(cfg.AdminToken))
+	admin.Get("/calibration", adminHandlers.HandleCalibrationReport)
+	admin.Post("/calibration/apply", adminHandlers.HandleCalibrationApply)
+	admin.Post("/seasons", seasonHandlers.HandleCreateSeason)
+	admin.Post("/seasons/rollover", seasonHandlers.HandleRollover)
+	admin.Get("/questions", questionHandlers.HandleListQuestions)
+	admin.Post("/questions", questionHandlers.HandleCreateQuestion)
+	admin.Post("/questions/import", questionHandlers.HandleImportQuestions)
+	admin.Get("/questions/export", questionHandlers.HandleExportQuestions)
+	admin.Get("/questions/:id", questionHandlers.HandleGetQuestion)
+	admin.Put("/questions/:id", questionHandlers.HandleUpdateQuestion)
+	admin.Delete("/questions/:id", questionHandlers.HandleRetireQuestion)
 
-	// 6. Start the server
+	// 7. Start the server
 	log.Fatal(app.Listen(":3001"))
 }
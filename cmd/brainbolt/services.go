@@ -0,0 +1,81 @@
+package main
+
+import (
+	"log"
+
+	"brainbolt/internal/config"
+	"brainbolt/internal/database"
+	"brainbolt/internal/repository"
+	"brainbolt/internal/service"
+)
+
+// services holds the wired-up service layer shared by the HTTP server and the CLI commands.
+type services struct {
+	user        *service.UserService
+	question    *service.QuestionService
+	answer      *service.AnswerService
+	leaderboard *service.LeaderboardService
+	questions   *service.QuestionBankService
+	calibration *service.CalibrationService
+	season      *service.SeasonService
+	stream      *service.LeaderboardStream
+	rankHistory *service.RankHistoryService
+}
+
+// newServices initializes repos and services on top of the global database connections.
+func newServices(cfg *config.Config) *services {
+	userRepo := repository.NewUserRepository(database.DB)
+	questionRepo := repository.NewQuestionRepository(database.DB)
+	leaderboardRepo := repository.NewLeaderboardRepository(database.RedisClient)
+	userCacheRepo := repository.NewUserCacheRepository(database.RedisClient)
+	questionCacheRepo := repository.NewQuestionCacheRepository(database.RedisClient)
+	answerHistoryRepo := repository.NewAnswerHistoryRepository(database.DB)
+	questionIssueRepo := repository.NewQuestionIssueRepository(database.DB)
+	memoryRepo := repository.NewMemoryRepository(database.DB)
+	seasonRepo := repository.NewSeasonRepository(database.DB)
+	rankHistoryRepo := repository.NewRankHistoryRepository(database.DB)
+	tokenSigner := service.NewQuestionTokenSigner(cfg.QuestionTokenSecret, cfg.QuestionTokenTTL)
+	difficulty, err := service.NewDifficultyStrategy(cfg.DifficultyStrategy, service.DifficultyConfig{
+		HysteresisUp:   cfg.HysteresisUp,
+		HysteresisDown: cfg.HysteresisDown,
+		EloK:           cfg.EloK,
+		EloQuestionK:   cfg.EloQuestionK,
+	})
+	if err != nil {
+		log.Fatalf("Invalid difficulty configuration: %v", err)
+	}
+	log.Printf("Using %s difficulty strategy", difficulty.Name())
+	scoring, err := service.NewModeScoring(cfg.ScoringPolicy, cfg.ScoringModes, service.ScoringConfig{
+		TimeBonusWindow:   cfg.TimeBonusWindow,
+		TimeBonusMaxRatio: cfg.TimeBonusMaxRatio,
+		NegativeRatio:     cfg.NegativeRatio,
+	})
+	if err != nil {
+		log.Fatalf("Invalid scoring configuration: %v", err)
+	}
+	log.Printf("Using %s scoring policy, quiz modes %v", scoring.Name(), scoring.Modes())
+
+	calendar, err := service.NewPeriodCalendar(cfg.LeaderboardTimezone, cfg.LeaderboardWeekStart)
+	if err != nil {
+		log.Fatalf("Invalid leaderboard configuration: %v", err)
+	}
+
+	leaderboardService := service.NewLeaderboardService(userRepo, leaderboardRepo, userCacheRepo, answerHistoryRepo, seasonRepo, calendar, cfg.LeaderboardAccuracyMinAnswers)
+	seasonService, err := service.NewSeasonService(seasonRepo, answerHistoryRepo, userRepo, leaderboardService, calendar, cfg.SeasonLength, cfg.SeasonRewards)
+	if err != nil {
+		log.Fatalf("Invalid season configuration: %v", err)
+	}
+	userService := service.NewUserService(userRepo, userCacheRepo, leaderboardRepo, leaderboardService)
+	questionBank := service.NewQuestionBankService(questionRepo, questionCacheRepo)
+	return &services{
+		user:        userService,
+		question:    service.NewQuestionService(questionRepo, userRepo, userService, questionIssueRepo, tokenSigner, difficulty, scoring),
+		answer:      service.NewAnswerService(userService, questionBank, questionRepo, userRepo, leaderboardRepo, leaderboardService, userCacheRepo, answerHistoryRepo, questionIssueRepo, memoryRepo, tokenSigner, difficulty, scoring),
+		leaderboard: leaderboardService,
+		questions:   questionBank,
+		calibration: service.NewCalibrationService(questionRepo, questionBank),
+		season:      seasonService,
+		rankHistory: service.NewRankHistoryService(rankHistoryRepo, userService, cfg.RankSnapshotInterval, cfg.RankSnapshotRetention),
+		stream:      service.NewLeaderboardStream(leaderboardService, cfg.LeaderboardStreamInterval, cfg.LeaderboardStreamMaxClients),
+	}
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Config holds application settings read from environment variables.
+type Config struct {
+	// QuestionTokenSecret is the HMAC key used to sign question tokens issued by /v1/quiz/next.
+	// All app instances must share it; if unset a random per-process key is generated.
+	QuestionTokenSecret []byte
+	// QuestionTokenTTL is how long a served question may be answered.
+	QuestionTokenTTL time.Duration
+
+	// DifficultyStrategy selects how player difficulty adapts: "step", "hysteresis" or "elo".
+	DifficultyStrategy string
+	// HysteresisUp / HysteresisDown are the correct / wrong answers in a row needed to change level.
+	HysteresisUp   int
+	HysteresisDown int
+	// EloK / EloQuestionK are the rating step sizes for players and questions.
+	EloK         float64
+	EloQuestionK float64
+
+	// ScoringPolicy is the default scoring policy ("standard", "timed", "negative", or "timed+negative").
+	ScoringPolicy string
+	// ScoringModes maps extra quiz modes to policies, e.g. "blitz=timed,exam=timed+negative".
+	ScoringModes string
+	// Tunables for the timed and negative-marking policies.
+	TimeBonusWindow   time.Duration
+	TimeBonusMaxRatio float64
+	NegativeRatio     float64
+
+	// Leaderboard values are capped at repository.MaxBoardValue (16,777,215); higher ones are
+	// logged and ranked as the cap. Ties are broken by time up to 2033-07-04 (see tiebreak.go).
+	//
+	// LeaderboardTimezone (IANA name) and LeaderboardWeekStart ("monday", "sunday", ...) define
+	// where the daily, weekly and monthly leaderboard windows begin.
+	LeaderboardTimezone  string
+	LeaderboardWeekStart string
+	// LeaderboardAccuracyMinAnswers is how many answers a user needs to appear on the accuracy board.
+	LeaderboardAccuracyMinAnswers int
+	// LeaderboardStreamInterval is the most often live leaderboard streams are refreshed, and
+	// LeaderboardStreamMaxClients caps concurrent streams per instance (0 = no cap).
+	LeaderboardStreamInterval   time.Duration
+	LeaderboardStreamMaxClients int
+	// LeaderboardWarmUp rebuilds the Redis leaderboards from MySQL at startup if they are empty.
+	LeaderboardWarmUp bool
+	// LeaderboardReconcileInterval runs the Redis/MySQL leaderboard reconciler periodically (0 = never).
+	LeaderboardReconcileInterval time.Duration
+	// RankSnapshotInterval snapshots every user's score and ranks periodically (0 = never), and
+	// RankSnapshotRetention is how long snapshots are kept (0 = forever).
+	RankSnapshotInterval  time.Duration
+	RankSnapshotRetention time.Duration
+	// SeasonLength is "monthly", "weekly" (seasons follow the leaderboard calendar and roll over
+	// automatically) or "manual" (seasons are only created through the admin API).
+	SeasonLength string
+	// SeasonRewards maps final score ranks to rewards, e.g. "1:gold,3:silver,10:bronze".
+	SeasonRewards string
+
+	// AdminToken authenticates /v1/admin requests; empty disables the admin API.
+	AdminToken string
+	// CalibrationInterval runs the question calibration job periodically (0 = never);
+	// it only re-buckets questions when CalibrationAutoApply is set.
+	CalibrationInterval  time.Duration
+	CalibrationAutoApply bool
+}
+
+// Load reads the configuration from the environment, falling back to defaults.
+func Load() *Config {
+	cfg := &Config{
+		QuestionTokenSecret:           []byte(getEnv("QUESTION_TOKEN_SECRET", "")),
+		QuestionTokenTTL:              getDuration("QUESTION_TOKEN_TTL", 10*time.Minute),
+		DifficultyStrategy:            getEnv("DIFFICULTY_STRATEGY", "step"),
+		HysteresisUp:                  getInt("DIFFICULTY_HYSTERESIS_UP", 3),
+		HysteresisDown:                getInt("DIFFICULTY_HYSTERESIS_DOWN", 2),
+		EloK:                          getFloat("DIFFICULTY_ELO_K", 32),
+		EloQuestionK:                  getFloat("DIFFICULTY_ELO_QUESTION_K", 8),
+		ScoringPolicy:                 getEnv("SCORING_POLICY", "standard"),
+		ScoringModes:                  getEnv("SCORING_MODES", ""),
+		TimeBonusWindow:               getDuration("SCORING_TIME_BONUS_WINDOW", 30*time.Second),
+		TimeBonusMaxRatio:             getFloat("SCORING_TIME_BONUS_MAX_RATIO", 0.5),
+		NegativeRatio:                 getFloat("SCORING_NEGATIVE_RATIO", 0.25),
+		LeaderboardTimezone:           getEnv("LEADERBOARD_TIMEZONE", "UTC"),
+		LeaderboardWeekStart:          getEnv("LEADERBOARD_WEEK_START", "monday"),
+		LeaderboardAccuracyMinAnswers: getInt("LEADERBOARD_ACCURACY_MIN_ANSWERS", 20),
+		LeaderboardStreamInterval:     getDuration("LEADERBOARD_STREAM_INTERVAL", 500*time.Millisecond),
+		LeaderboardStreamMaxClients:   getInt("LEADERBOARD_STREAM_MAX_CLIENTS", 1000),
+		LeaderboardWarmUp:             getBool("LEADERBOARD_WARMUP", true),
+		LeaderboardReconcileInterval:  getDurationAllowZero("LEADERBOARD_RECONCILE_INTERVAL", 15*time.Minute),
+		RankSnapshotInterval:          getDurationAllowZero("RANK_SNAPSHOT_INTERVAL", time.Hour),
+		RankSnapshotRetention:         getDurationAllowZero("RANK_SNAPSHOT_RETENTION", 90*24*time.Hour),
+		SeasonLength:                  getEnv("SEASON_LENGTH", "monthly"),
+		SeasonRewards:                 getEnv("SEASON_REWARDS", "1:gold,3:silver,10:bronze"),
+		AdminToken:                    getEnv("ADMIN_TOKEN", ""),
+		CalibrationInterval:           getDurationAllowZero("CALIBRATION_INTERVAL", 0),
+		CalibrationAutoApply:          getBool("CALIBRATION_AUTO_APPLY", false),
+	}
+	if len(cfg.QuestionTokenSecret) == 0 {
+		log.Println("QUESTION_TOKEN_SECRET not set; using a random key (tokens will not survive a restart or work across instances)")
+		cfg.QuestionTokenSecret = randomKey()
+	}
+	return cfg
+}
+
+// getEnv retrieves an environment variable or returns a default value
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getDuration parses a Go duration (e.g. "10m") from the environment, or returns the default
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid %s=%q, using default %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
+
+// getDurationAllowZero is getDuration for settings where 0 means never or forever; only negative
+// durations are rejected
+func getDurationAllowZero(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		log.Printf("Invalid %s=%q, using default %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
+
+// getInt parses an integer from the environment, or returns the default
+func getInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid %s=%q, using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// getFloat parses a float from the environment, or returns the default
+func getFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Invalid %s=%q, using default %v", key, value, defaultValue)
+		return defaultValue
+	}
+	return f
+}
+
+// getBool parses a boolean ("true", "1", ...) from the environment, or returns the default
+func getBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid %s=%q, using default %t", key, value, defaultValue)
+		return defaultValue
+	}
+	return b
+}
+
+func randomKey() []byte {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatalf("Failed to generate random key: %v", err)
+	}
+	return []byte(hex.EncodeToString(b))
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"brainbolt/internal/service"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminHandlers contains HTTP handlers for the authenticated /v1/admin endpoints
+type AdminHandlers struct {
+	calibrationService *service.CalibrationService
+}
+
+// NewAdminHandlers creates a new admin handlers instance
+func NewAdminHandlers(calibrationService *service.CalibrationService) *AdminHandlers {
+	return &AdminHandlers{calibrationService: calibrationService}
+}
+
+// calibrationOptionsFromQuery reads minAnswers, tolerance and minDiscrimination over the defaults
+func calibrationOptionsFromQuery(c *fiber.Ctx) service.CalibrationOptions {
+	opts := service.DefaultCalibrationOptions()
+	opts.MinAnswers = c.QueryInt("minAnswers", opts.MinAnswers)
+	opts.Tolerance = c.QueryFloat("tolerance", opts.Tolerance)
+	opts.MinDiscrimination = c.QueryFloat("minDiscrimination", opts.MinDiscrimination)
+	return opts
+}
+
+// HandleCalibrationReport handles GET /v1/admin/calibration (dry run, changes nothing)
+// Query params: minAnswers, tolerance, minDiscrimination
+func (h *AdminHandlers) HandleCalibrationReport(c *fiber.Ctx) error {
+	report, err := h.calibrationService.Run(calibrationOptionsFromQuery(c))
+	if err != nil {
+		log.Printf("Error running calibration: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to run calibration",
+			"details": err.Error(),
+		})
+	}
+	return c.JSON(report)
+}
+
+// HandleCalibrationApply handles POST /v1/admin/calibration/apply (re-buckets flagged questions)
+// Query params: minAnswers, tolerance, minDiscrimination
+func (h *AdminHandlers) HandleCalibrationApply(c *fiber.Ctx) error {
+	opts := calibrationOptionsFromQuery(c)
+	opts.Apply = true
+	report, err := h.calibrationService.Run(opts)
+	if err != nil {
+		log.Printf("Error applying calibration: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to apply calibration",
+			"details": err.Error(),
+		})
+	}
+	log.Printf("Calibration applied: %d flagged, %d questions re-bucketed", report.Flagged, report.Rebucketed)
+	return c.JSON(report)
+}
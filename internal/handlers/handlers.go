@@ -35,7 +35,9 @@ func NewQuizHandlers(
 }
 
 // HandleNextQuestion handles GET /v1/quiz/next
-// Query params: userId (required)
+// Query params: userId (required), mode (optional, default "classic"; "practice" serves questions
+// due for review first), category (optional; served at the user's level in that category), tags
+// (optional, comma-separated; any of them matches)
 func (h *QuizHandlers) HandleNextQuestion(c *fiber.Ctx) error {
 	userIDStr := c.Query("userId")
 	if userIDStr == "" {
@@ -51,7 +53,14 @@ func (h *QuizHandlers) HandleNextQuestion(c *fiber.Ctx) error {
 		})
 	}
 
-	question, currentDifficulty, err := h.questionService.GetNextQuestionForUser(userID)
+	filter, err := service.ParseQuestionFilter(c.Query("category"), c.Query("tags"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	served, err := h.questionService.GetNextQuestionForUser(userID, c.Query("mode"), filter)
 	if err != nil {
 		log.Printf("Error getting next question for userID %d: %v", userID, err)
 		if err == service.ErrUserNotFound {
@@ -60,10 +69,21 @@ func (h *QuizHandlers) HandleNextQuestion(c *fiber.Ctx) error {
 			})
 		}
 		if err == service.ErrQuestionNotFound {
+			if filter.Category != "" || len(filter.Tags) > 0 {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+					"error": "No questions found for this category and tags",
+				})
+			}
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "No questions found for this difficulty level",
 			})
 		}
+		if err == service.ErrUnknownMode {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+				"modes": h.questionService.Modes(),
+			})
+		}
 
 		errMsg := err.Error()
 		if strings.Contains(strings.ToLower(errMsg), "not found") {
@@ -78,22 +98,38 @@ func (h *QuizHandlers) HandleNextQuestion(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(fiber.Map{
+	question := served.Question
+	resp := fiber.Map{
 		"questionId":        question.ID,
+		"type":              question.Type,
 		"difficulty":        question.Difficulty,
 		"question":          question.Question,
 		"options":           question.Options,
-		"currentDifficulty": currentDifficulty,
+		"category":          question.Category,
+		"tags":              question.Tags,
+		"currentDifficulty": served.CurrentDifficulty,
+		"mode":              served.Mode,
 		"userId":            userID,
-	})
+		"questionToken":     served.Token,
+		"tokenExpiresAt":    served.ExpiresAt,
+	}
+	if served.Mode == service.PracticeMode {
+		resp["review"] = served.Review
+	}
+	return c.JSON(resp)
 }
 
+// maxAnswerLength is the longest answer accepted (free-text answers are stored as given).
+const maxAnswerLength = 255
+
 // HandleSubmitAnswer handles POST /v1/quiz/answer
+// Body: userId, questionId, answer and the questionToken from /v1/quiz/next
 func (h *QuizHandlers) HandleSubmitAnswer(c *fiber.Ctx) error {
 	var req struct {
-		UserID     int    `json:"userId"`
-		QuestionID int    `json:"questionId"`
-		Answer     string `json:"answer"`
+		UserID        int    `json:"userId"`
+		QuestionID    int    `json:"questionId"`
+		Answer        string `json:"answer"`
+		QuestionToken string `json:"questionToken"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -102,13 +138,18 @@ func (h *QuizHandlers) HandleSubmitAnswer(c *fiber.Ctx) error {
 		})
 	}
 
-	if req.UserID == 0 || req.QuestionID == 0 || req.Answer == "" {
+	if req.UserID == 0 || req.QuestionID == 0 || req.Answer == "" || req.QuestionToken == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "userId, questionId, and answer are required",
+			"error": "userId, questionId, answer, and questionToken are required",
+		})
+	}
+	if len(req.Answer) > maxAnswerLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "answer must be at most 255 characters",
 		})
 	}
 
-	isCorrect, user, err := h.answerService.SubmitAnswer(req.UserID, req.QuestionID, req.Answer)
+	result, err := h.answerService.SubmitAnswer(req.UserID, req.QuestionID, req.Answer, req.QuestionToken)
 	if err != nil {
 		if err == service.ErrDuplicateAnswer {
 			return c.SendStatus(fiber.StatusNoContent) // duplicate — ignore, no body
@@ -118,6 +159,21 @@ func (h *QuizHandlers) HandleSubmitAnswer(c *fiber.Ctx) error {
 				"error": err.Error(),
 			})
 		}
+		if err == service.ErrInvalidQuestionToken {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if err == service.ErrQuestionTokenExpired {
+			return c.Status(fiber.StatusGone).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if err == service.ErrAnswerConflict {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		log.Printf("Error submitting answer: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "Failed to submit answer",
@@ -139,13 +195,68 @@ func (h *QuizHandlers) HandleSubmitAnswer(c *fiber.Ctx) error {
 	}()
 	wg.Wait()
 
-	return c.JSON(fiber.Map{
-		"correct":               isCorrect,
+	user := result.User
+	resp := fiber.Map{
+		"correct":               result.Correct,
+		"credit":                result.Credit,
+		"correctAnswer":         result.CorrectAnswer,
+		"scoreDelta":            result.Score.Total,
+		"scoreBreakdown":        result.Score,
 		"newDifficulty":         user.CurrentDifficulty,
+		"category":              result.CategoryLevel.Category,
+		"newCategoryDifficulty": result.CategoryLevel.CurrentDifficulty,
 		"newStreak":             user.Streak,
 		"totalScore":            user.Score,
 		"leaderboardRankScore":  scoreRank,
 		"leaderboardRankStreak": streakRank,
+	}
+	if result.Explanation != "" {
+		resp["explanation"] = result.Explanation
+	}
+	if len(result.References) > 0 {
+		resp["references"] = result.References
+	}
+	if result.Memory != nil {
+		resp["nextReviewAt"] = result.Memory.DueAt
+	}
+	return c.JSON(resp)
+}
+
+// HandleGetReview handles GET /v1/quiz/review
+// Query params: userId (required), limit (default 20, max 50). Lists the user's latest wrong
+// answer to each question they missed, newest first, with the correct answer and explanation.
+func (h *QuizHandlers) HandleGetReview(c *fiber.Ctx) error {
+	userIDStr := c.Query("userId")
+	if userIDStr == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "userId query parameter is required",
+		})
+	}
+
+	userID, err := strconv.Atoi(userIDStr)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "userId must be a valid integer",
+		})
+	}
+
+	items, err := h.answerService.GetReview(userID, c.QueryInt("limit", 20))
+	if err != nil {
+		if err == service.ErrUserNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": fmt.Sprintf("User with ID %d not found", userID),
+			})
+		}
+		log.Printf("Error getting review: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to get review",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"userId":   userID,
+		"mistakes": items,
 	})
 }
 
@@ -179,6 +290,15 @@ func (h *QuizHandlers) HandleGetMetrics(c *fiber.Ctx) error {
 		})
 	}
 
+	categories, err := h.userService.GetCategoryLevels(userID)
+	if err != nil {
+		log.Printf("Error getting category levels: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to get user metrics",
+			"details": err.Error(),
+		})
+	}
+
 	// Calculate accuracy
 	accuracy := 0.0
 	if user.TotalAnswered > 0 {
@@ -193,51 +313,200 @@ func (h *QuizHandlers) HandleGetMetrics(c *fiber.Ctx) error {
 		"accuracy":          accuracy,
 		"totalCorrect":      user.TotalCorrect,
 		"totalAnswered":     user.TotalAnswered,
+		"categories":        categories,
 	})
 }
 
-// HandleGetScoreBoard handles GET /v1/leaderboard/score
-func (h *QuizHandlers) HandleGetScoreBoard(c *fiber.Ctx) error {
-	limitStr := c.Query("limit", "10")
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 10
+// HandleListCategories handles GET /v1/quiz/categories: every question category with its number
+// of questions, for the category filter of /v1/quiz/next
+func (h *QuizHandlers) HandleListCategories(c *fiber.Ctx) error {
+	categories, err := h.questionService.ListCategories()
+	if err != nil {
+		log.Printf("Error listing question categories: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to list categories",
+			"details": err.Error(),
+		})
+	}
+	return c.JSON(categories)
+}
+
+// parseLeaderboardQuery reads the shared leaderboard query params:
+// limit (default 10, max 100), period (alltime, daily, weekly, monthly or season; default alltime),
+// offset or cursor (rank of the last entry already seen) for paging, and
+// aroundUserId with radius (default 5, max 50) for the user's neighbourhood.
+func parseLeaderboardQuery(c *fiber.Ctx) (service.LeaderboardQuery, error) {
+	var q service.LeaderboardQuery
+	period, err := service.ParsePeriod(c.Query("period"))
+	if err != nil {
+		return q, err
+	}
+	q.Period = period
+
+	q.Limit, err = strconv.Atoi(c.Query("limit", "10"))
+	if err != nil || q.Limit <= 0 {
+		q.Limit = 10
 	}
-	if limit > 100 {
-		limit = 100 // Cap at 100
+	if q.Limit > 100 {
+		q.Limit = 100 // Cap at 100
+	}
+
+	for _, name := range []string{"offset", "cursor"} {
+		if v := c.Query(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return q, fmt.Errorf("%s must be a non-negative integer", name)
+			}
+			q.Offset = n
+		}
 	}
 
-	entries, err := h.leaderboardService.GetLeaderboardEntriesByScore(limit)
+	if v := c.Query("aroundUserId"); v != "" {
+		q.AroundUserID, err = strconv.Atoi(v)
+		if err != nil || q.AroundUserID <= 0 {
+			return q, fmt.Errorf("aroundUserId must be a positive integer")
+		}
+		q.Radius, err = strconv.Atoi(c.Query("radius", "5"))
+		if err != nil || q.Radius < 0 {
+			return q, fmt.Errorf("radius must be a non-negative integer")
+		}
+		if q.Radius > 50 {
+			q.Radius = 50
+		}
+	}
+	return q, nil
+}
+
+// leaderboardError writes the response for a failed leaderboard query.
+func leaderboardError(c *fiber.Ctx, q service.LeaderboardQuery, board string, err error) error {
+	if err == service.ErrUserNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": fmt.Sprintf("User with ID %d not found", q.AroundUserID),
+		})
+	}
+	if err == service.ErrPeriodNotSupported {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err == service.ErrUserNotRanked || err == service.ErrNoActiveSeason {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	log.Printf("Error getting %s leaderboard: %v", board, err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error":   "Failed to get leaderboard",
+		"details": err.Error(),
+	})
+}
+
+// setNextCursor sets X-Next-Cursor when a full page was returned, so clients can keep paging.
+func setNextCursor(c *fiber.Ctx, q service.LeaderboardQuery, count int, lastRank int64) {
+	if q.AroundUserID == 0 && count == q.Limit {
+		c.Set("X-Next-Cursor", strconv.FormatInt(lastRank, 10))
+	}
+}
+
+// HandleGetScoreBoard handles GET /v1/leaderboard/score (query params: see parseLeaderboardQuery)
+func (h *QuizHandlers) HandleGetScoreBoard(c *fiber.Ctx) error {
+	q, err := parseLeaderboardQuery(c)
 	if err != nil {
-		log.Printf("Error getting score leaderboard: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to get leaderboard",
-			"details": err.Error(),
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
 
+	entries, err := h.leaderboardService.GetLeaderboardEntriesByScore(q)
+	if err != nil {
+		return leaderboardError(c, q, "score", err)
+	}
+	if len(entries) > 0 {
+		setNextCursor(c, q, len(entries), entries[len(entries)-1].Rank)
+	}
+
 	return c.JSON(entries)
 }
 
-// HandleGetStreakBoard handles GET /v1/leaderboard/streak
+// HandleGetStreakBoard handles GET /v1/leaderboard/streak (query params: see parseLeaderboardQuery)
 func (h *QuizHandlers) HandleGetStreakBoard(c *fiber.Ctx) error {
-	limitStr := c.Query("limit", "10")
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 10
+	q, err := parseLeaderboardQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
 	}
-	if limit > 100 {
-		limit = 100 // Cap at 100
+
+	entries, err := h.leaderboardService.GetLeaderboardEntriesByStreak(q)
+	if err != nil {
+		return leaderboardError(c, q, "streak", err)
+	}
+	if len(entries) > 0 {
+		setNextCursor(c, q, len(entries), entries[len(entries)-1].Rank)
 	}
 
-	entries, err := h.leaderboardService.GetLeaderboardEntriesByStreak(limit)
+	return c.JSON(entries)
+}
+
+// HandleGetAccuracyBoard handles GET /v1/leaderboard/accuracy (query params: see parseLeaderboardQuery;
+// period must be alltime)
+func (h *QuizHandlers) HandleGetAccuracyBoard(c *fiber.Ctx) error {
+	q, err := parseLeaderboardQuery(c)
 	if err != nil {
-		log.Printf("Error getting streak leaderboard: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to get leaderboard",
-			"details": err.Error(),
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	entries, err := h.leaderboardService.GetLeaderboardEntriesByAccuracy(q)
+	if err != nil {
+		return leaderboardError(c, q, "accuracy", err)
+	}
+	if len(entries) > 0 {
+		setNextCursor(c, q, len(entries), entries[len(entries)-1].Rank)
+	}
+
+	return c.JSON(entries)
+}
+
+// HandleGetCurrentStreakBoard handles GET /v1/leaderboard/current-streak (query params: see parseLeaderboardQuery;
+// period must be alltime)
+func (h *QuizHandlers) HandleGetCurrentStreakBoard(c *fiber.Ctx) error {
+	q, err := parseLeaderboardQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	entries, err := h.leaderboardService.GetLeaderboardEntriesByCurrentStreak(q)
+	if err != nil {
+		return leaderboardError(c, q, "current streak", err)
+	}
+	if len(entries) > 0 {
+		setNextCursor(c, q, len(entries), entries[len(entries)-1].Rank)
+	}
+
+	return c.JSON(entries)
+}
+
+// HandleGetDifficultyBoard handles GET /v1/leaderboard/difficulty (query params: see parseLeaderboardQuery;
+// period must be alltime)
+func (h *QuizHandlers) HandleGetDifficultyBoard(c *fiber.Ctx) error {
+	q, err := parseLeaderboardQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
 
+	entries, err := h.leaderboardService.GetLeaderboardEntriesByDifficulty(q)
+	if err != nil {
+		return leaderboardError(c, q, "difficulty", err)
+	}
+	if len(entries) > 0 {
+		setNextCursor(c, q, len(entries), entries[len(entries)-1].Rank)
+	}
+
 	return c.JSON(entries)
 }
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -44,3 +46,25 @@ func RateLimitKeyByUser(c *fiber.Ctx) string {
 	}
 	return c.IP()
 }
+
+// AdminAuthMiddleware guards admin routes with a shared token sent as "Authorization: Bearer <token>"
+// or "X-Admin-Token: <token>". An empty configured token disables the admin API entirely.
+func AdminAuthMiddleware(token string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if token == "" {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Admin API is disabled (ADMIN_TOKEN not set)",
+			})
+		}
+		got := c.Get("X-Admin-Token")
+		if auth := c.Get(fiber.HeaderAuthorization); strings.HasPrefix(auth, "Bearer ") {
+			got = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid or missing admin token",
+			})
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"brainbolt/internal/repository"
+	"brainbolt/internal/service"
+	"bytes"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// QuestionHandlers contains HTTP handlers for the /v1/admin/questions endpoints
+type QuestionHandlers struct {
+	questionBank *service.QuestionBankService
+}
+
+// NewQuestionHandlers creates a new question handlers instance
+func NewQuestionHandlers(questionBank *service.QuestionBankService) *QuestionHandlers {
+	return &QuestionHandlers{questionBank: questionBank}
+}
+
+// parseQuestionIDParam reads the :id route param; ok is false if it is not a positive integer
+func parseQuestionIDParam(c *fiber.Ctx) (int, bool) {
+	questionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil || questionID <= 0 {
+		return 0, false
+	}
+	return questionID, true
+}
+
+// questionErrorResponse maps question bank errors onto HTTP status codes
+func questionErrorResponse(c *fiber.Ctx, questionID int, err error, action string) error {
+	if invalid, ok := err.(*service.InvalidQuestionError); ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":    "Invalid question",
+			"problems": invalid.Problems,
+		})
+	}
+	if err == service.ErrQuestionNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": fmt.Sprintf("Question with ID %d not found", questionID),
+		})
+	}
+	log.Printf("Error trying to %s: %v", action, err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error":   "Failed to " + action,
+		"details": err.Error(),
+	})
+}
+
+// HandleListQuestions handles GET /v1/admin/questions
+// Query params: category, difficulty, includeRetired (true/false), limit (default 50, max 500), offset
+func (h *QuestionHandlers) HandleListQuestions(c *fiber.Ctx) error {
+	filter := repository.QuestionListFilter{
+		Category:       strings.ToLower(c.Query("category")),
+		IncludeRetired: c.QueryBool("includeRetired"),
+	}
+	var err error
+	if v := c.Query("difficulty"); v != "" {
+		filter.Difficulty, err = strconv.Atoi(v)
+		if err != nil || filter.Difficulty < service.MinDifficulty || filter.Difficulty > service.MaxDifficulty {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "difficulty must be between 1 and 10",
+			})
+		}
+	}
+	filter.Limit, err = strconv.Atoi(c.Query("limit", "50"))
+	if err != nil || filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+	if filter.Limit > 500 {
+		filter.Limit = 500 // Cap at 500
+	}
+	filter.Offset, err = strconv.Atoi(c.Query("offset", "0"))
+	if err != nil || filter.Offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "offset must be a non-negative integer",
+		})
+	}
+
+	questions, err := h.questionBank.ListQuestions(filter)
+	if err != nil {
+		return questionErrorResponse(c, 0, err, "list questions")
+	}
+	return c.JSON(fiber.Map{"questions": questions})
+}
+
+// HandleGetQuestion handles GET /v1/admin/questions/:id (answer included)
+func (h *QuestionHandlers) HandleGetQuestion(c *fiber.Ctx) error {
+	questionID, ok := parseQuestionIDParam(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id must be a valid integer",
+		})
+	}
+	question, err := h.questionBank.GetQuestion(questionID)
+	if err != nil {
+		return questionErrorResponse(c, questionID, err, "get question")
+	}
+	return c.JSON(question)
+}
+
+// HandleCreateQuestion handles POST /v1/admin/questions
+// Body: { "difficulty": 1-10, "question": "...", "options": ["..."], "answer": "B" or option text,
+// "category": "...", "tags": ["..."] }
+func (h *QuestionHandlers) HandleCreateQuestion(c *fiber.Ctx) error {
+	var req service.QuestionInput
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	question, err := h.questionBank.CreateQuestion(req)
+	if err != nil {
+		return questionErrorResponse(c, 0, err, "create question")
+	}
+	return c.Status(fiber.StatusCreated).JSON(question)
+}
+
+// HandleUpdateQuestion handles PUT /v1/admin/questions/:id
+// Body: the full question, as for HandleCreateQuestion
+func (h *QuestionHandlers) HandleUpdateQuestion(c *fiber.Ctx) error {
+	questionID, ok := parseQuestionIDParam(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id must be a valid integer",
+		})
+	}
+	var req service.QuestionInput
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	question, err := h.questionBank.UpdateQuestion(questionID, req)
+	if err != nil {
+		return questionErrorResponse(c, questionID, err, "update question")
+	}
+	return c.JSON(question)
+}
+
+// HandleRetireQuestion handles DELETE /v1/admin/questions/:id
+// The question is retired, not deleted: it is no longer served but its answer history stays.
+func (h *QuestionHandlers) HandleRetireQuestion(c *fiber.Ctx) error {
+	questionID, ok := parseQuestionIDParam(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id must be a valid integer",
+		})
+	}
+	question, err := h.questionBank.RetireQuestion(questionID)
+	if err != nil {
+		return questionErrorResponse(c, questionID, err, "retire question")
+	}
+	return c.JSON(question)
+}
+
+// HandleImportQuestions handles POST /v1/admin/questions/import
+// Query params: format (json, csv, opentdb, gift or moodlexml; default json), dryRun (true/false). Body: the file.
+// Returns the import report; 422 (nothing imported) if any question is invalid.
+func (h *QuestionHandlers) HandleImportQuestions(c *fiber.Ctx) error {
+	format := strings.ToLower(c.Query("format", service.QuestionFormatJSON))
+	report, err := h.questionBank.ImportQuestions(format, c.Body(), c.QueryBool("dryRun"))
+	if err == service.ErrUnknownQuestionFormat {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return questionErrorResponse(c, 0, err, "import questions")
+	}
+	if len(report.Errors) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(report)
+	}
+	if report.Imported > 0 {
+		log.Printf("Imported %d questions (%s, %d duplicates and %d unsupported skipped)",
+			report.Imported, format, len(report.Duplicates), len(report.Skipped))
+	}
+	return c.JSON(report)
+}
+
+// exportContentTypes is the Content-Type and file name of each export format.
+var exportContentTypes = map[string][2]string{
+	service.QuestionFormatJSON:    {fiber.MIMEApplicationJSONCharsetUTF8, "questions.json"},
+	service.QuestionFormatCSV:     {"text/csv; charset=utf-8", "questions.csv"},
+	service.QuestionFormatOpenTDB: {fiber.MIMEApplicationJSONCharsetUTF8, "questions-opentdb.json"},
+	// Moodle only imports GIFT from .txt files.
+	service.QuestionFormatGIFT:      {fiber.MIMETextPlainCharsetUTF8, "questions-gift.txt"},
+	service.QuestionFormatMoodleXML: {fiber.MIMEApplicationXMLCharsetUTF8, "questions-moodle.xml"},
+}
+
+// HandleExportQuestions handles GET /v1/admin/questions/export
+// Query params: format (json, csv, opentdb, gift or moodlexml; default json), category, difficulty, includeRetired
+func (h *QuestionHandlers) HandleExportQuestions(c *fiber.Ctx) error {
+	format := strings.ToLower(c.Query("format", service.QuestionFormatJSON))
+	file, ok := exportContentTypes[format]
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": service.ErrUnknownQuestionFormat.Error(),
+		})
+	}
+	filter := repository.QuestionListFilter{
+		Category:       strings.ToLower(c.Query("category")),
+		Difficulty:     c.QueryInt("difficulty"),
+		IncludeRetired: c.QueryBool("includeRetired"),
+	}
+
+	var buf bytes.Buffer
+	if _, _, err := h.questionBank.ExportQuestions(format, &buf, filter); err != nil {
+		return questionErrorResponse(c, 0, err, "export questions")
+	}
+	c.Set(fiber.HeaderContentType, file[0])
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+file[1]+`"`)
+	return c.Send(buf.Bytes())
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"brainbolt/internal/service"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SeasonHandlers contains HTTP handlers for the /v1/leaderboard/seasons endpoints
+type SeasonHandlers struct {
+	seasonService *service.SeasonService
+}
+
+// NewSeasonHandlers creates a new season handlers instance
+func NewSeasonHandlers(seasonService *service.SeasonService) *SeasonHandlers {
+	return &SeasonHandlers{seasonService: seasonService}
+}
+
+// seasonErrorResponse maps season service errors onto HTTP status codes
+func seasonErrorResponse(c *fiber.Ctx, err error, action string) error {
+	switch err {
+	case service.ErrSeasonNotFound:
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case service.ErrInvalidSeason, service.ErrUnknownSeasonBoard:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case service.ErrSeasonOverlap:
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	log.Printf("Error trying to %s: %v", action, err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error":   "Failed to " + action,
+		"details": err.Error(),
+	})
+}
+
+// HandleListSeasons handles GET /v1/leaderboard/seasons (newest first)
+func (h *SeasonHandlers) HandleListSeasons(c *fiber.Ctx) error {
+	seasons, err := h.seasonService.ListSeasons()
+	if err != nil {
+		return seasonErrorResponse(c, err, "list seasons")
+	}
+	return c.JSON(fiber.Map{"seasons": seasons})
+}
+
+// HandleGetSeason handles GET /v1/leaderboard/seasons/:id
+// Query params: board (score or streak, default score), limit (default 10, max 100), offset
+func (h *SeasonHandlers) HandleGetSeason(c *fiber.Ctx) error {
+	seasonID, err := strconv.Atoi(c.Params("id"))
+	if err != nil || seasonID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "season id must be a positive integer",
+		})
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "10"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100 // Cap at 100
+	}
+	offset, err := strconv.Atoi(c.Query("offset", "0"))
+	if err != nil || offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "offset must be a non-negative integer",
+		})
+	}
+
+	standings, err := h.seasonService.GetStandings(seasonID, c.Query("board", service.SeasonBoardScore), offset, limit)
+	if err != nil {
+		return seasonErrorResponse(c, err, fmt.Sprintf("get season %d", seasonID))
+	}
+	return c.JSON(standings)
+}
+
+// HandleCreateSeason handles POST /v1/admin/seasons
+// Body: { "name": "...", "startsAt": RFC 3339, "endsAt": RFC 3339 }
+func (h *SeasonHandlers) HandleCreateSeason(c *fiber.Ctx) error {
+	var req struct {
+		Name     string    `json:"name"`
+		StartsAt time.Time `json:"startsAt"`
+		EndsAt   time.Time `json:"endsAt"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body (startsAt and endsAt must be RFC 3339 timestamps)",
+		})
+	}
+	season, err := h.seasonService.CreateSeason(req.Name, req.StartsAt, req.EndsAt)
+	if err != nil {
+		return seasonErrorResponse(c, err, "create season")
+	}
+	return c.Status(fiber.StatusCreated).JSON(season)
+}
+
+// HandleRollover handles POST /v1/admin/seasons/rollover (archives ended seasons now)
+func (h *SeasonHandlers) HandleRollover(c *fiber.Ctx) error {
+	result, err := h.seasonService.Rollover(time.Now())
+	if err != nil {
+		return seasonErrorResponse(c, err, "roll over seasons")
+	}
+	return c.JSON(result)
+}
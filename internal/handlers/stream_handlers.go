@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"brainbolt/internal/service"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// streamKeepAlive is how often an idle stream sends a comment line, so proxies keep it open and
+// disconnected clients are noticed.
+const streamKeepAlive = 15 * time.Second
+
+// StreamHandlers contains the Server-Sent Events leaderboard endpoint
+type StreamHandlers struct {
+	stream *service.LeaderboardStream
+}
+
+// NewStreamHandlers creates a new stream handlers instance
+func NewStreamHandlers(stream *service.LeaderboardStream) *StreamHandlers {
+	return &StreamHandlers{stream: stream}
+}
+
+// HandleLeaderboardStream handles GET /v1/leaderboard/stream (Server-Sent Events)
+// Query params: board (score, streak, accuracy, current-streak or difficulty; default score),
+// period (as for the leaderboards), limit (default 10, max 100), userId (optional, adds rank events)
+// Events: "top" with the board's top entries whenever they change, "rank" when userId's rank changes.
+func (h *StreamHandlers) HandleLeaderboardStream(c *fiber.Ctx) error {
+	period, err := service.ParsePeriod(c.Query("period"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	q := service.StreamQuery{Board: c.Query("board", "score"), Period: period}
+	q.Limit, err = strconv.Atoi(c.Query("limit", "10"))
+	if err != nil || q.Limit <= 0 {
+		q.Limit = 10
+	}
+	if q.Limit > 100 {
+		q.Limit = 100 // Cap at 100
+	}
+	if v := c.Query("userId"); v != "" {
+		q.UserID, err = strconv.Atoi(v)
+		if err != nil || q.UserID <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "userId must be a positive integer",
+			})
+		}
+	}
+
+	sub, err := h.stream.Subscribe(q)
+	if err != nil {
+		switch err {
+		case service.ErrUnknownBoard, service.ErrPeriodNotSupported:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		case service.ErrNoActiveSeason:
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		case service.ErrTooManyStreams:
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Printf("Error opening leaderboard stream: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to open leaderboard stream",
+			"details": err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // disable proxy buffering (nginx)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer h.stream.Unsubscribe(sub)
+		fmt.Fprint(w, "retry: 3000\n\n")
+		if err := w.Flush(); err != nil {
+			return
+		}
+		keepAlive := time.NewTicker(streamKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case event := <-sub.Events():
+				data, err := json.Marshal(event.Data)
+				if err != nil {
+					log.Printf("Leaderboard stream: failed to encode %s event: %v", event.Name, err)
+					continue
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Name, data)
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			}
+			// Flush fails once the client has gone away.
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"brainbolt/internal/repository"
+	"brainbolt/internal/service"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// UserHandlers contains HTTP handlers for the /v1/users resource
+type UserHandlers struct {
+	userService        *service.UserService
+	answerService      *service.AnswerService
+	rankHistoryService *service.RankHistoryService
+}
+
+// NewUserHandlers creates a new user handlers instance
+func NewUserHandlers(
+	userService *service.UserService,
+	answerService *service.AnswerService,
+	rankHistoryService *service.RankHistoryService,
+) *UserHandlers {
+	return &UserHandlers{
+		userService:        userService,
+		answerService:      answerService,
+		rankHistoryService: rankHistoryService,
+	}
+}
+
+// parseUserIDParam reads the :id path parameter as a positive integer
+func parseUserIDParam(c *fiber.Ctx) (int, bool) {
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil || userID <= 0 {
+		return 0, false
+	}
+	return userID, true
+}
+
+// parseTimeQuery reads an optional RFC 3339 query parameter; returns (nil, true) when absent
+func parseTimeQuery(c *fiber.Ctx, name string) (*time.Time, bool) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, false
+	}
+	return &t, true
+}
+
+// userErrorResponse maps user service errors onto HTTP status codes
+func userErrorResponse(c *fiber.Ctx, userID int, err error, action string) error {
+	switch err {
+	case service.ErrUserNotFound:
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": fmt.Sprintf("User with ID %d not found", userID),
+		})
+	case service.ErrInvalidUsername, service.ErrInvalidTimeRange:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case service.ErrUsernameTaken:
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	log.Printf("Error trying to %s: %v", action, err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error":   "Failed to " + action,
+		"details": err.Error(),
+	})
+}
+
+// HandleCreateUser handles POST /v1/users
+// Body: { "username": "..." }
+func (h *UserHandlers) HandleCreateUser(c *fiber.Ctx) error {
+	var req struct {
+		Username string `json:"username"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	user, err := h.userService.CreateUser(req.Username)
+	if err != nil {
+		return userErrorResponse(c, 0, err, "create user")
+	}
+	return c.Status(fiber.StatusCreated).JSON(user)
+}
+
+// HandleGetUser handles GET /v1/users/:id
+func (h *UserHandlers) HandleGetUser(c *fiber.Ctx) error {
+	userID, ok := parseUserIDParam(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id must be a valid integer",
+		})
+	}
+
+	user, err := h.userService.GetUserByID(userID)
+	if err != nil {
+		return userErrorResponse(c, userID, err, "get user")
+	}
+	return c.JSON(user)
+}
+
+// HandleRenameUser handles PATCH /v1/users/:id
+// Body: { "username": "..." }
+func (h *UserHandlers) HandleRenameUser(c *fiber.Ctx) error {
+	userID, ok := parseUserIDParam(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id must be a valid integer",
+		})
+	}
+
+	var req struct {
+		Username string `json:"username"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	user, err := h.userService.RenameUser(userID, req.Username)
+	if err != nil {
+		return userErrorResponse(c, userID, err, "rename user")
+	}
+	return c.JSON(user)
+}
+
+// HandleDeleteUser handles DELETE /v1/users/:id
+func (h *UserHandlers) HandleDeleteUser(c *fiber.Ctx) error {
+	userID, ok := parseUserIDParam(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id must be a valid integer",
+		})
+	}
+
+	if err := h.userService.DeleteUser(userID); err != nil {
+		return userErrorResponse(c, userID, err, "delete user")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// HandleGetAnswerHistory handles GET /v1/users/:id/answers
+// Query params: limit (default 20, max 100), cursor, correct (true|false), questionId, difficulty,
+// from and to (RFC 3339). Returns newest answers first and a nextCursor for the following page.
+func (h *UserHandlers) HandleGetAnswerHistory(c *fiber.Ctx) error {
+	userID, ok := parseUserIDParam(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id must be a valid integer",
+		})
+	}
+
+	filter := repository.AnswerHistoryFilter{
+		Limit:      c.QueryInt("limit", 20),
+		QuestionID: c.QueryInt("questionId", 0),
+		Difficulty: c.QueryInt("difficulty", 0),
+	}
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		cursor, err := strconv.ParseInt(cursorStr, 10, 64)
+		if err != nil || cursor <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "cursor must be a positive integer",
+			})
+		}
+		filter.BeforeID = cursor
+	}
+	if correctStr := c.Query("correct"); correctStr != "" {
+		correct, err := strconv.ParseBool(correctStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "correct must be true or false",
+			})
+		}
+		filter.Correct = &correct
+	}
+	var valid bool
+	if filter.From, valid = parseTimeQuery(c, "from"); !valid {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "from must be an RFC 3339 timestamp",
+		})
+	}
+	if filter.To, valid = parseTimeQuery(c, "to"); !valid {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "to must be an RFC 3339 timestamp",
+		})
+	}
+
+	records, nextCursor, err := h.answerService.GetAnswerHistory(userID, filter)
+	if err != nil {
+		return userErrorResponse(c, userID, err, "get answer history")
+	}
+
+	resp := fiber.Map{
+		"userId":  userID,
+		"answers": records,
+	}
+	if nextCursor > 0 {
+		resp["nextCursor"] = nextCursor
+	}
+	return c.JSON(resp)
+}
+
+// HandleGetRankHistory handles GET /v1/users/:id/rank-history
+// Query params: from and to (RFC 3339; default the last 30 days). Returns the user's score, score
+// rank and streak rank snapshots, oldest first (at most the latest 1000).
+func (h *UserHandlers) HandleGetRankHistory(c *fiber.Ctx) error {
+	userID, ok := parseUserIDParam(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "id must be a valid integer",
+		})
+	}
+	from, valid := parseTimeQuery(c, "from")
+	if !valid {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "from must be an RFC 3339 timestamp",
+		})
+	}
+	to, valid := parseTimeQuery(c, "to")
+	if !valid {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "to must be an RFC 3339 timestamp",
+		})
+	}
+
+	history, err := h.rankHistoryService.GetRankHistory(userID, from, to)
+	if err != nil {
+		return userErrorResponse(c, userID, err, "get rank history")
+	}
+	return c.JSON(history)
+}
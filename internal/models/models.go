@@ -2,13 +2,43 @@ package models
 
 import "time"
 
-// Question represents a quiz question
+// Question represents a quiz question. Type decides what Answer holds (see service/grading.go):
+// the letter of the correct option ("A" = Options[0]) for single-choice and true/false
+// questions, comma-separated letters ("A,C") for multi-select, a number for numeric and the
+// expected text for free-text questions, which have no options.
 type Question struct {
-	ID         int
-	Difficulty int
-	Question   string
-	Options    []string
-	Answer     string
+	ID         int      `json:"id" db:"id"`
+	Type       string   `json:"type" db:"type"`
+	Difficulty int      `json:"difficulty" db:"difficulty"`
+	Question   string   `json:"question" db:"question"`
+	Options    []string `json:"options" db:"options"`
+	Answer     string   `json:"answer" db:"answer"`
+	// Tolerance is how far a numeric answer may be from Answer and still count as correct.
+	Tolerance float64 `json:"tolerance,omitempty" db:"tolerance"`
+	// PartialCredit lets a multi-select answer earn a share of the points when partly right.
+	PartialCredit bool `json:"partialCredit,omitempty" db:"partial_credit"`
+	// Aliases are other accepted answers of a free-text question.
+	Aliases  []string `json:"aliases,omitempty" db:"aliases"`
+	Rating   float64  `json:"rating" db:"rating"` // Elo-style item rating, only moved by the elo difficulty strategy
+	Category string   `json:"category" db:"category"`
+	Tags     []string `json:"tags" db:"tags"`
+	// Explanation and References (sources, e.g. URLs) are shown once the question is answered.
+	Explanation string   `json:"explanation,omitempty" db:"explanation"`
+	References  []string `json:"references,omitempty" db:"refs"`
+	// RetiredAt is set once the question is withdrawn; it is no longer served but old answers keep pointing at it.
+	RetiredAt *time.Time `json:"retiredAt,omitempty" db:"retired_at"`
+}
+
+// CategoryLevel is a user's difficulty state within one question category; it moves like the
+// overall level on User but only with answers to questions of that category
+type CategoryLevel struct {
+	UserID             int     `json:"-" db:"user_id"`
+	Category           string  `json:"category" db:"category"`
+	CurrentDifficulty  int     `json:"currentDifficulty" db:"current_difficulty"`
+	DifficultyProgress int     `json:"difficultyProgress" db:"difficulty_progress"`
+	Rating             float64 `json:"rating" db:"rating"`
+	TotalAnswered      int     `json:"totalAnswered" db:"total_answered"`
+	TotalCorrect       int     `json:"totalCorrect" db:"total_correct"`
 }
 
 // User represents a user in the quiz system
@@ -22,4 +52,90 @@ type User struct {
 	TotalAnswered     int        `json:"totalAnswered" db:"total_answered"`
 	CurrentDifficulty int        `json:"currentDifficulty" db:"current_difficulty"`
 	LastAnsweredAt    *time.Time `json:"lastAnsweredAt,omitempty" db:"last_answered_at"`
+	Rating            float64    `json:"rating" db:"rating"`
+	// DifficultyProgress counts consecutive correct (>0) or wrong (<0) answers at the current level.
+	DifficultyProgress int `json:"difficultyProgress" db:"difficulty_progress"`
+	// ScoreReachedAt / MaxStreakReachedAt record when the current score and max streak were
+	// reached; leaderboard ties go to whoever got there first.
+	ScoreReachedAt     *time.Time `json:"scoreReachedAt,omitempty" db:"score_reached_at"`
+	MaxStreakReachedAt *time.Time `json:"maxStreakReachedAt,omitempty" db:"max_streak_reached_at"`
+	// MaxDifficulty is the highest difficulty level the user has reached, at MaxDifficultyReachedAt.
+	MaxDifficulty          int        `json:"maxDifficulty" db:"max_difficulty"`
+	MaxDifficultyReachedAt *time.Time `json:"maxDifficultyReachedAt,omitempty" db:"max_difficulty_reached_at"`
+	// StreakDecayedAt is the decay window boundary up to which Streak has already been decayed.
+	StreakDecayedAt *time.Time `json:"streakDecayedAt,omitempty" db:"streak_decayed_at"`
+}
+
+// AnswerRecord is one row of a user's answer history. Answer is in stored option letters;
+// ShuffleSeed is the option order the question was served in (0 = stored order).
+type AnswerRecord struct {
+	ID           int64     `json:"id" db:"id"`
+	UserID       int       `json:"userId" db:"user_id"`
+	QuestionID   int       `json:"questionId" db:"question_id"`
+	Answer       string    `json:"answer" db:"answer"`
+	IsCorrect    bool      `json:"correct" db:"is_correct"`
+	Mode         string    `json:"mode" db:"mode"`
+	ShuffleSeed  int64     `json:"shuffleSeed,omitempty" db:"shuffle_seed"`
+	Difficulty   int       `json:"difficulty" db:"difficulty"`
+	ScoreDelta   int64     `json:"scoreDelta" db:"score_delta"`
+	StreakBefore int       `json:"streakBefore" db:"streak_before"`
+	StreakAfter  int       `json:"streakAfter" db:"streak_after"`
+	AnsweredAt   time.Time `json:"answeredAt" db:"answered_at"`
+}
+
+// MemoryState is a user's spaced-repetition (SM-2) state for one question: how many reviews in a
+// row they got right, the current interval, the ease factor that grows it and when the question
+// is next due in practice mode
+type MemoryState struct {
+	UserID       int       `json:"-" db:"user_id"`
+	QuestionID   int       `json:"questionId" db:"question_id"`
+	Repetitions  int       `json:"repetitions" db:"repetitions"`
+	IntervalDays int       `json:"intervalDays" db:"interval_days"`
+	EaseFactor   float64   `json:"easeFactor" db:"ease_factor"`
+	Lapses       int       `json:"lapses" db:"lapses"`
+	DueAt        time.Time `json:"dueAt" db:"due_at"`
+	ReviewedAt   time.Time `json:"reviewedAt" db:"reviewed_at"`
+}
+
+// QuestionIssue records one serve of a question to a user; it can be answered at most once before ExpiresAt.
+// ShuffleSeed fixes the order the options were served in (0 = stored order).
+type QuestionIssue struct {
+	ID          int64      `json:"id" db:"id"`
+	UserID      int        `json:"userId" db:"user_id"`
+	QuestionID  int        `json:"questionId" db:"question_id"`
+	Mode        string     `json:"mode" db:"mode"`
+	ShuffleSeed int64      `json:"shuffleSeed" db:"shuffle_seed"`
+	IssuedAt    time.Time  `json:"issuedAt" db:"issued_at"`
+	ExpiresAt   time.Time  `json:"expiresAt" db:"expires_at"`
+	AnsweredAt  *time.Time `json:"answeredAt,omitempty" db:"answered_at"`
+}
+
+// Season is one leaderboard competition window [StartsAt, EndsAt). ArchivedAt is set once its
+// final standings have been written to season_standings.
+type Season struct {
+	ID         int        `json:"id" db:"id"`
+	Name       string     `json:"name" db:"name"`
+	StartsAt   time.Time  `json:"startsAt" db:"starts_at"`
+	EndsAt     time.Time  `json:"endsAt" db:"ends_at"`
+	ArchivedAt *time.Time `json:"archivedAt,omitempty" db:"archived_at"`
+}
+
+// SeasonStanding is one archived leaderboard row of a finished season; UserID is 0 once the user
+// was deleted
+type SeasonStanding struct {
+	SeasonID int    `json:"-" db:"season_id"`
+	Board    string `json:"-" db:"board"`
+	Rank     int64  `json:"rank" db:"season_rank"`
+	UserID   int    `json:"userId,omitempty" db:"user_id"`
+	Username string `json:"username" db:"username"`
+	Value    int64  `json:"value" db:"value"`
+	Reward   string `json:"reward,omitempty" db:"reward"`
+}
+
+// RankSnapshot is one point of a user's rank history, taken by the periodic snapshot job
+type RankSnapshot struct {
+	TakenAt    time.Time `json:"takenAt" db:"taken_at"`
+	Score      int64     `json:"score" db:"score"`
+	ScoreRank  int       `json:"scoreRank" db:"score_rank"`
+	StreakRank int       `json:"streakRank" db:"streak_rank"`
 }
@@ -0,0 +1,271 @@
+package repository
+
+import (
+	"brainbolt/internal/models"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AnswerHistoryFilter narrows a user's answer history. Zero values mean "no filter".
+// Results are newest first; BeforeID is the keyset cursor (only rows with id < BeforeID).
+type AnswerHistoryFilter struct {
+	BeforeID   int64
+	Limit      int
+	Correct    *bool
+	QuestionID int
+	Difficulty int
+	From       *time.Time
+	To         *time.Time
+}
+
+// PracticeMode is the quiz mode whose answers are kept in the history but do not count towards
+// the period leaderboards or question calibration.
+const PracticeMode = "practice"
+
+// rankedAnswer matches the user_answers rows that count towards leaderboards and calibration.
+const rankedAnswer = "mode <> '" + PracticeMode + "'"
+
+// AnswerHistoryRepository persists every answer submission in user_answers
+type AnswerHistoryRepository struct {
+	db *sql.DB
+}
+
+// NewAnswerHistoryRepository creates a new answer history repository
+func NewAnswerHistoryRepository(db *sql.DB) *AnswerHistoryRepository {
+	return &AnswerHistoryRepository{db: db}
+}
+
+// RecordAnswer inserts one answer submission inside tx and sets rec.ID to the generated id
+func (r *AnswerHistoryRepository) RecordAnswer(tx *sql.Tx, rec *models.AnswerRecord) error {
+	query := `INSERT INTO user_answers
+	          (user_id, question_id, answer, is_correct, mode, shuffle_seed, difficulty, score_delta, streak_before, streak_after, answered_at)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := tx.Exec(query, rec.UserID, rec.QuestionID, rec.Answer, rec.IsCorrect, rec.Mode, rec.ShuffleSeed,
+		rec.Difficulty, rec.ScoreDelta, rec.StreakBefore, rec.StreakAfter, rec.AnsweredAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	rec.ID = id
+	return nil
+}
+
+// ListAnswers returns a page of the user's answers, newest first, matching the filter
+func (r *AnswerHistoryRepository) ListAnswers(userID int, filter AnswerHistoryFilter) ([]models.AnswerRecord, error) {
+	conds := []string{"user_id = ?"}
+	args := []interface{}{userID}
+	if filter.BeforeID > 0 {
+		conds = append(conds, "id < ?")
+		args = append(args, filter.BeforeID)
+	}
+	if filter.Correct != nil {
+		conds = append(conds, "is_correct = ?")
+		args = append(args, *filter.Correct)
+	}
+	if filter.QuestionID > 0 {
+		conds = append(conds, "question_id = ?")
+		args = append(args, filter.QuestionID)
+	}
+	if filter.Difficulty > 0 {
+		conds = append(conds, "difficulty = ?")
+		args = append(args, filter.Difficulty)
+	}
+	if filter.From != nil {
+		conds = append(conds, "answered_at >= ?")
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		conds = append(conds, "answered_at < ?")
+		args = append(args, *filter.To)
+	}
+	args = append(args, filter.Limit)
+
+	query := `SELECT ` + answerColumns + `
+	          FROM user_answers a WHERE ` + strings.Join(conds, " AND ") + `
+	          ORDER BY id DESC LIMIT ?`
+	return r.queryAnswers(query, args...)
+}
+
+// ListMistakes returns the user's latest wrong answer to each question they got wrong, newest
+// first, at most limit of them.
+func (r *AnswerHistoryRepository) ListMistakes(userID int, limit int) ([]models.AnswerRecord, error) {
+	query := `SELECT ` + answerColumns + `
+	          FROM user_answers a
+	          JOIN (SELECT MAX(id) AS id FROM user_answers
+	                WHERE user_id = ? AND is_correct = 0 GROUP BY question_id) m ON m.id = a.id
+	          ORDER BY a.id DESC LIMIT ?`
+	return r.queryAnswers(query, userID, limit)
+}
+
+// answerColumns is the column list answer history queries select; queryAnswers reads it.
+const answerColumns = `a.id, a.user_id, a.question_id, a.answer, a.is_correct, a.mode, a.shuffle_seed, a.difficulty,
+	          a.score_delta, a.streak_before, a.streak_after, a.answered_at`
+
+// queryAnswers runs a query selecting answerColumns and returns its rows.
+func (r *AnswerHistoryRepository) queryAnswers(query string, args ...interface{}) ([]models.AnswerRecord, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []models.AnswerRecord{}
+	for rows.Next() {
+		var rec models.AnswerRecord
+		err := rows.Scan(
+			&rec.ID, &rec.UserID, &rec.QuestionID, &rec.Answer, &rec.IsCorrect, &rec.Mode, &rec.ShuffleSeed, &rec.Difficulty,
+			&rec.ScoreDelta, &rec.StreakBefore, &rec.StreakAfter, &rec.AnsweredAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+
+	return records, rows.Err()
+}
+
+// GetScoreLeaderboardBetween ranks users by the points they earned in [from, to), skipping the first offset
+func (r *AnswerHistoryRepository) GetScoreLeaderboardBetween(from, to time.Time, offset, limit int) ([]LeaderboardEntry, error) {
+	return r.leaderboardBetween(periodScoreValues, from, to, offset, limit)
+}
+
+// GetStreakLeaderboardBetween ranks users by the longest streak they reached in [from, to), skipping the first offset
+func (r *AnswerHistoryRepository) GetStreakLeaderboardBetween(from, to time.Time, offset, limit int) ([]LeaderboardEntry, error) {
+	return r.leaderboardBetween(periodStreakValues, from, to, offset, limit)
+}
+
+// GetScoreValuesBetween is GetScoreLeaderboardBetween with each value's tie-break time (for rebuilds)
+func (r *AnswerHistoryRepository) GetScoreValuesBetween(from, to time.Time, offset, limit int) ([]BoardValue, error) {
+	return r.valuesBetween(periodScoreValues, from, to, offset, limit)
+}
+
+// GetStreakValuesBetween is GetStreakLeaderboardBetween with each value's tie-break time (for rebuilds)
+func (r *AnswerHistoryRepository) GetStreakValuesBetween(from, to time.Time, offset, limit int) ([]BoardValue, error) {
+	return r.valuesBetween(periodStreakValues, from, to, offset, limit)
+}
+
+// GetUserScoreRankBetween returns the user's rank by points earned in [from, to) (1-indexed, 0 if
+// they did not answer in the window)
+func (r *AnswerHistoryRepository) GetUserScoreRankBetween(userID int, from, to time.Time) (int, error) {
+	return r.rankBetween(periodScoreValues, userID, from, to)
+}
+
+// GetUserStreakRankBetween returns the user's rank by longest streak in [from, to) (1-indexed, 0 if
+// they did not answer in the window)
+func (r *AnswerHistoryRepository) GetUserStreakRankBetween(userID int, from, to time.Time) (int, error) {
+	return r.rankBetween(periodStreakValues, userID, from, to)
+}
+
+// periodValues builds the per-user (user_id, value, reached_at) rows of a period board from
+// user_answers in a window; args repeats the window bounds as often as the query needs them.
+type periodValues struct {
+	query      string
+	windowArgs int
+}
+
+var (
+	// Points earned in the window, reached at the last answer that changed them (or the first
+	// answer if none did), like the period score script in leaderboard_repo.go.
+	periodScoreValues = periodValues{
+		query: `SELECT user_id, SUM(score_delta) AS value,
+		        COALESCE(MAX(CASE WHEN score_delta <> 0 THEN answered_at END), MIN(answered_at)) AS reached_at
+		        FROM user_answers
+		        WHERE answered_at >= ? AND answered_at < ? AND ` + rankedAnswer + `
+		        GROUP BY user_id`,
+		windowArgs: 1,
+	}
+	// Longest streak in the window, reached at the first answer that hit it (ZADD GT keeps that one).
+	periodStreakValues = periodValues{
+		query: `SELECT a.user_id, m.value, MIN(a.answered_at) AS reached_at
+		        FROM user_answers a
+		        JOIN (SELECT user_id, MAX(streak_after) AS value FROM user_answers
+		              WHERE answered_at >= ? AND answered_at < ? AND ` + rankedAnswer + `
+		              GROUP BY user_id) m ON m.user_id = a.user_id AND a.streak_after = m.value
+		        WHERE a.answered_at >= ? AND a.answered_at < ? AND a.` + rankedAnswer + `
+		        GROUP BY a.user_id, m.value`,
+		windowArgs: 2,
+	}
+)
+
+// args returns the window bounds for p.query followed by extra.
+func (p periodValues) args(from, to time.Time, extra ...interface{}) []interface{} {
+	args := make([]interface{}, 0, 2*p.windowArgs+len(extra))
+	for i := 0; i < p.windowArgs; i++ {
+		args = append(args, from, to)
+	}
+	return append(args, extra...)
+}
+
+// periodOrder orders period rows like the packed ZSET scores (see tiebreak.go).
+var periodOrder = boardScoreSQL("value", "reached_at")
+
+// leaderboardBetween ranks the users of a period board
+func (r *AnswerHistoryRepository) leaderboardBetween(p periodValues, from, to time.Time, offset, limit int) ([]LeaderboardEntry, error) {
+	values, err := r.valuesBetween(p, from, to, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]LeaderboardEntry, len(values))
+	for i, v := range values {
+		entries[i] = LeaderboardEntry{UserID: v.UserID, Score: v.Value, Rank: int64(offset + i + 1)}
+	}
+	return entries, nil
+}
+
+// valuesBetween returns a page of a period board in leaderboard order
+func (r *AnswerHistoryRepository) valuesBetween(p periodValues, from, to time.Time, offset, limit int) ([]BoardValue, error) {
+	query := `SELECT user_id, value, reached_at FROM (` + p.query + `) t
+	          ORDER BY ` + periodOrder + ` DESC, CAST(user_id AS CHAR) DESC
+	          LIMIT ? OFFSET ?`
+	rows, err := r.db.Query(query, p.args(from, to, limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []BoardValue{}
+	for rows.Next() {
+		var v BoardValue
+		var reachedAt sql.NullTime
+		if err := rows.Scan(&v.UserID, &v.Value, &reachedAt); err != nil {
+			return nil, err
+		}
+		if reachedAt.Valid {
+			v.ReachedAt = &reachedAt.Time
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+// rankBetween counts the users ordered ahead of userID on a period board
+func (r *AnswerHistoryRepository) rankBetween(p periodValues, userID int, from, to time.Time) (int, error) {
+	var me BoardValue
+	var reachedAt sql.NullTime
+	err := r.db.QueryRow(`SELECT value, reached_at FROM (`+p.query+`) t WHERE user_id = ?`,
+		p.args(from, to, userID)...).Scan(&me.Value, &reachedAt)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if reachedAt.Valid {
+		me.ReachedAt = &reachedAt.Time
+	}
+	key := encodeBoardKey(me.Value, me.ReachedAt)
+	member := strconv.Itoa(userID)
+
+	query := `SELECT COUNT(*) + 1 FROM (` + p.query + `) t
+	          WHERE ` + periodOrder + ` > ?
+	             OR (` + periodOrder + ` = ? AND CAST(user_id AS CHAR) > ?)`
+	var rank int
+	err = r.db.QueryRow(query, p.args(from, to, key, key, member)...).Scan(&rank)
+	return rank, err
+}
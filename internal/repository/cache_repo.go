@@ -11,8 +11,10 @@ import (
 )
 
 const (
-	userCacheKeyPrefix = "user:info:"
-	userCacheTTL       = 24 * time.Hour
+	userCacheKeyPrefix     = "user:info:"
+	userCacheTTL           = 24 * time.Hour
+	questionCacheKeyPrefix = "question:info:"
+	questionCacheTTL       = time.Hour
 )
 
 // UserCacheRepository caches user data in Redis (TTL 1 day).
@@ -46,6 +48,34 @@ func (r *UserCacheRepository) Get(userID int) (*models.User, error) {
 	return &user, nil
 }
 
+// GetMany returns the cached users among ids in one MGET, keyed by id; misses are simply absent.
+func (r *UserCacheRepository) GetMany(ids []int) (map[int]*models.User, error) {
+	users := make(map[int]*models.User, len(ids))
+	if len(ids) == 0 {
+		return users, nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = userCacheKeyPrefix + strconv.Itoa(id)
+	}
+	values, err := r.client.MGet(r.ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var user models.User
+		if err := json.Unmarshal([]byte(data), &user); err != nil {
+			continue
+		}
+		users[ids[i]] = &user
+	}
+	return users, nil
+}
+
 // Set stores the user in cache with 24h TTL.
 func (r *UserCacheRepository) Set(userID int, user *models.User) error {
 	key := userCacheKeyPrefix + strconv.Itoa(userID)
@@ -67,3 +97,65 @@ func (r *UserCacheRepository) QueueSet(pipe *redis.Pipeline, userID int, user *m
 	pipe.Set(r.ctx, key, data, userCacheTTL)
 	return nil
 }
+
+// QueueDelete queues DEL for the user cache; call Exec on the pipeline to run.
+func (r *UserCacheRepository) QueueDelete(pipe *redis.Pipeline, userID int) {
+	pipe.Del(r.ctx, userCacheKeyPrefix+strconv.Itoa(userID))
+}
+
+// QuestionCacheRepository caches questions in Redis (TTL 1 hour) for the answer path; every
+// change to a question row must delete its entry.
+type QuestionCacheRepository struct {
+	client *redis.Client
+	ctx    context.Context
+}
+
+// NewQuestionCacheRepository creates a new question cache repository.
+func NewQuestionCacheRepository(client *redis.Client) *QuestionCacheRepository {
+	return &QuestionCacheRepository{
+		client: client,
+		ctx:    context.Background(),
+	}
+}
+
+// Get returns the cached question, or (nil, nil) if not found.
+func (r *QuestionCacheRepository) Get(questionID int) (*models.Question, error) {
+	data, err := r.client.Get(r.ctx, questionCacheKeyPrefix+strconv.Itoa(questionID)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var question models.Question
+	if err := json.Unmarshal([]byte(data), &question); err != nil {
+		return nil, err
+	}
+	return &question, nil
+}
+
+// Set stores the question in cache with 1h TTL.
+func (r *QuestionCacheRepository) Set(question *models.Question) error {
+	data, err := json.Marshal(question)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(r.ctx, questionCacheKeyPrefix+strconv.Itoa(question.ID), data, questionCacheTTL).Err()
+}
+
+// Delete removes questions from the cache.
+func (r *QuestionCacheRepository) Delete(questionIDs ...int) error {
+	if len(questionIDs) == 0 {
+		return nil
+	}
+	keys := make([]string, len(questionIDs))
+	for i, id := range questionIDs {
+		keys[i] = questionCacheKeyPrefix + strconv.Itoa(id)
+	}
+	return r.client.Del(r.ctx, keys...).Err()
+}
+
+// QueueDelete queues DEL for a cached question; call Exec on the pipeline to run.
+func (r *QuestionCacheRepository) QueueDelete(pipe *redis.Pipeline, questionID int) {
+	pipe.Del(r.ctx, questionCacheKeyPrefix+strconv.Itoa(questionID))
+}
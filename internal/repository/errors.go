@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL server error numbers the repositories react to.
+const (
+	mysqlErrLockWaitTimeout = 1205 // ER_LOCK_WAIT_TIMEOUT
+	mysqlErrDeadlock        = 1213 // ER_LOCK_DEADLOCK
+	mysqlErrDuplicateEntry  = 1062 // ER_DUP_ENTRY
+)
+
+// ErrDuplicateEntry is returned when an insert or update would violate a unique constraint.
+var ErrDuplicateEntry = errors.New("duplicate entry")
+
+// ErrTxConflict is returned when a transaction kept losing lock contention and gave up retrying.
+var ErrTxConflict = errors.New("transaction conflict")
+
+// isDuplicateEntry reports whether err is a MySQL duplicate-key error.
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry
+}
+
+// isRetryableTxError reports whether err is a deadlock or lock wait timeout, after which
+// MySQL has rolled the transaction (or statement) back and it is safe to run it again.
+func isRetryableTxError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	return mysqlErr.Number == mysqlErrDeadlock || mysqlErr.Number == mysqlErrLockWaitTimeout
+}
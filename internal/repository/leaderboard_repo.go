@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"strconv"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -10,13 +11,39 @@ import (
 const (
 	LeaderboardScoreKey  = "leaderboard:score"
 	LeaderboardStreakKey = "leaderboard:streak"
+	// All-time only boards: accuracy in basis points, current (decaying) streak, highest difficulty.
+	LeaderboardAccuracyKey      = "leaderboard:accuracy"
+	LeaderboardCurrentStreakKey = "leaderboard:current_streak"
+	LeaderboardDifficultyKey    = "leaderboard:difficulty"
 )
 
+// LeaderboardUpdatesChannel is the pub/sub channel on which every leaderboard write announces the
+// user it changed, so all app instances can push live updates.
+const LeaderboardUpdatesChannel = "leaderboard:updates"
+
+// PeriodKey returns the ZSET key of a time-windowed board, e.g. "leaderboard:score:weekly:2026-10-12".
+// An empty suffix is the all-time board.
+func PeriodKey(base, suffix string) string {
+	if suffix == "" {
+		return base
+	}
+	return base + ":" + suffix
+}
+
+// LeaderboardProfile is the public profile shown next to a leaderboard entry (empty if the user
+// could not be looked up)
+type LeaderboardProfile struct {
+	Username          string   `json:"username,omitempty"`
+	Accuracy          *float64 `json:"accuracy,omitempty"` // percent, like /v1/quiz/metrics
+	CurrentDifficulty int      `json:"currentDifficulty,omitempty"`
+}
+
 // LeaderboardEntry represents a score leaderboard entry
 type LeaderboardEntry struct {
 	UserID int   `json:"userId"`
 	Score  int64 `json:"score"`
 	Rank   int64 `json:"rank"`
+	LeaderboardProfile
 }
 
 // StreakLeaderboardEntry represents a streak leaderboard entry (max_streak, not score)
@@ -24,6 +51,23 @@ type StreakLeaderboardEntry struct {
 	UserID int   `json:"userId"`
 	Streak int   `json:"streak"`
 	Rank   int64 `json:"rank"`
+	LeaderboardProfile
+}
+
+// AccuracyLeaderboardEntry represents an accuracy leaderboard entry (percent of answers correct)
+type AccuracyLeaderboardEntry struct {
+	UserID   int     `json:"userId"`
+	Accuracy float64 `json:"accuracy"`
+	Rank     int64   `json:"rank"`
+	LeaderboardProfile
+}
+
+// DifficultyLeaderboardEntry represents a highest-difficulty leaderboard entry
+type DifficultyLeaderboardEntry struct {
+	UserID        int   `json:"userId"`
+	MaxDifficulty int   `json:"maxDifficulty"`
+	Rank          int64 `json:"rank"`
+	LeaderboardProfile
 }
 
 // LeaderboardRepository handles Redis ZSet operations for leaderboards
@@ -41,17 +85,17 @@ func NewLeaderboardRepository(client *redis.Client) *LeaderboardRepository {
 }
 
 // UpdateScore updates user's score in the score leaderboard ZSet
-func (r *LeaderboardRepository) UpdateScore(userID int, score int64) error {
+func (r *LeaderboardRepository) UpdateScore(userID int, score int64, reachedAt *time.Time) error {
 	return r.client.ZAdd(r.ctx, LeaderboardScoreKey, redis.Z{
-		Score:  float64(score),       // ZSet uses float64 for scores
-		Member: strconv.Itoa(userID), // Store userID as stringified integer
+		Score:  EncodeBoardScore(score, reachedAt), // packed with the tie-break, see tiebreak.go
+		Member: strconv.Itoa(userID),               // Store userID as stringified integer
 	}).Err()
 }
 
 // UpdateStreak updates user's max streak in the streak leaderboard ZSet
-func (r *LeaderboardRepository) UpdateStreak(userID int, maxStreak int) error {
+func (r *LeaderboardRepository) UpdateStreak(userID int, maxStreak int, reachedAt *time.Time) error {
 	return r.client.ZAdd(r.ctx, LeaderboardStreakKey, redis.Z{
-		Score:  float64(maxStreak),
+		Score:  EncodeBoardScore(int64(maxStreak), reachedAt),
 		Member: strconv.Itoa(userID), // Store userID as stringified integer
 	}).Err()
 }
@@ -62,59 +106,102 @@ func (r *LeaderboardRepository) Pipeline() *redis.Pipeline {
 }
 
 // QueueUpdateScore queues ZADD for the score leaderboard; call Exec on the pipeline to run.
-func (r *LeaderboardRepository) QueueUpdateScore(pipe *redis.Pipeline, userID int, score int64) {
+func (r *LeaderboardRepository) QueueUpdateScore(pipe *redis.Pipeline, userID int, score int64, reachedAt *time.Time) {
 	pipe.ZAdd(r.ctx, LeaderboardScoreKey, redis.Z{
-		Score:  float64(score),
+		Score:  EncodeBoardScore(score, reachedAt),
 		Member: strconv.Itoa(userID),
 	})
 }
 
 // QueueUpdateStreak queues ZADD for the streak leaderboard; call Exec on the pipeline to run.
-func (r *LeaderboardRepository) QueueUpdateStreak(pipe *redis.Pipeline, userID int, maxStreak int) {
+func (r *LeaderboardRepository) QueueUpdateStreak(pipe *redis.Pipeline, userID int, maxStreak int, reachedAt *time.Time) {
 	pipe.ZAdd(r.ctx, LeaderboardStreakKey, redis.Z{
-		Score:  float64(maxStreak),
+		Score:  EncodeBoardScore(int64(maxStreak), reachedAt),
 		Member: strconv.Itoa(userID),
 	})
 }
 
-// GetTopByScore returns top N users by score
-func (r *LeaderboardRepository) GetTopByScore(limit int64) ([]LeaderboardEntry, error) {
-	// ZREVRANGE returns highest to lowest (descending order)
-	results, err := r.client.ZRevRangeWithScores(r.ctx, LeaderboardScoreKey, 0, limit-1).Result()
-	if err != nil {
-		return nil, err
-	}
+// incrScoreScript adds ARGV[2] to the packed value of member ARGV[1], clamped to ±ARGV[6] (a
+// period score only reaches the cap after the all-time one, whose clamp is logged). The
+// tie-break slot becomes ARGV[3] when the member is new or the value changes, and is kept
+// otherwise. Lua numbers are doubles, so the packed score is formatted with %.0f to keep every
+// digit.
+var incrScoreScript = redis.NewScript(`
+local slots = tonumber(ARGV[4])
+local delta = tonumber(ARGV[2])
+local limit = tonumber(ARGV[6])
+local cur = redis.call('ZSCORE', KEYS[1], ARGV[1])
+local value = 0
+local tie = tonumber(ARGV[3])
+if cur then
+  cur = tonumber(cur)
+  value = math.floor(cur / slots)
+  if delta == 0 then
+    tie = cur - value * slots
+  end
+end
+value = math.max(-limit, math.min(limit, value + delta))
+redis.call('ZADD', KEYS[1], string.format('%.0f', value * slots + tie), ARGV[1])
+redis.call('EXPIREAT', KEYS[1], ARGV[5])
+return 1
+`)
 
-	var entries []LeaderboardEntry
-	for _, result := range results {
-		userIDStr, ok := result.Member.(string)
-		if !ok {
-			continue
-		}
-		userID, err := strconv.Atoi(userIDStr)
-		if err != nil {
-			continue
-		}
-		entries = append(entries, LeaderboardEntry{
-			UserID: userID,
-			Score:  int64(result.Score),
-			Rank:   0, // set below
-		})
-	}
-	for i := range entries {
-		entries[i].Rank = int64(i) + 1
+// QueueIncrScore queues an increment of a period score board (reached at `at` for the
+// tie-break) and (re)sets its expiry; call Exec on the pipeline to run.
+func (r *LeaderboardRepository) QueueIncrScore(pipe *redis.Pipeline, key string, userID int, delta int64, at time.Time, expireAt time.Time) {
+	incrScoreScript.Eval(r.ctx, pipe, []string{key},
+		strconv.Itoa(userID), delta, tieBreakSlots-1-tieBreakSlot(&at), tieBreakSlots, expireAt.Unix(), MaxBoardValue)
+}
+
+// QueueMaxStreak queues ZADD GT (keep the highest streak seen, and the earliest time it was
+// reached) on a period streak board and (re)sets its expiry; call Exec on the pipeline to run.
+func (r *LeaderboardRepository) QueueMaxStreak(pipe *redis.Pipeline, key string, userID int, streak int, at time.Time, expireAt time.Time) {
+	pipe.ZAddGT(r.ctx, key, redis.Z{
+		Score:  EncodeBoardScore(int64(streak), &at),
+		Member: strconv.Itoa(userID),
+	})
+	pipe.ExpireAt(r.ctx, key, expireAt)
+}
+
+// QueueRemoveUser queues ZREM of the user from every all-time leaderboard and any extra (period)
+// keys; call Exec on the pipeline to run.
+func (r *LeaderboardRepository) QueueRemoveUser(pipe *redis.Pipeline, userID int, extraKeys ...string) {
+	member := strconv.Itoa(userID)
+	pipe.ZRem(r.ctx, LeaderboardScoreKey, member)
+	pipe.ZRem(r.ctx, LeaderboardStreakKey, member)
+	pipe.ZRem(r.ctx, LeaderboardAccuracyKey, member)
+	pipe.ZRem(r.ctx, LeaderboardCurrentStreakKey, member)
+	pipe.ZRem(r.ctx, LeaderboardDifficultyKey, member)
+	for _, key := range extraKeys {
+		pipe.ZRem(r.ctx, key, member)
 	}
-	return entries, nil
+}
+
+// GetTopByScore returns top N users by score
+func (r *LeaderboardRepository) GetTopByScore(limit int64) ([]LeaderboardEntry, error) {
+	return r.GetTop(LeaderboardScoreKey, limit)
 }
 
 // GetTopByStreak returns top N users by max streak
 func (r *LeaderboardRepository) GetTopByStreak(limit int64) ([]LeaderboardEntry, error) {
-	results, err := r.client.ZRevRangeWithScores(r.ctx, LeaderboardStreakKey, 0, limit-1).Result()
+	return r.GetTop(LeaderboardStreakKey, limit)
+}
+
+// GetTop returns the top N members of any leaderboard ZSET (score holds the ZSET score)
+func (r *LeaderboardRepository) GetTop(key string, limit int64) ([]LeaderboardEntry, error) {
+	return r.GetRange(key, 0, limit)
+}
+
+// GetRange returns limit members of any leaderboard ZSET starting after the first offset, ranked
+// from offset+1
+func (r *LeaderboardRepository) GetRange(key string, offset, limit int64) ([]LeaderboardEntry, error) {
+	// ZREVRANGE returns highest to lowest (descending order)
+	results, err := r.client.ZRevRangeWithScores(r.ctx, key, offset, offset+limit-1).Result()
 	if err != nil {
 		return nil, err
 	}
 
-	var entries []LeaderboardEntry
+	entries := []LeaderboardEntry{}
 	for _, result := range results {
 		userIDStr, ok := result.Member.(string)
 		if !ok {
@@ -126,20 +213,20 @@ func (r *LeaderboardRepository) GetTopByStreak(limit int64) ([]LeaderboardEntry,
 		}
 		entries = append(entries, LeaderboardEntry{
 			UserID: userID,
-			Score:  int64(result.Score), // This is actually max_streak
-			Rank:   0,                   // set below
+			Score:  DecodeBoardScore(result.Score),
+			Rank:   0, // set below
 		})
 	}
 	for i := range entries {
-		entries[i].Rank = int64(i) + 1
+		entries[i].Rank = offset + int64(i) + 1
 	}
 	return entries, nil
 }
 
-// GetUserRankByScore returns user's rank by score (1-indexed, 0 if not found)
-func (r *LeaderboardRepository) GetUserRankByScore(userID int) (int64, error) {
+// GetUserRank returns user's rank in any leaderboard ZSET (1-indexed, 0 if not found)
+func (r *LeaderboardRepository) GetUserRank(key string, userID int) (int64, error) {
 	// ZREVRANK returns 0-based rank (highest score = rank 0)
-	rank, err := r.client.ZRevRank(r.ctx, LeaderboardScoreKey, strconv.Itoa(userID)).Result()
+	rank, err := r.client.ZRevRank(r.ctx, key, strconv.Itoa(userID)).Result()
 	if err == redis.Nil {
 		return 0, nil // User not in leaderboard
 	}
@@ -149,16 +236,14 @@ func (r *LeaderboardRepository) GetUserRankByScore(userID int) (int64, error) {
 	return rank + 1, nil // Convert to 1-indexed
 }
 
+// GetUserRankByScore returns user's rank by score (1-indexed, 0 if not found)
+func (r *LeaderboardRepository) GetUserRankByScore(userID int) (int64, error) {
+	return r.GetUserRank(LeaderboardScoreKey, userID)
+}
+
 // GetUserRankByStreak returns user's rank by streak (1-indexed, 0 if not found)
 func (r *LeaderboardRepository) GetUserRankByStreak(userID int) (int64, error) {
-	rank, err := r.client.ZRevRank(r.ctx, LeaderboardStreakKey, strconv.Itoa(userID)).Result()
-	if err == redis.Nil {
-		return 0, nil
-	}
-	if err != nil {
-		return 0, err
-	}
-	return rank + 1, nil
+	return r.GetUserRank(LeaderboardStreakKey, userID)
 }
 
 // GetUserScore gets user's current score from ZSet
@@ -170,7 +255,7 @@ func (r *LeaderboardRepository) GetUserScore(userID int) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int64(score), nil
+	return DecodeBoardScore(score), nil
 }
 
 // GetUserStreak gets user's max streak from ZSet
@@ -182,5 +267,118 @@ func (r *LeaderboardRepository) GetUserStreak(userID int) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int(score), nil
+	return int(DecodeBoardScore(score)), nil
+}
+
+// QueueSet queues ZADD of an exact value on any leaderboard ZSET; call Exec on the pipeline to run.
+func (r *LeaderboardRepository) QueueSet(pipe *redis.Pipeline, key string, v BoardValue) {
+	pipe.ZAdd(r.ctx, key, redis.Z{
+		Score:  v.Encode(),
+		Member: strconv.Itoa(v.UserID),
+	})
+}
+
+// QueueRemove queues ZREM of the user from one leaderboard ZSET; call Exec on the pipeline to run.
+func (r *LeaderboardRepository) QueueRemove(pipe *redis.Pipeline, key string, userID int) {
+	pipe.ZRem(r.ctx, key, strconv.Itoa(userID))
+}
+
+// QueuePublishUpdate queues PUBLISH of the user's id on LeaderboardUpdatesChannel; queue it after
+// the ZSET writes so subscribers see them. Call Exec on the pipeline to run.
+func (r *LeaderboardRepository) QueuePublishUpdate(pipe *redis.Pipeline, userID int) {
+	pipe.Publish(r.ctx, LeaderboardUpdatesChannel, strconv.Itoa(userID))
+}
+
+// SubscribeUpdates subscribes to LeaderboardUpdatesChannel (go-redis reconnects on its own);
+// Close the PubSub to stop.
+func (r *LeaderboardRepository) SubscribeUpdates() *redis.PubSub {
+	return r.client.Subscribe(r.ctx, LeaderboardUpdatesChannel)
+}
+
+// QueueSetFormat queues writing the current BoardFormatVersion; call Exec on the pipeline to run.
+func (r *LeaderboardRepository) QueueSetFormat(pipe *redis.Pipeline) {
+	pipe.Set(r.ctx, LeaderboardFormatKey, BoardFormatVersion, 0)
+}
+
+// GetFormat returns the stored board format version ("" if never written)
+func (r *LeaderboardRepository) GetFormat() (string, error) {
+	v, err := r.client.Get(r.ctx, LeaderboardFormatKey).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return v, err
+}
+
+// QueueExpireAt queues EXPIREAT on a leaderboard ZSET; call Exec on the pipeline to run.
+func (r *LeaderboardRepository) QueueExpireAt(pipe *redis.Pipeline, key string, at time.Time) {
+	pipe.ExpireAt(r.ctx, key, at)
+}
+
+// Count returns the number of members of a leaderboard ZSET
+func (r *LeaderboardRepository) Count(key string) (int64, error) {
+	return r.client.ZCard(r.ctx, key).Result()
+}
+
+// Delete removes leaderboard ZSETs
+func (r *LeaderboardRepository) Delete(keys ...string) error {
+	return r.client.Del(r.ctx, keys...).Err()
+}
+
+// Replace atomically moves the ZSET at src over dst (dst is deleted if src is empty)
+func (r *LeaderboardRepository) Replace(src, dst string) error {
+	n, err := r.client.Exists(r.ctx, src).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return r.client.Del(r.ctx, dst).Err()
+	}
+	return r.client.Rename(r.ctx, src, dst).Err()
+}
+
+// GetPackedScores returns the raw (packed) ZSET scores of the given users in one round-trip;
+// users that are not members are absent from the map
+func (r *LeaderboardRepository) GetPackedScores(key string, userIDs []int) (map[int]float64, error) {
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.FloatCmd, len(userIDs))
+	for i, id := range userIDs {
+		cmds[i] = pipe.ZScore(r.ctx, key, strconv.Itoa(id))
+	}
+	if _, err := pipe.Exec(r.ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+	values := make(map[int]float64, len(userIDs))
+	for i, cmd := range cmds {
+		v, err := cmd.Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[userIDs[i]] = v
+	}
+	return values, nil
+}
+
+// ScanMembers calls fn with every user ID in a leaderboard ZSET (ZSCAN, so it may see a member
+// twice if the set changes while scanning)
+func (r *LeaderboardRepository) ScanMembers(key string, fn func(userID int)) error {
+	var cursor uint64
+	for {
+		keys, next, err := r.client.ZScan(r.ctx, key, cursor, "", 500).Result()
+		if err != nil {
+			return err
+		}
+		// ZSCAN returns member, score pairs
+		for i := 0; i+1 < len(keys); i += 2 {
+			if id, err := strconv.Atoi(keys[i]); err == nil {
+				fn(id)
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
 }
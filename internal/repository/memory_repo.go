@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"brainbolt/internal/models"
+	"database/sql"
+)
+
+// MemoryRepository persists the spaced-repetition state of practice mode in question_memory,
+// one row per user and question.
+type MemoryRepository struct {
+	db *sql.DB
+}
+
+// NewMemoryRepository creates a new memory repository
+func NewMemoryRepository(db *sql.DB) *MemoryRepository {
+	return &MemoryRepository{db: db}
+}
+
+// memoryColumns is the column list every question_memory query selects; scanMemory reads it in this order.
+const memoryColumns = `user_id, question_id, repetitions, interval_days, ease_factor, lapses, due_at, reviewed_at`
+
+// scanMemory reads one row selected with memoryColumns.
+func scanMemory(row rowScanner) (*models.MemoryState, error) {
+	var m models.MemoryState
+	if err := row.Scan(&m.UserID, &m.QuestionID, &m.Repetitions, &m.IntervalDays, &m.EaseFactor, &m.Lapses,
+		&m.DueAt, &m.ReviewedAt); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// GetMemoryForUpdate reads the user's memory state for a question inside tx and locks the row;
+// returns nil if there is none yet
+func (r *MemoryRepository) GetMemoryForUpdate(tx *sql.Tx, userID int, questionID int) (*models.MemoryState, error) {
+	query := `SELECT ` + memoryColumns + ` FROM question_memory WHERE user_id = ? AND question_id = ? FOR UPDATE`
+	m, err := scanMemory(tx.QueryRow(query, userID, questionID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return m, err
+}
+
+// SaveMemory inserts or updates the user's memory state for a question inside tx
+func (r *MemoryRepository) SaveMemory(tx *sql.Tx, m *models.MemoryState) error {
+	query := `INSERT INTO question_memory (` + memoryColumns + `)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	          ON DUPLICATE KEY UPDATE repetitions = VALUES(repetitions), interval_days = VALUES(interval_days),
+	            ease_factor = VALUES(ease_factor), lapses = VALUES(lapses), due_at = VALUES(due_at),
+	            reviewed_at = VALUES(reviewed_at)`
+	_, err := tx.Exec(query, m.UserID, m.QuestionID, m.Repetitions, m.IntervalDays, m.EaseFactor, m.Lapses,
+		m.DueAt, m.ReviewedAt)
+	return err
+}
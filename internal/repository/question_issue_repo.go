@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"brainbolt/internal/models"
+	"database/sql"
+	"time"
+)
+
+// QuestionIssueRepository persists the question_issues ledger: one row per question served by
+// /v1/quiz/next, marked answered when the matching answer is accepted.
+type QuestionIssueRepository struct {
+	db *sql.DB
+}
+
+// NewQuestionIssueRepository creates a new question issue repository
+func NewQuestionIssueRepository(db *sql.DB) *QuestionIssueRepository {
+	return &QuestionIssueRepository{db: db}
+}
+
+// CreateIssue records that questionID was served to userID in the given quiz mode with the given
+// option shuffle and returns the issue id
+func (r *QuestionIssueRepository) CreateIssue(userID int, questionID int, mode string, shuffleSeed int64, issuedAt, expiresAt time.Time) (int64, error) {
+	query := `INSERT INTO question_issues (user_id, question_id, mode, shuffle_seed, issued_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := r.db.Exec(query, userID, questionID, mode, shuffleSeed, issuedAt, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetIssueForUpdate reads an issue inside tx and locks the row until the transaction ends.
+// Returns nil if not found.
+func (r *QuestionIssueRepository) GetIssueForUpdate(tx *sql.Tx, id int64) (*models.QuestionIssue, error) {
+	var issue models.QuestionIssue
+	var answeredAt sql.NullTime
+	query := `SELECT id, user_id, question_id, mode, shuffle_seed, issued_at, expires_at, answered_at
+	          FROM question_issues WHERE id = ? FOR UPDATE`
+	err := tx.QueryRow(query, id).Scan(
+		&issue.ID, &issue.UserID, &issue.QuestionID, &issue.Mode, &issue.ShuffleSeed, &issue.IssuedAt, &issue.ExpiresAt, &answeredAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if answeredAt.Valid {
+		issue.AnsweredAt = &answeredAt.Time
+	}
+	return &issue, nil
+}
+
+// MarkIssueAnswered sets answered_at on an issue inside tx
+func (r *QuestionIssueRepository) MarkIssueAnswered(tx *sql.Tx, id int64, answeredAt time.Time) error {
+	_, err := tx.Exec(`UPDATE question_issues SET answered_at = ? WHERE id = ?`, answeredAt, id)
+	return err
+}
+
+// PurgeExpired deletes issues that expired before the given time and returns how many were removed
+func (r *QuestionIssueRepository) PurgeExpired(before time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM question_issues WHERE expires_at < ?`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
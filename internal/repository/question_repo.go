@@ -4,8 +4,55 @@ import (
 	"brainbolt/internal/models"
 	"database/sql"
 	"encoding/json"
+	"time"
 )
 
+// questionColumns is the column list every questions query selects; scanQuestion reads it in this order.
+// Unrated questions get the default rating for their difficulty level (see RatingBase).
+var questionColumns = `q.id, q.type, q.difficulty, q.question, q.options, q.answer, q.tolerance, q.partial_credit,
+	          q.aliases, ` + questionRatingSQL + ` as rating, q.category, q.tags, q.explanation,
+	          q.refs, q.retired_at`
+
+// questionRatingSQL is a question's rating, or the default rating of its difficulty if unrated.
+var questionRatingSQL = "COALESCE(q.rating, " + defaultRatingSQL("q.difficulty") + ")"
+
+// QuestionSelection describes how GetRandomQuestionForUser picks a question: either at an exact
+// difficulty level, or (ByRating) among the questions rated closest to TargetRating.
+// Category and Tags (any of them) optionally narrow the pool.
+type QuestionSelection struct {
+	Difficulty   int
+	ByRating     bool
+	TargetRating float64
+	Category     string
+	Tags         []string
+}
+
+// filter returns the conditions (each starting with AND) and arguments that narrow a questions
+// query to servable (not retired) questions of the selection's category and tags.
+func (sel QuestionSelection) filter() (string, []interface{}) {
+	where := ` AND q.retired_at IS NULL`
+	var args []interface{}
+	if sel.Category != "" {
+		where += ` AND q.category = ?`
+		args = append(args, sel.Category)
+	}
+	if len(sel.Tags) > 0 {
+		tags, _ := json.Marshal(sel.Tags)
+		where += ` AND JSON_OVERLAPS(q.tags, CAST(? AS JSON))`
+		args = append(args, string(tags))
+	}
+	return where, args
+}
+
+// CategoryCount is the number of questions in one category.
+type CategoryCount struct {
+	Category  string `json:"category"`
+	Questions int    `json:"questions"`
+}
+
+// nearestRatedPool is how many closest-rated questions a rating-based pick chooses from at random.
+const nearestRatedPool = 5
+
 // QuestionRepository handles DB access for questions
 type QuestionRepository struct {
 	db *sql.DB
@@ -16,28 +63,57 @@ func NewQuestionRepository(db *sql.DB) *QuestionRepository {
 	return &QuestionRepository{db: db}
 }
 
-// GetQuestionByID returns a question by ID, or nil if not found
-func (r *QuestionRepository) GetQuestionByID(id int) (*models.Question, error) {
+// scanQuestion reads one row selected with questionColumns.
+func scanQuestion(row rowScanner) (*models.Question, error) {
 	var q models.Question
-	var optionsJSON []byte
-	query := `SELECT id, difficulty, question, options, answer FROM questions WHERE id = ?`
-	err := r.db.QueryRow(query, id).Scan(&q.ID, &q.Difficulty, &q.Question, &optionsJSON, &q.Answer)
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
-	if err != nil {
+	var optionsJSON, aliasesJSON, tagsJSON, refsJSON []byte
+	var explanation sql.NullString
+	if err := row.Scan(&q.ID, &q.Type, &q.Difficulty, &q.Question, &optionsJSON, &q.Answer, &q.Tolerance, &q.PartialCredit,
+		&aliasesJSON, &q.Rating, &q.Category, &tagsJSON, &explanation, &refsJSON, &q.RetiredAt); err != nil {
 		return nil, err
 	}
+	q.Explanation = explanation.String
 	if err := json.Unmarshal(optionsJSON, &q.Options); err != nil {
 		return nil, err
 	}
+	if aliasesJSON != nil {
+		if err := json.Unmarshal(aliasesJSON, &q.Aliases); err != nil {
+			return nil, err
+		}
+	}
+	q.Tags = []string{}
+	if tagsJSON != nil {
+		if err := json.Unmarshal(tagsJSON, &q.Tags); err != nil {
+			return nil, err
+		}
+	}
+	if refsJSON != nil {
+		if err := json.Unmarshal(refsJSON, &q.References); err != nil {
+			return nil, err
+		}
+	}
 	return &q, nil
 }
 
-// GetRandomQuestionForUser returns one random question for the given difficulty that the user
-// has not been asked yet. If all at that difficulty were asked, returns any random question at that difficulty.
-// Uses a single join query so the question is returned directly without a second lookup.
-func (r *QuestionRepository) GetRandomQuestionForUser(userID int, difficulty int) (*models.Question, error) {
+// GetQuestionByID returns a question by ID (retired ones included), or nil if not found
+func (r *QuestionRepository) GetQuestionByID(id int) (*models.Question, error) {
+	query := `SELECT ` + questionColumns + ` FROM questions q WHERE q.id = ?`
+	q, err := scanQuestion(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return q, err
+}
+
+// GetRandomQuestionForUser returns one random question matching sel, preferring ones the user
+// has not been asked yet and falling back to the closest level that has one.
+func (r *QuestionRepository) GetRandomQuestionForUser(userID int, sel QuestionSelection) (*models.Question, error) {
+	filter, filterArgs := sel.filter()
+	if sel.ByRating {
+		return r.getNearestRatedQuestionForUser(userID, sel.TargetRating, filter, filterArgs)
+	}
+
+	difficulty := sel.Difficulty
 	if difficulty < 1 {
 		difficulty = 1
 	}
@@ -46,25 +122,347 @@ func (r *QuestionRepository) GetRandomQuestionForUser(userID int, difficulty int
 	}
 
 	// Prefer questions not yet asked; fallback to any at this difficulty
-	query := `SELECT q.id, q.difficulty, q.question, q.options, q.answer
+	query := `SELECT ` + questionColumns + `
 	          FROM questions q
-	          WHERE q.difficulty = ?
+	          WHERE q.difficulty = ?` + filter + `
 	          AND NOT EXISTS (SELECT 1 FROM user_questions uq WHERE uq.user_id = ? AND uq.question_id = q.id)
 	          ORDER BY RAND()
 	          LIMIT 1`
-	var q models.Question
-	var optionsJSON []byte
-	err := r.db.QueryRow(query, difficulty, userID).Scan(&q.ID, &q.Difficulty, &q.Question, &optionsJSON, &q.Answer)
+	q, err := scanQuestion(r.db.QueryRow(query, append(append([]interface{}{difficulty}, filterArgs...), userID)...))
 	if err == sql.ErrNoRows {
 		// All asked at this difficulty: allow repeats
-		queryRepeat := `SELECT id, difficulty, question, options, answer FROM questions WHERE difficulty = ? ORDER BY RAND() LIMIT 1`
-		err = r.db.QueryRow(queryRepeat, difficulty).Scan(&q.ID, &q.Difficulty, &q.Question, &optionsJSON, &q.Answer)
+		queryRepeat := `SELECT ` + questionColumns + ` FROM questions q WHERE q.difficulty = ?` + filter + ` ORDER BY RAND() LIMIT 1`
+		q, err = scanQuestion(r.db.QueryRow(queryRepeat, append([]interface{}{difficulty}, filterArgs...)...))
+	}
+	if err == sql.ErrNoRows {
+		// Nothing at this difficulty: closest level, unasked first
+		queryNearest := `SELECT ` + questionColumns + `
+		                 FROM questions q
+		                 WHERE 1 = 1` + filter + `
+		                 ORDER BY ABS(q.difficulty - ?),
+		                   EXISTS (SELECT 1 FROM user_questions uq WHERE uq.user_id = ? AND uq.question_id = q.id),
+		                   RAND()
+		                 LIMIT 1`
+		q, err = scanQuestion(r.db.QueryRow(queryNearest, append(filterArgs, difficulty, userID)...))
 	}
+	return q, err
+}
+
+// GetDueQuestionForUser returns the user's most overdue question in practice mode: the one whose
+// question_memory due time (at or before now) is oldest, among servable questions matching sel's
+// category and tags. Returns nil if none is due.
+func (r *QuestionRepository) GetDueQuestionForUser(userID int, now time.Time, sel QuestionSelection) (*models.Question, error) {
+	filter, filterArgs := sel.filter()
+	query := `SELECT ` + questionColumns + `
+	          FROM question_memory m
+	          JOIN questions q ON q.id = m.question_id
+	          WHERE m.user_id = ? AND m.due_at <= ?` + filter + `
+	          ORDER BY m.due_at
+	          LIMIT 1`
+	q, err := scanQuestion(r.db.QueryRow(query, append([]interface{}{userID, now}, filterArgs...)...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return q, err
+}
+
+// getNearestRatedQuestionForUser picks at random among the nearestRatedPool unasked questions
+// (narrowed by filter) whose rating is closest to target; falls back to all matching questions
+// once every one was asked.
+func (r *QuestionRepository) getNearestRatedQuestionForUser(userID int, target float64, filter string, filterArgs []interface{}) (*models.Question, error) {
+	query := `SELECT * FROM (
+	            SELECT ` + questionColumns + `
+	            FROM questions q
+	            WHERE NOT EXISTS (SELECT 1 FROM user_questions uq WHERE uq.user_id = ? AND uq.question_id = q.id)` + filter + `
+	            ORDER BY ABS(` + questionRatingSQL + ` - ?)
+	            LIMIT ?
+	          ) nearest ORDER BY RAND() LIMIT 1`
+	args := append(append([]interface{}{userID}, filterArgs...), target, nearestRatedPool)
+	q, err := scanQuestion(r.db.QueryRow(query, args...))
+	if err == sql.ErrNoRows {
+		queryRepeat := `SELECT * FROM (
+		                  SELECT ` + questionColumns + `
+		                  FROM questions q
+		                  WHERE 1 = 1` + filter + `
+		                  ORDER BY ABS(` + questionRatingSQL + ` - ?)
+		                  LIMIT ?
+		                ) nearest ORDER BY RAND() LIMIT 1`
+		q, err = scanQuestion(r.db.QueryRow(queryRepeat, append(filterArgs, target, nearestRatedPool)...))
+	}
+	return q, err
+}
+
+// ListCategories returns every category with its number of servable questions, by name.
+func (r *QuestionRepository) ListCategories() ([]CategoryCount, error) {
+	rows, err := r.db.Query(`SELECT category, COUNT(*) FROM questions WHERE retired_at IS NULL GROUP BY category ORDER BY category`)
 	if err != nil {
 		return nil, err
 	}
-	if err := json.Unmarshal(optionsJSON, &q.Options); err != nil {
+	defer rows.Close()
+
+	categories := []CategoryCount{}
+	for rows.Next() {
+		var c CategoryCount
+		if err := rows.Scan(&c.Category, &c.Questions); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+// QuestionListFilter selects a page of the question bank for ListQuestions.
+type QuestionListFilter struct {
+	Category       string
+	Difficulty     int // 0 = any
+	IncludeRetired bool
+	Offset         int
+	Limit          int
+}
+
+// ListQuestions returns a page of questions matching filter, by id.
+func (r *QuestionRepository) ListQuestions(filter QuestionListFilter) ([]models.Question, error) {
+	query := `SELECT ` + questionColumns + ` FROM questions q WHERE 1 = 1`
+	var args []interface{}
+	if filter.Category != "" {
+		query += ` AND q.category = ?`
+		args = append(args, filter.Category)
+	}
+	if filter.Difficulty != 0 {
+		query += ` AND q.difficulty = ?`
+		args = append(args, filter.Difficulty)
+	}
+	if !filter.IncludeRetired {
+		query += ` AND q.retired_at IS NULL`
+	}
+	query += ` ORDER BY q.id LIMIT ? OFFSET ?`
+	rows, err := r.db.Query(query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
 		return nil, err
 	}
-	return &q, nil
+	defer rows.Close()
+
+	questions := []models.Question{}
+	for rows.Next() {
+		q, err := scanQuestion(rows)
+		if err != nil {
+			return nil, err
+		}
+		questions = append(questions, *q)
+	}
+	return questions, rows.Err()
+}
+
+// CreateQuestion inserts a question and sets its ID. Its rating starts at the default for its level.
+func (r *QuestionRepository) CreateQuestion(q *models.Question) error {
+	args, err := questionArgs(q)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO questions (` + questionWriteColumns + `) VALUES ` + questionWriteValues
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	q.ID = int(id)
+	q.Rating = RatingBase + RatingPerLevel*float64(q.Difficulty)
+	return nil
+}
+
+// UpdateQuestion replaces the content of a question. Its rating is reset to the level default
+// when the difficulty changes. Returns sql.ErrNoRows if the question does not exist.
+func (r *QuestionRepository) UpdateQuestion(q *models.Question) error {
+	args, err := questionArgs(q)
+	if err != nil {
+		return err
+	}
+	// Assignments run left to right, so rating still sees the old difficulty.
+	query := `UPDATE questions SET rating = IF(difficulty = ?, rating, NULL), type = ?, difficulty = ?,
+	          question = ?, options = ?, answer = ?, tolerance = ?, partial_credit = ?, aliases = ?,
+	          category = ?, tags = ?, explanation = ?, refs = ?
+	          WHERE id = ?`
+	_, err = r.db.Exec(query, append(append([]interface{}{q.Difficulty}, args...), q.ID)...)
+	if err != nil {
+		return err
+	}
+	return r.questionExists(q.ID)
+}
+
+// RetireQuestion withdraws a question from serving as of at (a retired question keeps its first
+// retirement time). Returns sql.ErrNoRows if the question does not exist.
+func (r *QuestionRepository) RetireQuestion(id int, at time.Time) error {
+	if _, err := r.db.Exec(`UPDATE questions SET retired_at = COALESCE(retired_at, ?) WHERE id = ?`, at, id); err != nil {
+		return err
+	}
+	return r.questionExists(id)
+}
+
+// importBatchSize is how many questions CreateQuestions inserts per statement.
+const importBatchSize = 500
+
+// CreateQuestions inserts questions in one transaction: either all are added or none.
+func (r *QuestionRepository) CreateQuestions(questions []*models.Question) error {
+	return runInTx(r.db, func(tx *sql.Tx) error {
+		for start := 0; start < len(questions); start += importBatchSize {
+			end := start + importBatchSize
+			if end > len(questions) {
+				end = len(questions)
+			}
+			query := `INSERT INTO questions (` + questionWriteColumns + `) VALUES `
+			args := make([]interface{}, 0, questionWriteCount*(end-start))
+			for i, q := range questions[start:end] {
+				qArgs, err := questionArgs(q)
+				if err != nil {
+					return err
+				}
+				if i > 0 {
+					query += ","
+				}
+				query += questionWriteValues
+				args = append(args, qArgs...)
+			}
+			if _, err := tx.Exec(query, args...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListQuestionTexts returns the text of every question, retired ones included, by id.
+func (r *QuestionRepository) ListQuestionTexts() (map[int]string, error) {
+	rows, err := r.db.Query(`SELECT id, question FROM questions`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	texts := map[int]string{}
+	for rows.Next() {
+		var id int
+		var text string
+		if err := rows.Scan(&id, &text); err != nil {
+			return nil, err
+		}
+		texts[id] = text
+	}
+	return texts, rows.Err()
+}
+
+// questionExists returns sql.ErrNoRows if there is no question with id (an UPDATE that changed
+// nothing reports no affected rows either way).
+func (r *QuestionRepository) questionExists(id int) error {
+	var one int
+	return r.db.QueryRow(`SELECT 1 FROM questions WHERE id = ?`, id).Scan(&one)
+}
+
+// questionWriteColumns are the columns written from a question, in questionArgs order;
+// questionWriteValues has a placeholder for each.
+const (
+	questionWriteColumns = `type, difficulty, question, options, answer, tolerance, partial_credit, aliases, category, tags,
+	          explanation, refs`
+	questionWriteValues = `(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	questionWriteCount  = 12
+)
+
+// questionArgs returns the values of questionWriteColumns for q. JSON columns are sent as
+// strings (MySQL refuses JSON sent as binary); empty aliases, tags, explanation and references
+// store NULL.
+func questionArgs(q *models.Question) ([]interface{}, error) {
+	options, err := json.Marshal(q.Options)
+	if err != nil {
+		return nil, err
+	}
+	aliases, err := nullableJSON(q.Aliases)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := nullableJSON(q.Tags)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := nullableJSON(q.References)
+	if err != nil {
+		return nil, err
+	}
+	explanation := sql.NullString{String: q.Explanation, Valid: q.Explanation != ""}
+	return []interface{}{q.Type, q.Difficulty, q.Question, string(options), q.Answer, q.Tolerance,
+		q.PartialCredit, aliases, q.Category, tags, explanation, refs}, nil
+}
+
+// nullableJSON encodes a list for a nullable JSON column: NULL when empty.
+func nullableJSON(list []string) (interface{}, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(list)
+	return string(b), err
+}
+
+// AdjustQuestionRating adds delta to a question's rating inside tx. The increment is applied
+// in SQL so concurrent answers to the same question never overwrite each other.
+func (r *QuestionRepository) AdjustQuestionRating(tx *sql.Tx, questionID int, delta float64) error {
+	query := `UPDATE questions SET rating = COALESCE(rating, ` + defaultRatingSQL("difficulty") + `) + ? WHERE id = ?`
+	_, err := tx.Exec(query, delta, questionID)
+	return err
+}
+
+// QuestionAnswerStats aggregates a question's answer history. Ability is each answering user's
+// overall accuracy (total_correct / total_answered); the means and SD feed a point-biserial correlation.
+type QuestionAnswerStats struct {
+	QuestionID       int
+	Difficulty       int
+	Answers          int
+	Correct          int
+	MeanAbilityRight float64
+	MeanAbilityWrong float64
+	AbilityStdDev    float64
+}
+
+// GetAnswerStats returns answer statistics for every question with at least one recorded answer
+// outside practice mode.
+func (r *QuestionRepository) GetAnswerStats() ([]QuestionAnswerStats, error) {
+	query := `SELECT q.id, q.difficulty, COUNT(*) AS answers, SUM(ua.is_correct) AS correct,
+	          COALESCE(AVG(CASE WHEN ua.is_correct = 1 THEN u.total_correct / u.total_answered END), 0),
+	          COALESCE(AVG(CASE WHEN ua.is_correct = 0 THEN u.total_correct / u.total_answered END), 0),
+	          COALESCE(STDDEV_POP(u.total_correct / u.total_answered), 0)
+	          FROM user_answers ua
+	          JOIN questions q ON q.id = ua.question_id
+	          JOIN users u ON u.id = ua.user_id AND u.total_answered > 0
+	          WHERE ua.` + rankedAnswer + `
+	          GROUP BY q.id, q.difficulty
+	          ORDER BY q.id`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []QuestionAnswerStats
+	for rows.Next() {
+		var st QuestionAnswerStats
+		if err := rows.Scan(&st.QuestionID, &st.Difficulty, &st.Answers, &st.Correct,
+			&st.MeanAbilityRight, &st.MeanAbilityWrong, &st.AbilityStdDev); err != nil {
+			return nil, err
+		}
+		stats = append(stats, st)
+	}
+	return stats, rows.Err()
+}
+
+// UpdateDifficulties sets new difficulty labels (question id -> difficulty) in one transaction.
+// Ratings are reset so they re-derive from the new label.
+func (r *QuestionRepository) UpdateDifficulties(difficulties map[int]int) error {
+	return runInTx(r.db, func(tx *sql.Tx) error {
+		for id, difficulty := range difficulties {
+			if _, err := tx.Exec(`UPDATE questions SET difficulty = ?, rating = NULL WHERE id = ?`, difficulty, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
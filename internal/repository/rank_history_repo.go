@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"brainbolt/internal/models"
+	"database/sql"
+	"time"
+)
+
+// RankHistoryRepository persists periodic snapshots of every user's score and ranks in rank_snapshots
+type RankHistoryRepository struct {
+	db *sql.DB
+}
+
+// NewRankHistoryRepository creates a new rank history repository
+func NewRankHistoryRepository(db *sql.DB) *RankHistoryRepository {
+	return &RankHistoryRepository{db: db}
+}
+
+// HasSnapshot reports whether a snapshot was already taken at takenAt (by any instance)
+func (r *RankHistoryRepository) HasSnapshot(takenAt time.Time) (bool, error) {
+	var one int
+	err := r.db.QueryRow(`SELECT 1 FROM rank_snapshots WHERE taken_at = ? LIMIT 1`, takenAt).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// TakeSnapshot records every user's score, score rank and streak rank at takenAt in one statement,
+// ranking with the leaderboard order (see tiebreak.go). Rows already present for takenAt are kept,
+// so instances racing on the same snapshot do not duplicate it.
+func (r *RankHistoryRepository) TakeSnapshot(takenAt time.Time) (int64, error) {
+	query := `INSERT IGNORE INTO rank_snapshots (user_id, taken_at, score, score_rank, streak_rank)
+	          SELECT id, ?, score,
+	                 ROW_NUMBER() OVER (ORDER BY ` + userScoreOrder + ` DESC, CAST(id AS CHAR) DESC),
+	                 ROW_NUMBER() OVER (ORDER BY ` + userStreakOrder + ` DESC, CAST(id AS CHAR) DESC)
+	          FROM users`
+	result, err := r.db.Exec(query, takenAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ListSnapshots returns the user's snapshots in [from, to), oldest first; if there are more than
+// limit, the most recent limit are returned
+func (r *RankHistoryRepository) ListSnapshots(userID int, from, to time.Time, limit int) ([]models.RankSnapshot, error) {
+	query := `SELECT taken_at, score, score_rank, streak_rank FROM (
+	            SELECT taken_at, score, score_rank, streak_rank FROM rank_snapshots
+	            WHERE user_id = ? AND taken_at >= ? AND taken_at < ?
+	            ORDER BY taken_at DESC LIMIT ?
+	          ) t ORDER BY taken_at`
+	rows, err := r.db.Query(query, userID, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := []models.RankSnapshot{}
+	for rows.Next() {
+		var s models.RankSnapshot
+		if err := rows.Scan(&s.TakenAt, &s.Score, &s.ScoreRank, &s.StreakRank); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
+
+// PurgeBefore deletes snapshots taken before the given time and returns how many were removed
+func (r *RankHistoryRepository) PurgeBefore(before time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM rank_snapshots WHERE taken_at < ?`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
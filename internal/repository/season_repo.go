@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"brainbolt/internal/models"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrSeasonOverlap is returned when a new season would overlap an existing one.
+var ErrSeasonOverlap = errors.New("season overlaps an existing season")
+
+// standingsInsertBatch is how many standings rows go into one INSERT.
+const standingsInsertBatch = 500
+
+const seasonColumns = `id, name, starts_at, ends_at, archived_at`
+
+// SeasonRepository persists seasons and their archived final standings
+type SeasonRepository struct {
+	db *sql.DB
+}
+
+// NewSeasonRepository creates a new season repository
+func NewSeasonRepository(db *sql.DB) *SeasonRepository {
+	return &SeasonRepository{db: db}
+}
+
+// scanSeason reads one row selected with seasonColumns.
+func scanSeason(row rowScanner) (*models.Season, error) {
+	var season models.Season
+	var archivedAt sql.NullTime
+	if err := row.Scan(&season.ID, &season.Name, &season.StartsAt, &season.EndsAt, &archivedAt); err != nil {
+		return nil, err
+	}
+	if archivedAt.Valid {
+		season.ArchivedAt = &archivedAt.Time
+	}
+	return &season, nil
+}
+
+// querySeasons runs a query selecting seasonColumns
+func (r *SeasonRepository) querySeasons(query string, args ...interface{}) ([]models.Season, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seasons := []models.Season{}
+	for rows.Next() {
+		season, err := scanSeason(rows)
+		if err != nil {
+			return nil, err
+		}
+		seasons = append(seasons, *season)
+	}
+	return seasons, rows.Err()
+}
+
+// CreateSeason inserts a season unless it overlaps another one (ErrSeasonOverlap)
+func (r *SeasonRepository) CreateSeason(name string, startsAt, endsAt time.Time) (*models.Season, error) {
+	var season *models.Season
+	err := runInTx(r.db, func(tx *sql.Tx) error {
+		// Lock the overlapping range so two concurrent creates cannot both pass the check.
+		var overlapping int
+		err := tx.QueryRow(`SELECT COUNT(*) FROM seasons WHERE starts_at < ? AND ends_at > ? FOR UPDATE`,
+			endsAt, startsAt).Scan(&overlapping)
+		if err != nil {
+			return err
+		}
+		if overlapping > 0 {
+			return ErrSeasonOverlap
+		}
+		result, err := tx.Exec(`INSERT INTO seasons (name, starts_at, ends_at) VALUES (?, ?, ?)`, name, startsAt, endsAt)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		season = &models.Season{ID: int(id), Name: name, StartsAt: startsAt, EndsAt: endsAt}
+		return nil
+	})
+	return season, err
+}
+
+// GetSeason returns a season by id, or nil if not found
+func (r *SeasonRepository) GetSeason(id int) (*models.Season, error) {
+	season, err := scanSeason(r.db.QueryRow(`SELECT `+seasonColumns+` FROM seasons WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return season, err
+}
+
+// ListSeasons returns all seasons, newest first
+func (r *SeasonRepository) ListSeasons() ([]models.Season, error) {
+	return r.querySeasons(`SELECT ` + seasonColumns + ` FROM seasons ORDER BY starts_at DESC`)
+}
+
+// GetActiveSeason returns the unarchived season running at the given time, or nil if there is none
+func (r *SeasonRepository) GetActiveSeason(at time.Time) (*models.Season, error) {
+	season, err := scanSeason(r.db.QueryRow(`SELECT `+seasonColumns+` FROM seasons
+	          WHERE starts_at <= ? AND ends_at > ? AND archived_at IS NULL
+	          ORDER BY starts_at LIMIT 1`, at, at))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return season, err
+}
+
+// GetLatestSeason returns the season with the latest end, or nil if there are none
+func (r *SeasonRepository) GetLatestSeason() (*models.Season, error) {
+	season, err := scanSeason(r.db.QueryRow(`SELECT ` + seasonColumns + ` FROM seasons ORDER BY ends_at DESC LIMIT 1`))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return season, err
+}
+
+// ListEndedUnarchived returns seasons that ended at or before the given time but were not archived yet
+func (r *SeasonRepository) ListEndedUnarchived(at time.Time) ([]models.Season, error) {
+	return r.querySeasons(`SELECT `+seasonColumns+` FROM seasons
+	          WHERE ends_at <= ? AND archived_at IS NULL ORDER BY ends_at`, at)
+}
+
+// ArchiveSeason writes the final standings and marks the season archived, in one transaction.
+// It returns false (and writes nothing) if another instance archived the season first.
+func (r *SeasonRepository) ArchiveSeason(seasonID int, standings []models.SeasonStanding, at time.Time) (bool, error) {
+	archived := false
+	err := runInTx(r.db, func(tx *sql.Tx) error {
+		var archivedAt sql.NullTime
+		err := tx.QueryRow(`SELECT archived_at FROM seasons WHERE id = ? FOR UPDATE`, seasonID).Scan(&archivedAt)
+		if err != nil {
+			return err
+		}
+		if archivedAt.Valid {
+			return nil
+		}
+		for start := 0; start < len(standings); start += standingsInsertBatch {
+			end := start + standingsInsertBatch
+			if end > len(standings) {
+				end = len(standings)
+			}
+			query := `INSERT INTO season_standings (season_id, board, season_rank, user_id, username, value, reward) VALUES `
+			args := make([]interface{}, 0, 7*(end-start))
+			for i, s := range standings[start:end] {
+				if i > 0 {
+					query += ","
+				}
+				query += "(?, ?, ?, ?, ?, ?, NULLIF(?, ''))"
+				args = append(args, seasonID, s.Board, s.Rank, s.UserID, s.Username, s.Value, s.Reward)
+			}
+			if _, err := tx.Exec(query, args...); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec(`UPDATE seasons SET archived_at = ? WHERE id = ?`, at, seasonID); err != nil {
+			return err
+		}
+		archived = true
+		return nil
+	})
+	return archived, err
+}
+
+// ListStandings returns a page of a season's archived board ("score" or "streak"), by rank
+func (r *SeasonRepository) ListStandings(seasonID int, board string, offset, limit int) ([]models.SeasonStanding, error) {
+	rows, err := r.db.Query(`SELECT season_id, board, season_rank, COALESCE(user_id, 0), username, value, COALESCE(reward, '')
+	          FROM season_standings WHERE season_id = ? AND board = ?
+	          ORDER BY season_rank LIMIT ? OFFSET ?`, seasonID, board, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	standings := []models.SeasonStanding{}
+	for rows.Next() {
+		var s models.SeasonStanding
+		if err := rows.Scan(&s.SeasonID, &s.Board, &s.Rank, &s.UserID, &s.Username, &s.Value, &s.Reward); err != nil {
+			return nil, err
+		}
+		standings = append(standings, s)
+	}
+	return standings, rows.Err()
+}
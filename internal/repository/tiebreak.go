@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Leaderboard ties are broken by who reached the value first, identically in Redis and MySQL.
+//
+// A ZSET score packs both parts into one exact float64 integer:
+//
+//	value*tieBreakSlots + (tieBreakSlots-1 - seconds from tieBreakEpoch to reachedAt)
+//
+// so a higher value wins and, for equal values, the earlier second wins. A missing reachedAt
+// counts as the epoch. Members with the same packed score (same value reached in the same
+// second) keep Redis's order for equal scores in ZREVRANGE (reverse lexicographic member), which
+// the SQL queries mirror with CAST(id AS CHAR) DESC. Values are clamped to ±MaxBoardValue so the
+// packed score stays below 2^53, where float64 integers are exact.
+//
+// The slots run out at tieBreakHorizon (2033-07-04 21:24:16 UTC): values reached later all get
+// the last slot, so their ties fall back to member order. Both clamps are logged the first time
+// they are hit; moving the epoch means bumping BoardFormatVersion so the boards are rebuilt.
+const (
+	tieBreakSlots    = 1 << 28 // seconds, about 8.5 years from tieBreakEpoch
+	tieBreakSQLEpoch = "2025-01-01 00:00:00"
+	MaxBoardValue    = 1<<24 - 1
+)
+
+var (
+	tieBreakEpoch   = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tieBreakHorizon = tieBreakEpoch.Add(tieBreakSlots * time.Second)
+
+	// horizonLogged and capLogged keep the clamp warnings to one per process.
+	horizonLogged atomic.Bool
+	capLogged     atomic.Bool
+)
+
+// BoardFormatVersion identifies the packed-score format and set of boards; WarmUp rebuilds boards
+// written with another format.
+const (
+	BoardFormatVersion   = "4"
+	LeaderboardFormatKey = "leaderboard:format"
+)
+
+// tieBreakSlot returns the seconds from tieBreakEpoch to reachedAt, clamped to the slot range.
+// Times are rounded to milliseconds first, as MySQL does when storing DATETIME(3).
+func tieBreakSlot(reachedAt *time.Time) int64 {
+	if reachedAt == nil {
+		return 0
+	}
+	seconds := int64(reachedAt.Round(time.Millisecond).Sub(tieBreakEpoch) / time.Second)
+	if seconds < 0 {
+		return 0
+	}
+	if seconds > tieBreakSlots-1 {
+		if horizonLogged.CompareAndSwap(false, true) {
+			log.Printf("Leaderboard tie-break horizon %s passed: ties on values reached since are ordered by user id (move tieBreakEpoch)",
+				tieBreakHorizon.Format(time.RFC3339))
+		}
+		return tieBreakSlots - 1
+	}
+	return seconds
+}
+
+// clampBoardValue limits a leaderboard value to ±MaxBoardValue.
+func clampBoardValue(value int64) int64 {
+	if value > MaxBoardValue || value < -MaxBoardValue {
+		if capLogged.CompareAndSwap(false, true) {
+			log.Printf("Leaderboard value %d is beyond the ±%d the boards can hold; it is ranked and shown as the cap", value, MaxBoardValue)
+		}
+		return max(-MaxBoardValue, min(value, MaxBoardValue))
+	}
+	return value
+}
+
+// EncodeBoardScore packs a leaderboard value and the time it was reached into a ZSET score.
+func EncodeBoardScore(value int64, reachedAt *time.Time) float64 {
+	return float64(encodeBoardKey(value, reachedAt))
+}
+
+// encodeBoardKey is EncodeBoardScore as an integer, for comparisons in SQL.
+func encodeBoardKey(value int64, reachedAt *time.Time) int64 {
+	return clampBoardValue(value)*tieBreakSlots + tieBreakSlots - 1 - tieBreakSlot(reachedAt)
+}
+
+// DecodeBoardScore returns the leaderboard value of a packed ZSET score.
+func DecodeBoardScore(score float64) int64 {
+	return int64(math.Floor(score / tieBreakSlots))
+}
+
+// boardScoreSQL is the SQL equivalent of EncodeBoardScore for a value and reached-at expression.
+func boardScoreSQL(value, reachedAt string) string {
+	return fmt.Sprintf("(LEAST(GREATEST(%s, %d), %d) * %d + %d - LEAST(GREATEST(COALESCE(TIMESTAMPDIFF(SECOND, '%s', %s), 0), 0), %d))",
+		value, -MaxBoardValue, MaxBoardValue, tieBreakSlots, tieBreakSlots-1, tieBreakSQLEpoch, reachedAt, tieBreakSlots-1)
+}
+
+// BoardValue is one leaderboard member with the time its value was reached.
+type BoardValue struct {
+	UserID    int
+	Value     int64
+	ReachedAt *time.Time
+}
+
+// Encode returns the packed ZSET score of v.
+func (v BoardValue) Encode() float64 {
+	return EncodeBoardScore(v.Value, v.ReachedAt)
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+)
+
+// maxTxAttempts bounds how often runInTx re-runs a transaction that hit a deadlock or lock wait timeout.
+const maxTxAttempts = 3
+
+// runInTx runs fn inside a transaction on db, committing when fn returns nil and rolling back otherwise.
+// Deadlocks and lock wait timeouts are retried with a short backoff; if every attempt loses,
+// ErrTxConflict is returned. fn must therefore be safe to run more than once.
+func runInTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	for attempt := 1; ; attempt++ {
+		err := runTxOnce(db, fn)
+		if err == nil || !isRetryableTxError(err) {
+			return err
+		}
+		if attempt == maxTxAttempts {
+			return ErrTxConflict
+		}
+		time.Sleep(time.Duration(attempt) * 10 * time.Millisecond)
+	}
+}
+
+func runTxOnce(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
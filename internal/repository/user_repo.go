@@ -3,8 +3,81 @@ package repository
 import (
 	"brainbolt/internal/models"
 	"database/sql"
+	"fmt"
+	"time"
 )
 
+// Ratings that were never set default to the rating of the row's difficulty level,
+// RatingBase + RatingPerLevel*difficulty (level 1 = 700, level 10 = 1600).
+const (
+	RatingBase     = 600.0
+	RatingPerLevel = 100.0
+)
+
+// defaultRatingSQL is the SQL expression of the default rating for a difficulty level expression.
+func defaultRatingSQL(level string) string {
+	return fmt.Sprintf("(%g + %g * %s)", RatingBase, RatingPerLevel, level)
+}
+
+// userColumns is the column list every users query selects; scanUser reads it in this order.
+var userColumns = `id, username, score, streak, max_streak, total_correct, total_answered, 
+	          COALESCE(current_difficulty, 0) as current_difficulty, last_answered_at,
+	          COALESCE(rating, ` + defaultRatingSQL("COALESCE(current_difficulty, 1)") + `) as rating, difficulty_progress,
+	          score_reached_at, max_streak_reached_at,
+	          max_difficulty, max_difficulty_reached_at, streak_decayed_at`
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser reads one row selected with userColumns.
+func scanUser(row rowScanner) (*models.User, error) {
+	var user models.User
+	var lastAnsweredAt, scoreReachedAt, maxStreakReachedAt, maxDifficultyReachedAt, streakDecayedAt sql.NullTime
+	err := row.Scan(
+		&user.ID, &user.Username, &user.Score, &user.Streak, &user.MaxStreak,
+		&user.TotalCorrect, &user.TotalAnswered, &user.CurrentDifficulty, &lastAnsweredAt,
+		&user.Rating, &user.DifficultyProgress, &scoreReachedAt, &maxStreakReachedAt,
+		&user.MaxDifficulty, &maxDifficultyReachedAt, &streakDecayedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if lastAnsweredAt.Valid {
+		user.LastAnsweredAt = &lastAnsweredAt.Time
+	}
+	if scoreReachedAt.Valid {
+		user.ScoreReachedAt = &scoreReachedAt.Time
+	}
+	if maxStreakReachedAt.Valid {
+		user.MaxStreakReachedAt = &maxStreakReachedAt.Time
+	}
+	if maxDifficultyReachedAt.Valid {
+		user.MaxDifficultyReachedAt = &maxDifficultyReachedAt.Time
+	}
+	if streakDecayedAt.Valid {
+		user.StreakDecayedAt = &streakDecayedAt.Time
+	}
+	return &user, nil
+}
+
+// scanUsers reads all rows selected with userColumns and closes rows.
+func scanUsers(rows *sql.Rows) ([]models.User, error) {
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+
+	return users, rows.Err()
+}
+
 // UserRepository handles all database operations for users
 type UserRepository struct {
 	db *sql.DB
@@ -17,34 +90,36 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 
 // GetUserByID retrieves a user by id
 func (r *UserRepository) GetUserByID(id int) (*models.User, error) {
-	var user models.User
-	var lastAnsweredAt sql.NullTime
-	query := `SELECT id, username, score, streak, max_streak, total_correct, total_answered, 
-	          COALESCE(current_difficulty, 0) as current_difficulty, last_answered_at 
+	query := `SELECT ` + userColumns + `
 	          FROM users WHERE id = ?`
 
-	err := r.db.QueryRow(query, id).Scan(
-		&user.ID, &user.Username, &user.Score, &user.Streak, &user.MaxStreak,
-		&user.TotalCorrect, &user.TotalAnswered, &user.CurrentDifficulty, &lastAnsweredAt,
-	)
+	return scanUser(r.db.QueryRow(query, id))
+}
 
-	if err != nil {
-		return nil, err
-	}
+// RunInTx runs fn in a transaction on the users database, retrying on deadlocks and
+// lock wait timeouts (see runInTx). Returns ErrTxConflict if every attempt lost.
+func (r *UserRepository) RunInTx(fn func(tx *sql.Tx) error) error {
+	return runInTx(r.db, fn)
+}
 
-	if lastAnsweredAt.Valid {
-		user.LastAnsweredAt = &lastAnsweredAt.Time
-	}
+// GetUserByIDForUpdate reads a user inside tx and locks the row (SELECT ... FOR UPDATE)
+// until the transaction ends, so concurrent writers for the same user are serialized.
+func (r *UserRepository) GetUserByIDForUpdate(tx *sql.Tx, id int) (*models.User, error) {
+	query := `SELECT ` + userColumns + `
+	          FROM users WHERE id = ? FOR UPDATE`
 
-	return &user, nil
+	return scanUser(tx.QueryRow(query, id))
 }
 
 // CreateUser creates a new user with default values and returns the user with generated ID
 func (r *UserRepository) CreateUser(username string) (*models.User, error) {
-	insertQuery := `INSERT INTO users (username, score, streak, max_streak, total_correct, total_answered, current_difficulty) 
-	                VALUES (?, 0, 0, 0, 0, 0, 1)`
+	insertQuery := `INSERT INTO users (username, score, streak, max_streak, total_correct, total_answered, current_difficulty, max_difficulty) 
+	                VALUES (?, 0, 0, 0, 0, 0, 1, 1)`
 	result, err := r.db.Exec(insertQuery, username)
 	if err != nil {
+		if isDuplicateEntry(err) {
+			return nil, ErrDuplicateEntry
+		}
 		return nil, err
 	}
 
@@ -62,9 +137,73 @@ func (r *UserRepository) CreateUser(username string) (*models.User, error) {
 		TotalCorrect:      0,
 		TotalAnswered:     0,
 		CurrentDifficulty: 1,
+		MaxDifficulty:     1,
+		Rating:            RatingBase + RatingPerLevel,
 	}, nil
 }
 
+// UpdateUsername renames a user. Returns sql.ErrNoRows if the user does not exist
+// and ErrDuplicateEntry if the username is already taken.
+func (r *UserRepository) UpdateUsername(userID int, username string) error {
+	query := `UPDATE users SET username = ? WHERE id = ?`
+	result, err := r.db.Exec(query, username, userID)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			return ErrDuplicateEntry
+		}
+		return err
+	}
+	// MySQL reports 0 affected rows when the value is unchanged, so confirm the user exists.
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		var exists int
+		return r.db.QueryRow(`SELECT 1 FROM users WHERE id = ?`, userID).Scan(&exists)
+	}
+	return nil
+}
+
+// DeleteUser removes a user and their asked-question, issued-question and answer history (plus
+// rank, category and practice memory rows) in one transaction. Archived season standings are
+// kept, with user_id cleared by the foreign key.
+// Returns sql.ErrNoRows if the user does not exist.
+func (r *UserRepository) DeleteUser(userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM user_questions WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM user_answers WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM question_issues WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM rank_snapshots WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM user_category_levels WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM question_memory WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	result, err := tx.Exec(`DELETE FROM users WHERE id = ?`, userID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
 // UpdateUserDifficulty updates the user's current difficulty
 func (r *UserRepository) UpdateUserDifficulty(userID int, difficulty int) error {
 	query := `UPDATE users SET current_difficulty = ? WHERE id = ?`
@@ -72,88 +211,174 @@ func (r *UserRepository) UpdateUserDifficulty(userID int, difficulty int) error
 	return err
 }
 
-// UpdateUserStreak updates only the current streak (e.g. after time-based decay).
-func (r *UserRepository) UpdateUserStreak(userID int, streak int) error {
-	query := `UPDATE users SET streak = ? WHERE id = ?`
-	_, err := r.db.Exec(query, streak, userID)
-	return err
+// UpdateUserStreak stores a time-decayed streak and the decay boundary it was decayed up to,
+// unless the user answered after lastAnsweredAt (the answer already set a fresh streak).
+// Returns false if the row was not updated.
+func (r *UserRepository) UpdateUserStreak(userID int, streak int, decayedAt, lastAnsweredAt *time.Time) (bool, error) {
+	query := `UPDATE users SET streak = ?, streak_decayed_at = ? WHERE id = ? AND last_answered_at <=> ?`
+	var answeredAt interface{}
+	if lastAnsweredAt != nil {
+		// Cached users carry nanoseconds; the column holds them rounded to milliseconds.
+		answeredAt = lastAnsweredAt.Round(time.Millisecond)
+	}
+	result, err := r.db.Exec(query, streak, decayedAt, userID, answeredAt)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// ListDecayableStreaksAfter returns up to limit users with id > afterID, ordered by id, whose
+// streak is positive and has not been decayed since before (for the streak decay sweep)
+func (r *UserRepository) ListDecayableStreaksAfter(before time.Time, afterID int, limit int) ([]models.User, error) {
+	query := `SELECT ` + userColumns + `
+	          FROM users
+	          WHERE id > ? AND streak > 0 AND last_answered_at <= ?
+	            AND (streak_decayed_at IS NULL OR streak_decayed_at <= ?)
+	          ORDER BY id LIMIT ?`
+
+	rows, err := r.db.Query(query, afterID, before, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanUsers(rows)
 }
 
-// UpdateUserAfterAnswer updates user stats after answering a question
-func (r *UserRepository) UpdateUserAfterAnswer(userID int, user *models.User) error {
+// UpdateUserAfterAnswer updates user stats after answering a question, inside tx.
+// The row should have been read with GetUserByIDForUpdate in the same transaction.
+func (r *UserRepository) UpdateUserAfterAnswer(tx *sql.Tx, userID int, user *models.User) error {
 	query := `UPDATE users SET 
 	          score = ?, streak = ?, max_streak = ?, total_correct = ?, 
-	          total_answered = ?, current_difficulty = ?, last_answered_at = ? 
+	          total_answered = ?, current_difficulty = ?, last_answered_at = ?, 
+	          rating = ?, difficulty_progress = ?, score_reached_at = ?, max_streak_reached_at = ?, 
+	          max_difficulty = ?, max_difficulty_reached_at = ?, streak_decayed_at = ? 
 	          WHERE id = ?`
 
-	_, err := r.db.Exec(query, user.Score, user.Streak, user.MaxStreak,
+	_, err := tx.Exec(query, user.Score, user.Streak, user.MaxStreak,
 		user.TotalCorrect, user.TotalAnswered, user.CurrentDifficulty,
-		user.LastAnsweredAt, userID)
+		user.LastAnsweredAt, user.Rating, user.DifficultyProgress,
+		user.ScoreReachedAt, user.MaxStreakReachedAt,
+		user.MaxDifficulty, user.MaxDifficultyReachedAt, user.StreakDecayedAt, userID)
 	return err
 }
 
-// GetLeaderboardByScore returns top N users by score
-func (r *UserRepository) GetLeaderboardByScore(limit int) ([]models.User, error) {
-	query := `SELECT id, username, score, streak, max_streak, total_correct, total_answered, 
-	          COALESCE(current_difficulty, 0) as current_difficulty, last_answered_at 
-	          FROM users ORDER BY score DESC LIMIT ?`
+// Leaderboard order of users, matching the packed ZSET scores (see tiebreak.go).
+var (
+	userScoreOrder  = boardScoreSQL("score", "score_reached_at")
+	userStreakOrder = boardScoreSQL("max_streak", "max_streak_reached_at")
+	// Accuracy in basis points, last changed by the latest answer.
+	userAccuracyOrder = boardScoreSQL("(total_correct * 10000 DIV total_answered)", "last_answered_at")
+	// The current streak was last set by the latest answer or the latest decay, whichever is later.
+	userCurrentStreakOrder = boardScoreSQL("streak",
+		"GREATEST(COALESCE(last_answered_at, streak_decayed_at), COALESCE(streak_decayed_at, last_answered_at))")
+	userDifficultyOrder = boardScoreSQL("max_difficulty", "max_difficulty_reached_at")
+)
 
-	rows, err := r.db.Query(query, limit)
+// GetLeaderboardByScore returns N users by score, skipping the first offset
+func (r *UserRepository) GetLeaderboardByScore(offset, limit int) ([]models.User, error) {
+	return r.leaderboard(userScoreOrder, "", offset, limit)
+}
+
+// GetLeaderboardByStreak returns N users by max streak, skipping the first offset
+func (r *UserRepository) GetLeaderboardByStreak(offset, limit int) ([]models.User, error) {
+	return r.leaderboard(userStreakOrder, "", offset, limit)
+}
+
+// GetLeaderboardByAccuracy returns N users with at least minAnswers answers by accuracy, skipping the first offset
+func (r *UserRepository) GetLeaderboardByAccuracy(minAnswers, offset, limit int) ([]models.User, error) {
+	return r.leaderboard(userAccuracyOrder, "total_answered >= ?", offset, limit, minAnswers)
+}
+
+// GetLeaderboardByCurrentStreak returns N users by current (decayed) streak, skipping the first offset
+func (r *UserRepository) GetLeaderboardByCurrentStreak(offset, limit int) ([]models.User, error) {
+	return r.leaderboard(userCurrentStreakOrder, "", offset, limit)
+}
+
+// GetLeaderboardByDifficulty returns N users by highest difficulty reached, skipping the first offset
+func (r *UserRepository) GetLeaderboardByDifficulty(offset, limit int) ([]models.User, error) {
+	return r.leaderboard(userDifficultyOrder, "", offset, limit)
+}
+
+// leaderboard returns a page of the users matching where (all users if empty) by order.
+func (r *UserRepository) leaderboard(order, where string, offset, limit int, whereArgs ...interface{}) ([]models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users`
+	if where != "" {
+		query += ` WHERE ` + where
+	}
+	query += ` ORDER BY ` + order + ` DESC, CAST(id AS CHAR) DESC LIMIT ? OFFSET ?`
+
+	rows, err := r.db.Query(query, append(whereArgs, limit, offset)...)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return scanUsers(rows)
+}
 
-	var users []models.User
-	for rows.Next() {
-		var user models.User
-		var lastAnsweredAt sql.NullTime
-		err := rows.Scan(
-			&user.ID, &user.Username, &user.Score, &user.Streak, &user.MaxStreak,
-			&user.TotalCorrect, &user.TotalAnswered, &user.CurrentDifficulty, &lastAnsweredAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		if lastAnsweredAt.Valid {
-			user.LastAnsweredAt = &lastAnsweredAt.Time
-		}
-		users = append(users, user)
+// categoryLevelColumns is the column list every user_category_levels query selects; scanCategoryLevel reads it in this order.
+const categoryLevelColumns = `user_id, category, current_difficulty, difficulty_progress, rating, total_answered, total_correct`
+
+// scanCategoryLevel reads one row selected with categoryLevelColumns.
+func scanCategoryLevel(row rowScanner) (*models.CategoryLevel, error) {
+	var l models.CategoryLevel
+	if err := row.Scan(&l.UserID, &l.Category, &l.CurrentDifficulty, &l.DifficultyProgress, &l.Rating,
+		&l.TotalAnswered, &l.TotalCorrect); err != nil {
+		return nil, err
 	}
+	return &l, nil
+}
 
-	return users, rows.Err()
+// GetCategoryLevel returns the user's level in a question category, or nil if they never answered one
+func (r *UserRepository) GetCategoryLevel(userID int, category string) (*models.CategoryLevel, error) {
+	query := `SELECT ` + categoryLevelColumns + ` FROM user_category_levels WHERE user_id = ? AND category = ?`
+	level, err := scanCategoryLevel(r.db.QueryRow(query, userID, category))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return level, err
 }
 
-// GetLeaderboardByStreak returns top N users by max streak
-func (r *UserRepository) GetLeaderboardByStreak(limit int) ([]models.User, error) {
-	query := `SELECT id, username, score, streak, max_streak, total_correct, total_answered, 
-	          COALESCE(current_difficulty, 0) as current_difficulty, last_answered_at 
-	          FROM users ORDER BY max_streak DESC LIMIT ?`
+// GetCategoryLevelForUpdate reads the user's level in a category inside tx and locks the row;
+// returns nil if there is none yet
+func (r *UserRepository) GetCategoryLevelForUpdate(tx *sql.Tx, userID int, category string) (*models.CategoryLevel, error) {
+	query := `SELECT ` + categoryLevelColumns + ` FROM user_category_levels WHERE user_id = ? AND category = ? FOR UPDATE`
+	level, err := scanCategoryLevel(tx.QueryRow(query, userID, category))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return level, err
+}
 
-	rows, err := r.db.Query(query, limit)
+// SaveCategoryLevel inserts or updates the user's level in a category inside tx
+func (r *UserRepository) SaveCategoryLevel(tx *sql.Tx, level *models.CategoryLevel) error {
+	query := `INSERT INTO user_category_levels (` + categoryLevelColumns + `)
+	          VALUES (?, ?, ?, ?, ?, ?, ?)
+	          ON DUPLICATE KEY UPDATE current_difficulty = VALUES(current_difficulty),
+	            difficulty_progress = VALUES(difficulty_progress), rating = VALUES(rating),
+	            total_answered = VALUES(total_answered), total_correct = VALUES(total_correct)`
+	_, err := tx.Exec(query, level.UserID, level.Category, level.CurrentDifficulty, level.DifficultyProgress,
+		level.Rating, level.TotalAnswered, level.TotalCorrect)
+	return err
+}
+
+// ListCategoryLevels returns the user's levels in every category they answered, by category
+func (r *UserRepository) ListCategoryLevels(userID int) ([]models.CategoryLevel, error) {
+	query := `SELECT ` + categoryLevelColumns + ` FROM user_category_levels WHERE user_id = ? ORDER BY category`
+	rows, err := r.db.Query(query, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var users []models.User
+	levels := []models.CategoryLevel{}
 	for rows.Next() {
-		var user models.User
-		var lastAnsweredAt sql.NullTime
-		err := rows.Scan(
-			&user.ID, &user.Username, &user.Score, &user.Streak, &user.MaxStreak,
-			&user.TotalCorrect, &user.TotalAnswered, &user.CurrentDifficulty, &lastAnsweredAt,
-		)
+		level, err := scanCategoryLevel(rows)
 		if err != nil {
 			return nil, err
 		}
-		if lastAnsweredAt.Valid {
-			user.LastAnsweredAt = &lastAnsweredAt.Time
-		}
-		users = append(users, user)
+		levels = append(levels, *level)
 	}
-
-	return users, rows.Err()
+	return levels, rows.Err()
 }
 
 // GetAskedQuestionIDs returns a set of question IDs that have been asked to a user
@@ -184,22 +409,60 @@ func (r *UserRepository) RecordQuestionAsked(userID int, questionID int) error {
 	return err
 }
 
-// GetUserRankByScore returns the rank of a user by score (1-indexed)
+// GetUserRankByScore returns the rank of a user by score (1-indexed), with the leaderboard tie-break
 func (r *UserRepository) GetUserRankByScore(userID int) (int, error) {
-	query := `SELECT COUNT(*) + 1 FROM users WHERE score > (SELECT score FROM users WHERE id = ?)`
-	var rank int
-	err := r.db.QueryRow(query, userID).Scan(&rank)
-	return rank, err
+	return r.userRank(userScoreOrder, "", userID)
 }
 
-// GetUserRankByStreak returns the rank of a user by max streak (1-indexed)
+// GetUserRankByStreak returns the rank of a user by max streak (1-indexed), with the leaderboard tie-break
 func (r *UserRepository) GetUserRankByStreak(userID int) (int, error) {
-	query := `SELECT COUNT(*) + 1 FROM users WHERE max_streak > (SELECT max_streak FROM users WHERE id = ?)`
+	return r.userRank(userStreakOrder, "", userID)
+}
+
+// GetUserRankByAccuracy returns the rank of a user among users with at least minAnswers answers by
+// accuracy (1-indexed); the caller checks that the user qualifies
+func (r *UserRepository) GetUserRankByAccuracy(minAnswers, userID int) (int, error) {
+	return r.userRank(userAccuracyOrder, "total_answered >= ?", userID, minAnswers)
+}
+
+// GetUserRankByCurrentStreak returns the rank of a user by current streak (1-indexed)
+func (r *UserRepository) GetUserRankByCurrentStreak(userID int) (int, error) {
+	return r.userRank(userCurrentStreakOrder, "", userID)
+}
+
+// GetUserRankByDifficulty returns the rank of a user by highest difficulty reached (1-indexed)
+func (r *UserRepository) GetUserRankByDifficulty(userID int) (int, error) {
+	return r.userRank(userDifficultyOrder, "", userID)
+}
+
+// userRank counts the users matching where (all users if empty) ordered ahead of userID by order
+// (then CAST(id AS CHAR) DESC). The derived table me only exposes k and member, so the bare
+// columns in order and where refer to u.
+func (r *UserRepository) userRank(order, where string, userID int, whereArgs ...interface{}) (int, error) {
+	query := `SELECT COUNT(*) + 1 FROM users u
+	          JOIN (SELECT ` + order + ` AS k, CAST(id AS CHAR) AS member FROM users WHERE id = ?) me
+	          WHERE (` + order + ` > me.k
+	             OR (` + order + ` = me.k AND CAST(u.id AS CHAR) > me.member))`
+	if where != "" {
+		query += ` AND ` + where
+	}
 	var rank int
-	err := r.db.QueryRow(query, userID).Scan(&rank)
+	err := r.db.QueryRow(query, append([]interface{}{userID}, whereArgs...)...).Scan(&rank)
 	return rank, err
 }
 
+// ListUsersAfter returns up to limit users with id > afterID, ordered by id (for batch scans)
+func (r *UserRepository) ListUsersAfter(afterID int, limit int) ([]models.User, error) {
+	query := `SELECT ` + userColumns + `
+	          FROM users WHERE id > ? ORDER BY id LIMIT ?`
+
+	rows, err := r.db.Query(query, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanUsers(rows)
+}
+
 // GetUsersByIDs retrieves multiple users by their IDs (for batch lookup)
 func (r *UserRepository) GetUsersByIDs(ids []int) ([]models.User, error) {
 	if len(ids) == 0 {
@@ -217,32 +480,12 @@ func (r *UserRepository) GetUsersByIDs(ids []int) ([]models.User, error) {
 		args[i] = id
 	}
 
-	query := `SELECT id, username, score, streak, max_streak, total_correct, total_answered, 
-	          COALESCE(current_difficulty, 0) as current_difficulty, last_answered_at 
+	query := `SELECT ` + userColumns + `
 	          FROM users WHERE id IN (` + placeholders + `)`
 
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var users []models.User
-	for rows.Next() {
-		var user models.User
-		var lastAnsweredAt sql.NullTime
-		err := rows.Scan(
-			&user.ID, &user.Username, &user.Score, &user.Streak, &user.MaxStreak,
-			&user.TotalCorrect, &user.TotalAnswered, &user.CurrentDifficulty, &lastAnsweredAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		if lastAnsweredAt.Valid {
-			user.LastAnsweredAt = &lastAnsweredAt.Time
-		}
-		users = append(users, user)
-	}
-
-	return users, rows.Err()
+	return scanUsers(rows)
 }
@@ -4,146 +4,380 @@ import (
 	"brainbolt/internal/models"
 	"brainbolt/internal/repository"
 	"context"
+	"database/sql"
 	"log"
-	"sync"
 	"time"
 )
 
 // AnswerService handles answer submission business logic.
 type AnswerService struct {
 	userService     *UserService
+	questions       *QuestionBankService
 	questionRepo    *repository.QuestionRepository
-	lastAnswerRepo  *repository.LastAnswerRepository
 	userRepo        *repository.UserRepository
 	leaderboardRepo *repository.LeaderboardRepository
+	leaderboards    *LeaderboardService
 	userCacheRepo   *repository.UserCacheRepository
+	historyRepo     *repository.AnswerHistoryRepository
+	issueRepo       *repository.QuestionIssueRepository
+	memoryRepo      *repository.MemoryRepository
+	tokenSigner     *QuestionTokenSigner
+	difficulty      DifficultyStrategy
+	scoring         ScoringPolicy
 }
 
 // NewAnswerService creates a new answer service.
 func NewAnswerService(
 	userService *UserService,
+	questions *QuestionBankService,
 	questionRepo *repository.QuestionRepository,
-	lastAnswerRepo *repository.LastAnswerRepository,
 	userRepo *repository.UserRepository,
 	leaderboardRepo *repository.LeaderboardRepository,
+	leaderboards *LeaderboardService,
 	userCacheRepo *repository.UserCacheRepository,
+	historyRepo *repository.AnswerHistoryRepository,
+	issueRepo *repository.QuestionIssueRepository,
+	memoryRepo *repository.MemoryRepository,
+	tokenSigner *QuestionTokenSigner,
+	difficulty DifficultyStrategy,
+	scoring ScoringPolicy,
 ) *AnswerService {
 	return &AnswerService{
 		userService:     userService,
+		questions:       questions,
 		questionRepo:    questionRepo,
-		lastAnswerRepo:  lastAnswerRepo,
 		userRepo:        userRepo,
 		leaderboardRepo: leaderboardRepo,
+		leaderboards:    leaderboards,
 		userCacheRepo:   userCacheRepo,
+		historyRepo:     historyRepo,
+		issueRepo:       issueRepo,
+		memoryRepo:      memoryRepo,
+		tokenSigner:     tokenSigner,
+		difficulty:      difficulty,
+		scoring:         scoring,
 	}
 }
 
-// AdjustDifficulty adjusts the difficulty based on whether the last answer was correct (1-10).
-func (s *AnswerService) AdjustDifficulty(currentDifficulty int, lastAnswerCorrect bool) int {
-	if lastAnswerCorrect {
-		if currentDifficulty < 10 {
-			return currentDifficulty + 1
-		}
-		return 10
-	}
-	if currentDifficulty > 1 {
-		return currentDifficulty - 1
-	}
-	return 1
+// AnswerResult is the outcome of one accepted answer.
+type AnswerResult struct {
+	Correct bool
+	// Credit is the share of the question's points earned: 1 if correct, between 0 and 1 for a
+	// partly right multi-select answer.
+	Credit float64
+	// CorrectAnswer is the question's correct answer and Explanation and References its
+	// optional explanation and sources, for feedback.
+	CorrectAnswer *CorrectAnswer
+	Explanation   string
+	References    []string
+	User          *models.User
+	Score         ScoreBreakdown
+	// CategoryLevel is the user's new level in the question's category.
+	CategoryLevel *models.CategoryLevel
+	// Memory is the question's new spaced-repetition state, nil if the question is not in the
+	// user's practice memory (see tracksMemory).
+	Memory *models.MemoryState
 }
 
-// CalculateScore computes the score delta for a correct answer.
-func (s *AnswerService) CalculateScore(difficulty int, streak, totalCorrect, totalAnswered int) int64 {
-	baseScore := int64(difficulty * 10)
-	streakMultiplier := 1.0
-	if streak > 0 {
-		streakMultiplier = 1.0 + float64(streak)*0.1
-		if streakMultiplier > 2.0 {
-			streakMultiplier = 2.0
-		}
+// SubmitAnswer grades an answer to an issued question and updates user stats in one row-locked
+// transaction; Redis is refreshed after commit.
+func (s *AnswerService) SubmitAnswer(userID int, questionID int, answer string, token string) (*AnswerResult, error) {
+	claims, err := s.tokenSigner.Verify(token, time.Now())
+	if err != nil {
+		return nil, err
 	}
-	accuracy := 0.0
-	if totalAnswered > 0 {
-		accuracy = float64(totalCorrect) / float64(totalAnswered)
+	if claims.UserID != userID || claims.QuestionID != questionID {
+		return nil, ErrInvalidQuestionToken
+	}
+
+	question, err := s.questions.GetQuestion(questionID)
+	if err != nil {
+		return nil, ErrQuestionNotFound
 	}
-	accuracyMultiplier := 0.5 + (accuracy * 1.0)
-	return int64(float64(baseScore) * streakMultiplier * accuracyMultiplier)
-}
 
-// SubmitAnswer processes an answer submission and updates user stats.
-func (s *AnswerService) SubmitAnswer(userID int, questionID int, answer string) (bool, *models.User, error) {
+	var grade Grade
+	var served *models.Question
 	var user *models.User
-	var userErr error
-	var lastQ int
-	var lastFound bool
-	var lastErr error
-	var wg sync.WaitGroup
-	wg.Add(2)
-	go func() {
-		defer wg.Done()
-		user, userErr = s.userService.GetUserByID(userID)
-	}()
-	go func() {
-		defer wg.Done()
-		lastQ, lastFound, lastErr = s.lastAnswerRepo.GetLastAnsweredQuestionID(userID)
-	}()
-	wg.Wait()
-	if userErr != nil {
-		return false, nil, userErr
-	}
-
-	if s.userService.applyStreakDecay(user) {
-		_ = s.userRepo.UpdateUserStreak(userID, user.Streak)
-		if s.userCacheRepo != nil {
-			_ = s.userCacheRepo.Set(userID, user)
+	var categoryLevel *models.CategoryLevel
+	var memory *models.MemoryState
+	var breakdown ScoreBreakdown
+	var questionRatingDelta float64
+	var answeredAt time.Time
+	var practice bool
+	err = s.userRepo.RunInTx(func(tx *sql.Tx) error {
+		var err error
+		// Locking the user row first makes concurrent answers from the same user wait for each
+		// other; RunInTx retries deadlocks, and one that persists is ErrAnswerConflict below.
+		user, err = s.userRepo.GetUserByIDForUpdate(tx, userID)
+		if err != nil {
+			return err
 		}
-	}
 
-	if lastErr == nil && lastFound && lastQ == questionID {
-		return false, nil, ErrDuplicateAnswer
-	}
+		now := time.Now()
+		answeredAt = now
+		issue, err := s.issueRepo.GetIssueForUpdate(tx, claims.IssueID)
+		if err != nil {
+			return err
+		}
+		if issue == nil || issue.UserID != userID || issue.QuestionID != questionID {
+			return ErrInvalidQuestionToken
+		}
+		if issue.AnsweredAt != nil {
+			return ErrDuplicateAnswer
+		}
+		if !now.Before(issue.ExpiresAt) {
+			return ErrQuestionTokenExpired
+		}
+		if err := s.issueRepo.MarkIssueAnswered(tx, issue.ID, now); err != nil {
+			return err
+		}
 
-	question, err := s.questionRepo.GetQuestionByID(questionID)
-	if err != nil || question == nil {
-		return false, nil, ErrQuestionNotFound
-	}
+		served = shuffledQuestion(question, issue.ShuffleSeed)
+		grade = GradeAnswer(served, answer)
+		isCorrect := grade.Correct
+		recorded := storedAnswer(question, issue.ShuffleSeed, grade.Answer)
+		if recorded == "" {
+			recorded = normalizeAnswer(answer)
+		}
 
-	isCorrect := question.Answer == answer
+		categoryLevel, err = s.userRepo.GetCategoryLevelForUpdate(tx, userID, question.Category)
+		if err != nil {
+			return err
+		}
+		// Taken before the overall level moves: a first answer in a category starts from it.
+		categoryUser := withCategoryLevel(user, categoryLevel)
+
+		// Practice answers leave the user's stats, levels and the question's rating alone.
+		practice = issue.Mode == PracticeMode
+		streakBefore := user.Streak
+		if !practice {
+			s.userService.applyStreakDecay(user)
+			streakBefore = user.Streak
+
+			user.TotalAnswered++
+			if isCorrect {
+				user.TotalCorrect++
+				user.Streak++
+				if user.Streak > user.MaxStreak {
+					user.MaxStreak = user.Streak
+					user.MaxStreakReachedAt = &now
+				}
+			} else {
+				user.Streak = 0
+			}
+		}
+
+		breakdown = s.scoring.Score(ScoringContext{
+			User:         user,
+			Question:     question,
+			Correct:      isCorrect,
+			Credit:       grade.Credit,
+			TimeToAnswer: now.Sub(issue.IssuedAt),
+			Mode:         issue.Mode,
+		})
+
+		if practice {
+			if categoryLevel == nil {
+				categoryLevel = &models.CategoryLevel{UserID: userID, Category: question.Category, CurrentDifficulty: categoryUser.CurrentDifficulty}
+			}
+		} else {
+			user.Score += breakdown.Total
+			if breakdown.Total != 0 {
+				user.ScoreReachedAt = &now
+			}
+
+			questionRatingDelta = s.difficulty.Apply(user, question, isCorrect)
+			if user.CurrentDifficulty > user.MaxDifficulty {
+				user.MaxDifficulty = user.CurrentDifficulty
+				user.MaxDifficultyReachedAt = &now
+			}
+			user.LastAnsweredAt = &now
+
+			// The category level moves the same way; the question's rating only moves once, above.
+			s.difficulty.Apply(categoryUser, question, isCorrect)
+			categoryLevel = nextCategoryLevel(categoryLevel, categoryUser, question.Category, isCorrect)
+			if err := s.userRepo.SaveCategoryLevel(tx, categoryLevel); err != nil {
+				return err
+			}
+
+			if questionRatingDelta != 0 {
+				if err := s.questionRepo.AdjustQuestionRating(tx, questionID, questionRatingDelta); err != nil {
+					return err
+				}
+			}
+			if err := s.userRepo.UpdateUserAfterAnswer(tx, userID, user); err != nil {
+				return err
+			}
+		}
 
-	user.TotalAnswered++
-	if isCorrect {
-		user.TotalCorrect++
-		user.Streak++
-		if user.Streak > user.MaxStreak {
-			user.MaxStreak = user.Streak
+		memory, err = s.memoryRepo.GetMemoryForUpdate(tx, userID, questionID)
+		if err != nil {
+			return err
 		}
-	} else {
-		user.Streak = 0
+		if tracksMemory(memory, issue.Mode, isCorrect) {
+			memory = nextMemory(memory, userID, questionID, reviewQuality(grade, now.Sub(issue.IssuedAt)), now)
+			if err := s.memoryRepo.SaveMemory(tx, memory); err != nil {
+				return err
+			}
+		}
+		return s.historyRepo.RecordAnswer(tx, &models.AnswerRecord{
+			UserID:       userID,
+			QuestionID:   questionID,
+			Answer:       recorded,
+			IsCorrect:    isCorrect,
+			Mode:         issue.Mode,
+			ShuffleSeed:  issue.ShuffleSeed,
+			Difficulty:   question.Difficulty,
+			ScoreDelta:   breakdown.Total,
+			StreakBefore: streakBefore,
+			StreakAfter:  user.Streak,
+			AnsweredAt:   now,
+		})
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		if err == repository.ErrTxConflict {
+			return nil, ErrAnswerConflict
+		}
+		return nil, err
 	}
 
-	if isCorrect {
-		scoreDelta := s.CalculateScore(question.Difficulty, user.Streak, user.TotalCorrect, user.TotalAnswered)
-		user.Score += scoreDelta
+	if !practice {
+		pipe := s.leaderboardRepo.Pipeline()
+		if s.userCacheRepo != nil {
+			_ = s.userCacheRepo.QueueSet(pipe, userID, user)
+		}
+		s.leaderboards.QueueAnswer(pipe, user, breakdown.Total, answeredAt)
+		if questionRatingDelta != 0 {
+			s.questions.queueInvalidate(pipe, questionID)
+		}
+		if _, err := pipe.Exec(context.Background()); err != nil {
+			log.Printf("Redis pipeline Exec failed for userID %d: %v", userID, err)
+		}
 	}
 
-	user.CurrentDifficulty = s.AdjustDifficulty(user.CurrentDifficulty, isCorrect)
-	now := time.Now()
-	user.LastAnsweredAt = &now
+	return &AnswerResult{
+		Correct:       grade.Correct,
+		Credit:        grade.Credit,
+		CorrectAnswer: correctAnswer(served),
+		Explanation:   question.Explanation,
+		References:    question.References,
+		User:          user,
+		Score:         breakdown,
+		CategoryLevel: categoryLevel,
+		Memory:        memory,
+	}, nil
+}
+
+// nextCategoryLevel is the category level after an answer, with the difficulty state the strategy
+// left on categoryUser.
+func nextCategoryLevel(prev *models.CategoryLevel, categoryUser *models.User, category string, correct bool) *models.CategoryLevel {
+	level := &models.CategoryLevel{UserID: categoryUser.ID, Category: category}
+	if prev != nil {
+		*level = *prev
+	}
+	level.CurrentDifficulty = categoryUser.CurrentDifficulty
+	level.DifficultyProgress = categoryUser.DifficultyProgress
+	level.Rating = categoryUser.Rating
+	level.TotalAnswered++
+	if correct {
+		level.TotalCorrect++
+	}
+	return level
+}
+
+// GetAnswerHistory returns a page of the user's recorded answers (newest first) and the cursor
+// for the next page (0 when there are no more rows).
+func (s *AnswerService) GetAnswerHistory(userID int, filter repository.AnswerHistoryFilter) ([]models.AnswerRecord, int64, error) {
+	if _, err := s.userService.GetUserByID(userID); err != nil {
+		return nil, 0, err
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
 
-	if err := s.userRepo.UpdateUserAfterAnswer(userID, user); err != nil {
-		return false, nil, err
+	// Fetch one extra row to learn whether another page exists.
+	limit := filter.Limit
+	filter.Limit++
+	records, err := s.historyRepo.ListAnswers(userID, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	var nextCursor int64
+	if len(records) > limit {
+		records = records[:limit]
+		nextCursor = records[limit-1].ID
 	}
+	return records, nextCursor, nil
+}
+
+// ReviewItem is one mistake in a user's review list: the question, the user's answer and the
+// correct answer with the question's explanation and references. Options and answers use the
+// question's own option order, not the order it was served in.
+type ReviewItem struct {
+	AnswerID      int64          `json:"answerId"`
+	QuestionID    int            `json:"questionId"`
+	Type          string         `json:"type"`
+	Question      string         `json:"question"`
+	Options       []string       `json:"options"`
+	Category      string         `json:"category"`
+	Difficulty    int            `json:"difficulty"`
+	Answer        string         `json:"answer"`
+	CorrectAnswer *CorrectAnswer `json:"correctAnswer"`
+	Explanation   string         `json:"explanation,omitempty"`
+	References    []string       `json:"references,omitempty"`
+	AnsweredAt    time.Time      `json:"answeredAt"`
+}
 
-	pipe := s.leaderboardRepo.Pipeline()
-	if s.userCacheRepo != nil {
-		_ = s.userCacheRepo.QueueSet(pipe, userID, user)
+// GetReview returns the user's recent mistakes for review: their latest wrong (or only partly
+// right) answer to each question, newest first, limit 20 by default and at most 50. Questions
+// retired since are left out.
+func (s *AnswerService) GetReview(userID int, limit int) ([]ReviewItem, error) {
+	if _, err := s.userService.GetUserByID(userID); err != nil {
+		return nil, err
 	}
-	s.lastAnswerRepo.QueueSetLastAnswered(pipe, userID, questionID)
-	s.leaderboardRepo.QueueUpdateScore(pipe, userID, user.Score)
-	s.leaderboardRepo.QueueUpdateStreak(pipe, userID, user.MaxStreak)
-	if _, err := pipe.Exec(context.Background()); err != nil {
-		log.Printf("Redis pipeline Exec failed for userID %d: %v", userID, err)
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 50 {
+		limit = 50
 	}
 
-	return isCorrect, user, nil
+	records, err := s.historyRepo.ListMistakes(userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]ReviewItem, 0, len(records))
+	for _, rec := range records {
+		q, err := s.questions.GetQuestion(rec.QuestionID)
+		if err == ErrQuestionNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if q.RetiredAt != nil {
+			continue
+		}
+		items = append(items, ReviewItem{
+			AnswerID:      rec.ID,
+			QuestionID:    q.ID,
+			Type:          questionType(q),
+			Question:      q.Question,
+			Options:       q.Options,
+			Category:      q.Category,
+			Difficulty:    q.Difficulty,
+			Answer:        rec.Answer,
+			CorrectAnswer: correctAnswer(q),
+			Explanation:   q.Explanation,
+			References:    q.References,
+			AnsweredAt:    rec.AnsweredAt,
+		})
+	}
+	return items, nil
 }
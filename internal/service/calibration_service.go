@@ -0,0 +1,161 @@
+package service
+
+import (
+	"brainbolt/internal/repository"
+	"math"
+	"time"
+)
+
+// Calibration flags explain why a question was singled out.
+const (
+	FlagTooEasy                = "too_easy"                // observed p-value well above what its level predicts
+	FlagTooHard                = "too_hard"                // observed p-value well below what its level predicts
+	FlagLowDiscrimination      = "low_discrimination"      // strong and weak players do about equally well on it
+	FlagNegativeDiscrimination = "negative_discrimination" // weak players do better than strong ones (bad key?)
+)
+
+// CalibrationOptions tunes a calibration run.
+type CalibrationOptions struct {
+	MinAnswers        int     `json:"minAnswers"`        // questions with fewer answers are reported but never flagged
+	Tolerance         float64 `json:"tolerance"`         // allowed |observed - expected| p-value gap before flagging
+	MinDiscrimination float64 `json:"minDiscrimination"` // point-biserial correlation below which a question is flagged
+	Apply             bool    `json:"apply"`             // re-bucket mis-labelled questions; false = dry run
+}
+
+// QuestionCalibration is the calibration result for one question.
+type QuestionCalibration struct {
+	QuestionID          int      `json:"questionId"`
+	LabeledDifficulty   int      `json:"labeledDifficulty"`
+	Answers             int      `json:"answers"`
+	PValue              float64  `json:"pValue"`
+	ExpectedPValue      float64  `json:"expectedPValue"`
+	Discrimination      float64  `json:"discrimination"`
+	SuggestedDifficulty int      `json:"suggestedDifficulty"`
+	Flags               []string `json:"flags,omitempty"`
+}
+
+// CalibrationReport summarizes a calibration run.
+type CalibrationReport struct {
+	GeneratedAt time.Time             `json:"generatedAt"`
+	DryRun      bool                  `json:"dryRun"`
+	Options     CalibrationOptions    `json:"options"`
+	Questions   []QuestionCalibration `json:"questions"`
+	Flagged     int                   `json:"flagged"`
+	Proposed    int                   `json:"proposedRebuckets"` // mis-labelled questions with a different suggested level
+	Rebucketed  int                   `json:"rebucketed"`        // how many of those were actually moved (0 on dry run)
+}
+
+// CalibrationService compares hand-set question difficulty with how players actually perform.
+type CalibrationService struct {
+	questionRepo *repository.QuestionRepository
+	questions    *QuestionBankService
+}
+
+// NewCalibrationService creates a new calibration service; questions invalidates the cached
+// copies of re-bucketed questions.
+func NewCalibrationService(questionRepo *repository.QuestionRepository, questions *QuestionBankService) *CalibrationService {
+	return &CalibrationService{questionRepo: questionRepo, questions: questions}
+}
+
+// DefaultCalibrationOptions returns a conservative dry-run configuration.
+func DefaultCalibrationOptions() CalibrationOptions {
+	return CalibrationOptions{
+		MinAnswers:        30,
+		Tolerance:         0.15,
+		MinDiscrimination: 0.1,
+	}
+}
+
+// ExpectedPValue is the share of correct answers a well-labelled question at this level should see:
+// 0.95 at level 1 falling linearly to 0.365 at level 10.
+func ExpectedPValue(difficulty int) float64 {
+	return 0.95 - 0.065*float64(clampDifficulty(difficulty)-MinDifficulty)
+}
+
+// difficultyForPValue returns the level whose expected p-value is closest to p.
+func difficultyForPValue(p float64) int {
+	return clampDifficulty(MinDifficulty + int(math.Round((0.95-p)/0.065)))
+}
+
+// pointBiserial is the correlation between answering this question correctly and overall ability.
+func pointBiserial(st repository.QuestionAnswerStats) float64 {
+	if st.AbilityStdDev == 0 || st.Correct == 0 || st.Correct == st.Answers {
+		return 0
+	}
+	p := float64(st.Correct) / float64(st.Answers)
+	return (st.MeanAbilityRight - st.MeanAbilityWrong) / st.AbilityStdDev * math.Sqrt(p*(1-p))
+}
+
+// Run computes each question's empirical p-value and discrimination, flags questions whose observed
+// difficulty disagrees with their label, and (only when opts.Apply) moves mis-labelled questions to
+// the suggested level in one transaction.
+func (s *CalibrationService) Run(opts CalibrationOptions) (*CalibrationReport, error) {
+	stats, err := s.questionRepo.GetAnswerStats()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CalibrationReport{
+		GeneratedAt: time.Now(),
+		DryRun:      !opts.Apply,
+		Options:     opts,
+		Questions:   make([]QuestionCalibration, 0, len(stats)),
+	}
+	rebucket := make(map[int]int)
+	for _, st := range stats {
+		p := float64(st.Correct) / float64(st.Answers)
+		qc := QuestionCalibration{
+			QuestionID:          st.QuestionID,
+			LabeledDifficulty:   st.Difficulty,
+			Answers:             st.Answers,
+			PValue:              p,
+			ExpectedPValue:      ExpectedPValue(st.Difficulty),
+			Discrimination:      pointBiserial(st),
+			SuggestedDifficulty: difficultyForPValue(p),
+		}
+		if st.Answers >= opts.MinAnswers {
+			switch {
+			case p-qc.ExpectedPValue > opts.Tolerance:
+				qc.Flags = append(qc.Flags, FlagTooEasy)
+			case qc.ExpectedPValue-p > opts.Tolerance:
+				qc.Flags = append(qc.Flags, FlagTooHard)
+			}
+			switch {
+			case qc.Discrimination < 0:
+				qc.Flags = append(qc.Flags, FlagNegativeDiscrimination)
+			case qc.Discrimination < opts.MinDiscrimination:
+				qc.Flags = append(qc.Flags, FlagLowDiscrimination)
+			}
+		}
+		if len(qc.Flags) > 0 {
+			report.Flagged++
+		}
+		if hasDifficultyFlag(qc.Flags) && qc.SuggestedDifficulty != st.Difficulty {
+			rebucket[st.QuestionID] = qc.SuggestedDifficulty
+		}
+		report.Questions = append(report.Questions, qc)
+	}
+	report.Proposed = len(rebucket)
+
+	if opts.Apply && len(rebucket) > 0 {
+		if err := s.questionRepo.UpdateDifficulties(rebucket); err != nil {
+			return nil, err
+		}
+		ids := make([]int, 0, len(rebucket))
+		for id := range rebucket {
+			ids = append(ids, id)
+		}
+		s.questions.Invalidate(ids...)
+		report.Rebucketed = len(rebucket)
+	}
+	return report, nil
+}
+
+func hasDifficultyFlag(flags []string) bool {
+	for _, f := range flags {
+		if f == FlagTooEasy || f == FlagTooHard {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"brainbolt/internal/models"
+	"regexp"
+	"strings"
+)
+
+// DefaultCategory is the category of questions that were never given one.
+const DefaultCategory = "general"
+
+// maxFilterTags caps how many tags one /v1/quiz/next filter may list.
+const maxFilterTags = 10
+
+// categoryPattern is the format of category and tag names: lowercase letters, digits and '-'.
+var categoryPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// QuestionFilter narrows the questions GetNextQuestionForUser serves: Category exactly, and Tags
+// to questions carrying at least one of them. The zero value matches every question.
+type QuestionFilter struct {
+	Category string
+	Tags     []string
+}
+
+// ParseQuestionFilter validates the ?category= and comma-separated ?tags= values of /v1/quiz/next;
+// names are case-insensitive.
+func ParseQuestionFilter(category, tags string) (QuestionFilter, error) {
+	var f QuestionFilter
+	if category = strings.ToLower(strings.TrimSpace(category)); category != "" {
+		if !categoryPattern.MatchString(category) {
+			return f, ErrInvalidQuestionFilter
+		}
+		f.Category = category
+	}
+	seen := map[string]bool{}
+	for _, tag := range strings.Split(tags, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if !categoryPattern.MatchString(tag) || len(f.Tags) == maxFilterTags {
+			return f, ErrInvalidQuestionFilter
+		}
+		seen[tag] = true
+		f.Tags = append(f.Tags, tag)
+	}
+	return f, nil
+}
+
+// withCategoryLevel returns a copy of user carrying the difficulty state of level, so a
+// DifficultyStrategy can run on a category exactly as it does on the overall level. A category
+// the user never answered (nil level) starts from their overall level.
+func withCategoryLevel(user *models.User, level *models.CategoryLevel) *models.User {
+	u := *user
+	if level != nil {
+		u.CurrentDifficulty = level.CurrentDifficulty
+		u.DifficultyProgress = level.DifficultyProgress
+		u.Rating = level.Rating
+	}
+	return &u
+}
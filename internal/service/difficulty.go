@@ -0,0 +1,186 @@
+package service
+
+import (
+	"brainbolt/internal/models"
+	"brainbolt/internal/repository"
+	"fmt"
+	"math"
+)
+
+// Difficulty levels range from MinDifficulty to MaxDifficulty.
+const (
+	MinDifficulty = 1
+	MaxDifficulty = 10
+)
+
+// DifficultyStrategy decides how a player's difficulty moves after each answer and how their
+// next question is picked. Implementations must be safe for concurrent use.
+type DifficultyStrategy interface {
+	// Name is the config value that selects the strategy.
+	Name() string
+	// Apply updates user.CurrentDifficulty (and any strategy state kept on user) after the user
+	// answered question. It returns how much the question's own rating should move (0 if unrated).
+	Apply(user *models.User, question *models.Question, correct bool) (questionRatingDelta float64)
+	// Selection tells the question repository how to pick the next question for user.
+	Selection(user *models.User) repository.QuestionSelection
+}
+
+// DifficultyConfig holds the tunables for the built-in strategies.
+type DifficultyConfig struct {
+	HysteresisUp   int     // correct answers in a row needed to go up a level
+	HysteresisDown int     // wrong answers in a row needed to go down a level
+	EloK           float64 // player rating step size
+	EloQuestionK   float64 // question rating step size
+}
+
+// NewDifficultyStrategy returns the strategy registered under name: "step", "hysteresis" or "elo".
+func NewDifficultyStrategy(name string, cfg DifficultyConfig) (DifficultyStrategy, error) {
+	switch name {
+	case "", "step":
+		return StepDifficulty{}, nil
+	case "hysteresis":
+		if cfg.HysteresisUp < 1 || cfg.HysteresisDown < 1 {
+			return nil, fmt.Errorf("hysteresis difficulty needs up/down thresholds >= 1, got %d/%d", cfg.HysteresisUp, cfg.HysteresisDown)
+		}
+		return HysteresisDifficulty{Up: cfg.HysteresisUp, Down: cfg.HysteresisDown}, nil
+	case "elo":
+		if cfg.EloK <= 0 || cfg.EloQuestionK < 0 {
+			return nil, fmt.Errorf("elo difficulty needs K > 0 and question K >= 0, got %v/%v", cfg.EloK, cfg.EloQuestionK)
+		}
+		return EloDifficulty{K: cfg.EloK, QuestionK: cfg.EloQuestionK}, nil
+	}
+	return nil, fmt.Errorf("unknown difficulty strategy %q (want step, hysteresis or elo)", name)
+}
+
+// clampDifficulty keeps a difficulty level within MinDifficulty..MaxDifficulty.
+func clampDifficulty(d int) int {
+	if d < MinDifficulty {
+		return MinDifficulty
+	}
+	if d > MaxDifficulty {
+		return MaxDifficulty
+	}
+	return d
+}
+
+// levelSelection picks by the user's exact difficulty level.
+func levelSelection(user *models.User) repository.QuestionSelection {
+	d := user.CurrentDifficulty
+	if d == 0 {
+		d = MinDifficulty
+	}
+	return repository.QuestionSelection{Difficulty: d}
+}
+
+// StepDifficulty moves one level up after a correct answer and one level down after a wrong one.
+type StepDifficulty struct{}
+
+// Name implements DifficultyStrategy.
+func (StepDifficulty) Name() string { return "step" }
+
+// Apply implements DifficultyStrategy.
+func (StepDifficulty) Apply(user *models.User, _ *models.Question, correct bool) float64 {
+	if correct {
+		user.CurrentDifficulty = clampDifficulty(user.CurrentDifficulty + 1)
+	} else {
+		user.CurrentDifficulty = clampDifficulty(user.CurrentDifficulty - 1)
+	}
+	user.DifficultyProgress = 0
+	return 0
+}
+
+// Selection implements DifficultyStrategy.
+func (StepDifficulty) Selection(user *models.User) repository.QuestionSelection {
+	return levelSelection(user)
+}
+
+// HysteresisDifficulty only moves up after Up correct answers in a row and only moves down after
+// Down wrong answers in a row, so a single lucky guess or slip does not change the level.
+type HysteresisDifficulty struct {
+	Up   int
+	Down int
+}
+
+// Name implements DifficultyStrategy.
+func (HysteresisDifficulty) Name() string { return "hysteresis" }
+
+// Apply implements DifficultyStrategy.
+func (h HysteresisDifficulty) Apply(user *models.User, _ *models.Question, correct bool) float64 {
+	if user.CurrentDifficulty == 0 {
+		user.CurrentDifficulty = MinDifficulty
+	}
+	if correct {
+		if user.DifficultyProgress < 0 {
+			user.DifficultyProgress = 0
+		}
+		user.DifficultyProgress++
+		if user.DifficultyProgress >= h.Up {
+			user.CurrentDifficulty = clampDifficulty(user.CurrentDifficulty + 1)
+			user.DifficultyProgress = 0
+		}
+		return 0
+	}
+	if user.DifficultyProgress > 0 {
+		user.DifficultyProgress = 0
+	}
+	user.DifficultyProgress--
+	if -user.DifficultyProgress >= h.Down {
+		user.CurrentDifficulty = clampDifficulty(user.CurrentDifficulty - 1)
+		user.DifficultyProgress = 0
+	}
+	return 0
+}
+
+// Selection implements DifficultyStrategy.
+func (HysteresisDifficulty) Selection(user *models.User) repository.QuestionSelection {
+	return levelSelection(user)
+}
+
+// EloDifficulty treats each answer as a match between player and question (a Rasch/1PL IRT model
+// on the Elo scale): P(correct) = 1 / (1 + 10^((questionRating - playerRating) / 400)).
+// Both ratings move by K * (outcome - expected); the player's level is derived from their rating
+// and questions are served from the pool rated closest to the player.
+type EloDifficulty struct {
+	K         float64
+	QuestionK float64
+}
+
+// Name implements DifficultyStrategy.
+func (EloDifficulty) Name() string { return "elo" }
+
+// ExpectedScore is the probability that a player rated playerRating answers a question rated questionRating correctly.
+func ExpectedScore(playerRating, questionRating float64) float64 {
+	return 1 / (1 + math.Pow(10, (questionRating-playerRating)/400))
+}
+
+// DifficultyForRating maps a rating back onto the 1..10 level scale.
+func DifficultyForRating(rating float64) int {
+	return clampDifficulty(int(math.Round((rating - repository.RatingBase) / repository.RatingPerLevel)))
+}
+
+// Apply implements DifficultyStrategy.
+func (e EloDifficulty) Apply(user *models.User, question *models.Question, correct bool) float64 {
+	outcome := 0.0
+	if correct {
+		outcome = 1
+	}
+	surprise := outcome - ExpectedScore(user.Rating, question.Rating)
+	user.Rating += e.K * surprise
+	user.CurrentDifficulty = DifficultyForRating(user.Rating)
+	user.DifficultyProgress = 0
+	return -e.QuestionK * surprise
+}
+
+// Selection implements DifficultyStrategy.
+func (EloDifficulty) Selection(user *models.User) repository.QuestionSelection {
+	target := user.Rating
+	if target == 0 {
+		// Cached profiles written before ratings existed: fall back to the level's default rating.
+		target = repository.RatingBase + repository.RatingPerLevel*float64(levelSelection(user).Difficulty)
+	}
+	return repository.QuestionSelection{
+		Difficulty:   user.CurrentDifficulty,
+		ByRating:     true,
+		TargetRating: target,
+	}
+}
@@ -2,9 +2,28 @@ package service
 
 // Shared errors used by handlers and answer service.
 var (
-	ErrQuestionNotFound = &Error{Message: "question not found"}
-	ErrUserNotFound     = &Error{Message: "user not found"}
-	ErrDuplicateAnswer  = &Error{Message: "duplicate answer"}
+	ErrQuestionNotFound      = &Error{Message: "question not found"}
+	ErrUserNotFound          = &Error{Message: "user not found"}
+	ErrDuplicateAnswer       = &Error{Message: "duplicate answer"}
+	ErrInvalidQuestionToken  = &Error{Message: "missing or invalid question token; fetch the question from /v1/quiz/next"}
+	ErrQuestionTokenExpired  = &Error{Message: "question token expired; fetch a new question"}
+	ErrUnknownMode           = &Error{Message: "unknown quiz mode"}
+	ErrInvalidQuestionFilter = &Error{Message: "category and tags must be 1-32 characters of letters, digits or '-' (at most 10 tags)"}
+	ErrUnknownQuestionFormat = &Error{Message: "unknown question format (want json, csv, opentdb, gift or moodlexml)"}
+	ErrUserNotRanked         = &Error{Message: "user is not on this leaderboard"}
+	ErrUnknownPeriod         = &Error{Message: "unknown leaderboard period (want alltime, daily, weekly, monthly or season)"}
+	ErrUnknownBoard          = &Error{Message: "unknown leaderboard (want score, streak, accuracy, current-streak or difficulty)"}
+	ErrTooManyStreams        = &Error{Message: "too many live leaderboard streams, please poll instead"}
+	ErrPeriodNotSupported    = &Error{Message: "this leaderboard only supports period=alltime"}
+	ErrNoActiveSeason        = &Error{Message: "no season is active"}
+	ErrSeasonNotFound        = &Error{Message: "season not found"}
+	ErrInvalidSeason         = &Error{Message: "season needs a name and startsAt before endsAt"}
+	ErrSeasonOverlap         = &Error{Message: "season overlaps an existing season"}
+	ErrUnknownSeasonBoard    = &Error{Message: "unknown season board (want score or streak)"}
+	ErrAnswerConflict        = &Error{Message: "answer could not be applied due to a concurrent update, please retry"}
+	ErrInvalidUsername       = &Error{Message: "username must be 3-32 characters of letters, digits, '_', '-' or '.'"}
+	ErrUsernameTaken         = &Error{Message: "username already taken"}
+	ErrInvalidTimeRange      = &Error{Message: "from must be before to"}
 )
 
 // Error is a simple error type for quiz errors.
@@ -0,0 +1,382 @@
+package service
+
+import (
+	"brainbolt/internal/models"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Question types. Choice questions (single, truefalse, multi) have options and are answered with
+// option letters; numeric and text questions have no options.
+const (
+	QuestionTypeSingle    = "single"
+	QuestionTypeTrueFalse = "truefalse"
+	QuestionTypeMulti     = "multi"
+	QuestionTypeNumeric   = "numeric"
+	QuestionTypeText      = "text"
+)
+
+// maxAliases is how many other accepted answers a text question may have.
+const maxAliases = 10
+
+// Grade is the outcome of grading one answer. Credit is the share of full marks earned, from 0
+// to 1; only full credit counts as correct. Answer is the submitted answer in canonical form
+// (option letters, a formatted number or normalized text), empty if it was not understood.
+type Grade struct {
+	Correct bool
+	Credit  float64
+	Answer  string
+}
+
+// fullCredit is the grade of an answer that is either right or wrong.
+func fullCredit(answer string, correct bool) Grade {
+	if correct {
+		return Grade{Correct: true, Credit: 1, Answer: answer}
+	}
+	return Grade{Answer: answer}
+}
+
+// CorrectAnswer is the correct answer to a question as shown after answering it: Answer in the
+// form it is submitted (option letters, a number or text), the text of the correct options of
+// choice questions, and the tolerance or aliases that are also accepted.
+type CorrectAnswer struct {
+	Answer    string   `json:"answer"`
+	Options   []string `json:"options,omitempty"`
+	Tolerance float64  `json:"tolerance,omitempty"`
+	Aliases   []string `json:"aliases,omitempty"`
+}
+
+// correctAnswer returns the correct answer to q.
+func correctAnswer(q *models.Question) *CorrectAnswer {
+	correct := &CorrectAnswer{Answer: q.Answer, Tolerance: q.Tolerance, Aliases: q.Aliases}
+	for _, letter := range strings.Split(q.Answer, ",") {
+		for i, option := range q.Options {
+			if optionLetter(i) == letter {
+				correct.Options = append(correct.Options, option)
+			}
+		}
+	}
+	return correct
+}
+
+// QuestionGrader validates and grades the questions of one type. Implementations must be safe
+// for concurrent use.
+type QuestionGrader interface {
+	// Validate sets the type-specific fields of q (options, answer, grading settings) from in,
+	// normalized, and returns everything wrong with them.
+	Validate(q *models.Question, in QuestionInput) []string
+	// Grade grades a submitted answer to q.
+	Grade(q *models.Question, answer string) Grade
+}
+
+// questionGraders maps each question type to its grader.
+var questionGraders = map[string]QuestionGrader{
+	QuestionTypeSingle:    SingleChoiceGrader{},
+	QuestionTypeTrueFalse: TrueFalseGrader{},
+	QuestionTypeMulti:     MultiSelectGrader{},
+	QuestionTypeNumeric:   NumericGrader{},
+	QuestionTypeText:      TextGrader{},
+}
+
+// questionType is the type of q; questions stored (or cached) before types existed are single choice.
+func questionType(q *models.Question) string {
+	if q.Type == "" {
+		return QuestionTypeSingle
+	}
+	return q.Type
+}
+
+// GradeAnswer grades a submitted answer to q with the grader of its type.
+func GradeAnswer(q *models.Question, answer string) Grade {
+	grader, ok := questionGraders[questionType(q)]
+	if !ok {
+		return Grade{}
+	}
+	return grader.Grade(q, answer)
+}
+
+// setChoiceOptions validates and trims the options of a choice question into q.
+func setChoiceOptions(q *models.Question, options []string) []string {
+	var problems []string
+	if len(options) < MinQuestionOptions || len(options) > MaxQuestionOptions {
+		problems = append(problems, "a question needs 2 to 6 options")
+	}
+	seen := map[string]bool{}
+	for i, option := range options {
+		option = strings.TrimSpace(option)
+		key := foldAnswer(option)
+		switch {
+		case option == "" || len(option) > maxOptionLength:
+			problems = append(problems, "option "+optionLetter(i)+" must be 1-255 characters")
+		case seen[key]:
+			problems = append(problems, "option "+optionLetter(i)+" duplicates another option")
+		}
+		seen[key] = true
+		q.Options = append(q.Options, option)
+	}
+	return problems
+}
+
+// choiceLetter resolves an answer naming an option to the option's letter. The answer may be
+// the option's letter, its text or its 1-based number, compared after foldAnswer. A letter wins
+// over an option whose text happens to be a letter, and an option's text over the option with
+// that number ("3" is the option "3" if there is one).
+func choiceLetter(options []string, answer string) (string, bool) {
+	answer = foldAnswer(answer)
+	if answer == "" {
+		return "", false
+	}
+	for i := range options {
+		if answer == foldAnswer(optionLetter(i)) {
+			return optionLetter(i), true
+		}
+	}
+	for i, option := range options {
+		if answer == foldAnswer(option) {
+			return optionLetter(i), true
+		}
+	}
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+		return optionLetter(n - 1), true
+	}
+	return "", false
+}
+
+// SingleChoiceGrader grades questions with one correct option, answered with its letter, text
+// or number (see choiceLetter).
+type SingleChoiceGrader struct{}
+
+// Validate implements QuestionGrader.
+func (SingleChoiceGrader) Validate(q *models.Question, in QuestionInput) []string {
+	problems := setChoiceOptions(q, in.Options)
+	letter, ok := choiceLetter(q.Options, in.Answer)
+	if !ok {
+		problems = append(problems, "answer must be an option letter or the text of an option")
+	}
+	q.Answer = letter
+	return problems
+}
+
+// Grade implements QuestionGrader.
+func (SingleChoiceGrader) Grade(q *models.Question, answer string) Grade {
+	letter, ok := choiceLetter(q.Options, answer)
+	return fullCredit(letter, ok && letter == q.Answer)
+}
+
+// TrueFalseGrader grades true/false questions: single choice between the options True and False.
+type TrueFalseGrader struct{}
+
+// Validate implements QuestionGrader. The options may be left out.
+func (TrueFalseGrader) Validate(q *models.Question, in QuestionInput) []string {
+	var problems []string
+	options := in.Options
+	if len(options) == 0 {
+		options = []string{"True", "False"}
+	}
+	if len(options) != 2 || !strings.EqualFold(strings.TrimSpace(options[0]), "true") ||
+		!strings.EqualFold(strings.TrimSpace(options[1]), "false") {
+		problems = append(problems, "true/false questions have the options True and False")
+	}
+	q.Options = []string{"True", "False"}
+	letter, ok := choiceLetter(q.Options, in.Answer)
+	if !ok {
+		problems = append(problems, "answer must be A, B, True or False")
+	}
+	q.Answer = letter
+	return problems
+}
+
+// Grade implements QuestionGrader.
+func (TrueFalseGrader) Grade(q *models.Question, answer string) Grade {
+	letter, ok := choiceLetter(q.Options, answer)
+	return fullCredit(letter, ok && letter == q.Answer)
+}
+
+// MultiSelectGrader grades questions with one or more correct options, answered with their
+// letters separated by commas ("A,C"; texts and numbers work too, see choiceLetter). Without
+// PartialCredit only the exact set is correct; with it an answer earns
+// (right picks - wrong picks) / correct options, never below 0. A pick that names no option
+// counts as a wrong pick.
+type MultiSelectGrader struct{}
+
+// multiPicks resolves a multi-select answer ("A,C", "a; c", "A C" or "Paris, Rome") to the
+// letters it picks and the number of picks that name no option.
+func multiPicks(options []string, answer string) (letters []string, unknown int) {
+	for _, part := range strings.FieldsFunc(answer, func(r rune) bool { return r == ',' || r == ';' }) {
+		if letter, ok := choiceLetter(options, part); ok {
+			letters = append(letters, letter)
+			continue
+		}
+		// "A C": letters separated by spaces only.
+		for _, field := range strings.Fields(part) {
+			letter, ok := choiceLetter(options, field)
+			if !ok {
+				unknown++
+				continue
+			}
+			letters = append(letters, letter)
+		}
+	}
+	return letters, unknown
+}
+
+// Validate implements QuestionGrader. The answer may list option letters or texts, separated
+// by commas; it is stored as sorted letters.
+func (MultiSelectGrader) Validate(q *models.Question, in QuestionInput) []string {
+	problems := setChoiceOptions(q, in.Options)
+	picked := map[string]bool{}
+	for _, part := range strings.Split(in.Answer, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		letter, ok := choiceLetter(q.Options, part)
+		if !ok {
+			problems = append(problems, "answer "+strings.TrimSpace(part)+" is not an option letter or the text of an option")
+			continue
+		}
+		picked[letter] = true
+	}
+	if len(picked) == 0 {
+		problems = append(problems, "answer must list at least one correct option")
+	}
+	letters := make([]string, 0, len(picked))
+	for letter := range picked {
+		letters = append(letters, letter)
+	}
+	sort.Strings(letters)
+	q.Answer = strings.Join(letters, ",")
+	q.PartialCredit = in.PartialCredit
+	return problems
+}
+
+// Grade implements QuestionGrader.
+func (MultiSelectGrader) Grade(q *models.Question, answer string) Grade {
+	correct := map[string]bool{}
+	for _, letter := range strings.Split(q.Answer, ",") {
+		correct[letter] = true
+	}
+	letters, unknown := multiPicks(q.Options, answer)
+	picked := map[string]bool{}
+	right, wrong := 0, unknown
+	for _, letter := range letters {
+		if picked[letter] {
+			continue
+		}
+		picked[letter] = true
+		if correct[letter] {
+			right++
+		} else {
+			wrong++
+		}
+	}
+	canonical := ""
+	if unknown == 0 {
+		sorted := make([]string, 0, len(picked))
+		for letter := range picked {
+			sorted = append(sorted, letter)
+		}
+		sort.Strings(sorted)
+		canonical = strings.Join(sorted, ",")
+	}
+	if right == len(correct) && wrong == 0 {
+		return fullCredit(canonical, true)
+	}
+	if !q.PartialCredit || right <= wrong {
+		return Grade{Answer: canonical}
+	}
+	return Grade{Credit: float64(right-wrong) / float64(len(correct)), Answer: canonical}
+}
+
+// NumericGrader grades numeric questions: an answer within Tolerance of Answer is correct.
+type NumericGrader struct{}
+
+// toleranceEpsilon absorbs floating point error at the edge of the tolerance.
+const toleranceEpsilon = 1e-9
+
+// Validate implements QuestionGrader.
+func (NumericGrader) Validate(q *models.Question, in QuestionInput) []string {
+	var problems []string
+	if len(in.Options) > 0 {
+		problems = append(problems, "numeric questions have no options")
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(in.Answer), 64)
+	if err != nil || math.IsInf(value, 0) || math.IsNaN(value) {
+		problems = append(problems, "answer must be a number")
+	}
+	if in.Tolerance < 0 || math.IsInf(in.Tolerance, 0) || math.IsNaN(in.Tolerance) {
+		problems = append(problems, "tolerance must be a number of at least 0")
+	}
+	q.Answer = strconv.FormatFloat(value, 'g', -1, 64)
+	q.Tolerance = in.Tolerance
+	return problems
+}
+
+// Grade implements QuestionGrader.
+func (NumericGrader) Grade(q *models.Question, answer string) Grade {
+	expected, err := strconv.ParseFloat(q.Answer, 64)
+	if err != nil {
+		return Grade{}
+	}
+	value, err := strconv.ParseFloat(normalizeAnswer(answer), 64)
+	if err != nil || math.IsInf(value, 0) || math.IsNaN(value) {
+		return Grade{}
+	}
+	return fullCredit(strconv.FormatFloat(value, 'g', -1, 64), math.Abs(value-expected) <= q.Tolerance+toleranceEpsilon)
+}
+
+// TextGrader grades free-text questions: an answer matching Answer or one of the Aliases after
+// normalizeText is correct.
+type TextGrader struct{}
+
+// normalizeText is the form free-text answers are compared in: foldAnswer with surrounding
+// punctuation removed.
+func normalizeText(s string) string {
+	s = foldAnswer(s)
+	return strings.TrimSpace(strings.Trim(s, ".,;:!?'\"()"))
+}
+
+// Validate implements QuestionGrader.
+func (TextGrader) Validate(q *models.Question, in QuestionInput) []string {
+	var problems []string
+	if len(in.Options) > 0 {
+		problems = append(problems, "text questions have no options")
+	}
+	q.Answer = strings.TrimSpace(in.Answer)
+	if normalizeText(q.Answer) == "" || len(q.Answer) > maxOptionLength {
+		problems = append(problems, "answer must be 1-255 characters")
+	}
+	seen := map[string]bool{normalizeText(q.Answer): true}
+	for _, alias := range in.Aliases {
+		alias = strings.TrimSpace(alias)
+		key := normalizeText(alias)
+		if key == "" || seen[key] {
+			continue
+		}
+		if len(alias) > maxOptionLength {
+			problems = append(problems, "aliases must be at most 255 characters")
+			continue
+		}
+		seen[key] = true
+		q.Aliases = append(q.Aliases, alias)
+	}
+	if len(q.Aliases) > maxAliases {
+		problems = append(problems, "a question can have at most 10 aliases")
+	}
+	return problems
+}
+
+// Grade implements QuestionGrader.
+func (TextGrader) Grade(q *models.Question, answer string) Grade {
+	key := normalizeText(answer)
+	if key == "" {
+		return Grade{}
+	}
+	for _, accepted := range append([]string{q.Answer}, q.Aliases...) {
+		if normalizeText(accepted) == key {
+			return fullCredit(normalizeAnswer(answer), true)
+		}
+	}
+	return Grade{Answer: normalizeAnswer(answer)}
+}
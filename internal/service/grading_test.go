@@ -0,0 +1,183 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"brainbolt/internal/models"
+)
+
+func TestChoiceLetter(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []string
+		answer  string
+		letter  string
+		ok      bool
+	}{
+		{"letter", []string{"Paris", "Rome"}, "b", "B", true},
+		{"text", []string{"Paris", "Rome"}, "  rome ", "B", true},
+		{"number", []string{"Paris", "Rome"}, "2", "B", true},
+		{"number out of range", []string{"Paris", "Rome"}, "3", "", false},
+		{"unknown", []string{"Paris", "Rome"}, "Berlin", "", false},
+		{"empty", []string{"Paris", "Rome"}, "  ", "", false},
+		{"letter over text", []string{"B", "A"}, "A", "A", true},
+		{"text over number", []string{"3", "2", "1"}, "1", "C", true},
+		{"number without such text", []string{"10", "20", "30"}, "2", "B", true},
+		{"normalized text", []string{"Café “Noir”", "Tea"}, "CAFÉ \"noir\"", "A", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			letter, ok := choiceLetter(tt.options, tt.answer)
+			if letter != tt.letter || ok != tt.ok {
+				t.Errorf("choiceLetter(%q, %q) = %q, %v; want %q, %v", tt.options, tt.answer, letter, ok, tt.letter, tt.ok)
+			}
+		})
+	}
+}
+
+// gradeTest is one answer graded against a question.
+type gradeTest struct {
+	name   string
+	answer string
+	want   Grade
+}
+
+func runGradeTests(t *testing.T, q *models.Question, tests []gradeTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GradeAnswer(q, tt.answer)
+			if got.Correct != tt.want.Correct || math.Abs(got.Credit-tt.want.Credit) > 1e-9 || got.Answer != tt.want.Answer {
+				t.Errorf("GradeAnswer(%q) = %+v, want %+v", tt.answer, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSingleChoiceGrader(t *testing.T) {
+	// No type: questions stored before types existed are single choice.
+	q := &models.Question{Options: []string{"Paris", "Rome", "Vienna"}, Answer: "B"}
+	runGradeTests(t, q, []gradeTest{
+		{"letter", "B", Grade{Correct: true, Credit: 1, Answer: "B"}},
+		{"text", "rome", Grade{Correct: true, Credit: 1, Answer: "B"}},
+		{"number", "2", Grade{Correct: true, Credit: 1, Answer: "B"}},
+		{"wrong", "Paris", Grade{Answer: "A"}},
+		{"unknown", "Berlin", Grade{}},
+	})
+}
+
+func TestTrueFalseGrader(t *testing.T) {
+	q := &models.Question{Type: QuestionTypeTrueFalse, Options: []string{"True", "False"}, Answer: "B"}
+	runGradeTests(t, q, []gradeTest{
+		{"text", "false", Grade{Correct: true, Credit: 1, Answer: "B"}},
+		{"letter", "b", Grade{Correct: true, Credit: 1, Answer: "B"}},
+		{"wrong", "TRUE", Grade{Answer: "A"}},
+		{"unknown", "maybe", Grade{}},
+	})
+}
+
+func TestMultiSelectGrader(t *testing.T) {
+	options := []string{"2", "4", "5", "9", "11"}
+	exact := &models.Question{Type: QuestionTypeMulti, Options: options, Answer: "A,C,E"}
+	runGradeTests(t, exact, []gradeTest{
+		{"exact set", "E, a ,C", Grade{Correct: true, Credit: 1, Answer: "A,C,E"}},
+		{"spaces only", "A C E", Grade{Correct: true, Credit: 1, Answer: "A,C,E"}},
+		{"repeated picks", "A,A,C;E", Grade{Correct: true, Credit: 1, Answer: "A,C,E"}},
+		{"partly right without partial credit", "A,C", Grade{Answer: "A,C"}},
+	})
+
+	partial := &models.Question{Type: QuestionTypeMulti, Options: options, Answer: "A,C,E", PartialCredit: true}
+	runGradeTests(t, partial, []gradeTest{
+		{"all right", "A,C,E", Grade{Correct: true, Credit: 1, Answer: "A,C,E"}},
+		{"two of three", "A,C", Grade{Credit: 2.0 / 3, Answer: "A,C"}},
+		{"one of three", "E", Grade{Credit: 1.0 / 3, Answer: "E"}},
+		{"all right and one wrong", "A,B,C,E", Grade{Credit: 2.0 / 3, Answer: "A,B,C,E"}},
+		{"two right and one wrong", "A,C,D", Grade{Credit: 1.0 / 3, Answer: "A,C,D"}},
+		{"as many wrong as right", "A,B", Grade{Answer: "A,B"}},
+		{"every option", "A,B,C,D,E", Grade{Credit: 1.0 / 3, Answer: "A,B,C,D,E"}},
+		{"unknown pick counts as wrong", "A,C,Z", Grade{Credit: 1.0 / 3}},
+		{"texts", "5, 11", Grade{Credit: 2.0 / 3, Answer: "C,E"}},
+	})
+}
+
+func TestNumericGrader(t *testing.T) {
+	q := &models.Question{Type: QuestionTypeNumeric, Answer: "3.14", Tolerance: 0.01}
+	runGradeTests(t, q, []gradeTest{
+		{"exact", "3.14", Grade{Correct: true, Credit: 1, Answer: "3.14"}},
+		{"edge of the tolerance", "3.13", Grade{Correct: true, Credit: 1, Answer: "3.13"}},
+		{"upper edge", " 3.15 ", Grade{Correct: true, Credit: 1, Answer: "3.15"}},
+		{"outside the tolerance", "3.16", Grade{Answer: "3.16"}},
+		{"canonical form", "3.140", Grade{Correct: true, Credit: 1, Answer: "3.14"}},
+		{"full-width digits", "３.１４", Grade{Correct: true, Credit: 1, Answer: "3.14"}},
+		{"not a number", "pi", Grade{}},
+		{"infinity", "Inf", Grade{}},
+	})
+
+	exact := &models.Question{Type: QuestionTypeNumeric, Answer: "0.3"}
+	runGradeTests(t, exact, []gradeTest{
+		{"floating point error", "0.30000000000000004", Grade{Correct: true, Credit: 1, Answer: "0.30000000000000004"}},
+		{"no tolerance", "0.31", Grade{Answer: "0.31"}},
+	})
+}
+
+func TestTextGrader(t *testing.T) {
+	q := &models.Question{Type: QuestionTypeText, Answer: "Jupiter", Aliases: []string{"Planet Jupiter", "Jove"}}
+	runGradeTests(t, q, []gradeTest{
+		{"answer", "Jupiter", Grade{Correct: true, Credit: 1, Answer: "Jupiter"}},
+		{"case and punctuation", "  JUPITER! ", Grade{Correct: true, Credit: 1, Answer: "JUPITER!"}},
+		{"alias", "planet   jupiter", Grade{Correct: true, Credit: 1, Answer: "planet jupiter"}},
+		{"other alias", "\"Jove.\"", Grade{Correct: true, Credit: 1, Answer: "\"Jove.\""}},
+		{"wrong", "Saturn", Grade{Answer: "Saturn"}},
+		{"punctuation only", "?!", Grade{}},
+	})
+}
+
+func TestValidateQuestionTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		in   QuestionInput
+		// answer is the stored answer of a valid input; problems the ones reported otherwise.
+		answer   string
+		problems []string
+	}{
+		{"single by text", QuestionInput{Options: []string{"Paris", "Rome"}, Answer: "Rome"}, "B", nil},
+		{"single duplicate options", QuestionInput{Options: []string{"Paris", " paris "}, Answer: "A"}, "",
+			[]string{"option B duplicates another option"}},
+		{"truefalse without options", QuestionInput{Type: QuestionTypeTrueFalse, Answer: "false"}, "B", nil},
+		{"multi sorted letters", QuestionInput{Type: QuestionTypeMulti, Options: []string{"2", "4", "5"}, Answer: "C, 2,A"},
+			"A,C", nil},
+		{"multi unknown option", QuestionInput{Type: QuestionTypeMulti, Options: []string{"2", "4"}, Answer: "A,Z"}, "",
+			[]string{"answer Z is not an option letter or the text of an option"}},
+		{"numeric", QuestionInput{Type: QuestionTypeNumeric, Answer: " 3.140 ", Tolerance: 0.5}, "3.14", nil},
+		{"numeric negative tolerance", QuestionInput{Type: QuestionTypeNumeric, Answer: "1", Tolerance: -1}, "",
+			[]string{"tolerance must be a number of at least 0"}},
+		{"text with options", QuestionInput{Type: QuestionTypeText, Options: []string{"A", "B"}, Answer: "x"}, "",
+			[]string{"text questions have no options"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var q models.Question
+			problems := questionGraders[questionType(&models.Question{Type: tt.in.Type})].Validate(&q, tt.in)
+			if len(problems) != len(tt.problems) {
+				t.Fatalf("problems = %q, want %q", problems, tt.problems)
+			}
+			for i := range problems {
+				if problems[i] != tt.problems[i] {
+					t.Errorf("problems = %q, want %q", problems, tt.problems)
+				}
+			}
+			if tt.problems == nil && q.Answer != tt.answer {
+				t.Errorf("answer = %q, want %q", q.Answer, tt.answer)
+			}
+		})
+	}
+}
+
+func TestCorrectAnswer(t *testing.T) {
+	q := &models.Question{Type: QuestionTypeMulti, Options: []string{"2", "4", "5"}, Answer: "A,C"}
+	got := correctAnswer(q)
+	if got.Answer != "A,C" || len(got.Options) != 2 || got.Options[0] != "2" || got.Options[1] != "5" {
+		t.Errorf("correctAnswer = %+v", got)
+	}
+}
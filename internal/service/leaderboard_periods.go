@@ -0,0 +1,94 @@
+package service
+
+import (
+	"brainbolt/internal/models"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Period selects a leaderboard time window.
+type Period string
+
+// Supported leaderboard periods.
+const (
+	PeriodAllTime Period = "alltime"
+	PeriodDaily   Period = "daily"
+	PeriodWeekly  Period = "weekly"
+	PeriodMonthly Period = "monthly"
+	PeriodSeason  Period = "season" // the active season, see SeasonService
+)
+
+// ParsePeriod validates a ?period= value ("" means all-time).
+func ParsePeriod(s string) (Period, error) {
+	switch p := Period(strings.ToLower(s)); p {
+	case "", PeriodAllTime:
+		return PeriodAllTime, nil
+	case PeriodDaily, PeriodWeekly, PeriodMonthly, PeriodSeason:
+		return p, nil
+	}
+	return "", ErrUnknownPeriod
+}
+
+// PeriodWindow is one concrete period: [Start, End) plus the suffix of its Redis keys.
+type PeriodWindow struct {
+	Period Period
+	Start  time.Time
+	End    time.Time
+	// KeySuffix identifies the window in Redis, e.g. "weekly:2026-10-12" or "season:7"; empty for all-time.
+	KeySuffix string
+}
+
+// PeriodCalendar computes period windows in a configured timezone and week start.
+type PeriodCalendar struct {
+	Location  *time.Location
+	WeekStart time.Weekday
+}
+
+// NewPeriodCalendar loads the IANA timezone (e.g. "Europe/Berlin") and parses the week start day
+// ("monday", "sunday", ...).
+func NewPeriodCalendar(timezone, weekStart string) (*PeriodCalendar, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid leaderboard timezone %q: %w", timezone, err)
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), weekStart) {
+			return &PeriodCalendar{Location: loc, WeekStart: d}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid leaderboard week start %q", weekStart)
+}
+
+// Window returns the window of period that contains t.
+func (c *PeriodCalendar) Window(period Period, t time.Time) PeriodWindow {
+	local := t.In(c.Location)
+	y, m, d := local.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, c.Location)
+	switch period {
+	case PeriodDaily:
+		return PeriodWindow{Period: period, Start: day, End: day.AddDate(0, 0, 1),
+			KeySuffix: "daily:" + day.Format("2006-01-02")}
+	case PeriodWeekly:
+		offset := (int(local.Weekday()) - int(c.WeekStart) + 7) % 7
+		start := day.AddDate(0, 0, -offset)
+		return PeriodWindow{Period: period, Start: start, End: start.AddDate(0, 0, 7),
+			KeySuffix: "weekly:" + start.Format("2006-01-02")}
+	case PeriodMonthly:
+		start := time.Date(y, m, 1, 0, 0, 0, 0, c.Location)
+		return PeriodWindow{Period: period, Start: start, End: start.AddDate(0, 1, 0),
+			KeySuffix: "monthly:" + start.Format("2006-01")}
+	}
+	return PeriodWindow{Period: PeriodAllTime}
+}
+
+// seasonWindow is the window of a season; its boards are keyed by season id.
+func seasonWindow(season *models.Season) PeriodWindow {
+	return PeriodWindow{Period: PeriodSeason, Start: season.StartsAt, End: season.EndsAt,
+		KeySuffix: "season:" + strconv.Itoa(season.ID)}
+}
+
+// timedPeriods are the calendar periods that get their own expiring ZSETs (the season board is
+// added separately while a season is active).
+var timedPeriods = []Period{PeriodDaily, PeriodWeekly, PeriodMonthly}
@@ -1,81 +1,493 @@
 package service
 
 import (
+	"brainbolt/internal/models"
 	"brainbolt/internal/repository"
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
 )
 
+// periodKeyGrace keeps a finished window's ZSETs around a little longer than the window itself,
+// so reads that straddle the boundary still find them.
+const periodKeyGrace = 24 * time.Hour
+
+// activeSeasonTTL bounds how long the active season lookup is cached; season changes made on
+// another instance show up within this delay (rollover on this instance invalidates it at once).
+const activeSeasonTTL = time.Minute
+
 // LeaderboardService handles leaderboard and rank logic (Redis with DB fallback).
 type LeaderboardService struct {
 	userRepo        *repository.UserRepository
 	leaderboardRepo *repository.LeaderboardRepository
+	userCacheRepo   *repository.UserCacheRepository
+	historyRepo     *repository.AnswerHistoryRepository
+	seasonRepo      *repository.SeasonRepository
+	calendar        *PeriodCalendar
+	// accuracyMinAnswers is how many answers a user needs to appear on the accuracy board.
+	accuracyMinAnswers int
+
+	seasonMu       sync.Mutex
+	season         *models.Season
+	seasonLoadedAt time.Time
 }
 
 // NewLeaderboardService creates a new leaderboard service.
-func NewLeaderboardService(userRepo *repository.UserRepository, leaderboardRepo *repository.LeaderboardRepository) *LeaderboardService {
+func NewLeaderboardService(
+	userRepo *repository.UserRepository,
+	leaderboardRepo *repository.LeaderboardRepository,
+	userCacheRepo *repository.UserCacheRepository,
+	historyRepo *repository.AnswerHistoryRepository,
+	seasonRepo *repository.SeasonRepository,
+	calendar *PeriodCalendar,
+	accuracyMinAnswers int,
+) *LeaderboardService {
+	if accuracyMinAnswers < 1 {
+		accuracyMinAnswers = 1
+	}
 	return &LeaderboardService{
 		userRepo:        userRepo,
 		leaderboardRepo: leaderboardRepo,
+		userCacheRepo:   userCacheRepo,
+		historyRepo:     historyRepo,
+		seasonRepo:      seasonRepo,
+		calendar:        calendar,
+
+		accuracyMinAnswers: accuracyMinAnswers,
+	}
+}
+
+// activeSeason returns the season running at t (nil if none), cached for activeSeasonTTL.
+func (s *LeaderboardService) activeSeason(t time.Time) (*models.Season, error) {
+	s.seasonMu.Lock()
+	defer s.seasonMu.Unlock()
+	cached := s.season
+	if time.Since(s.seasonLoadedAt) < activeSeasonTTL &&
+		(cached == nil || (!t.Before(cached.StartsAt) && t.Before(cached.EndsAt))) {
+		return cached, nil
+	}
+	season, err := s.seasonRepo.GetActiveSeason(t)
+	if err != nil {
+		return nil, err
+	}
+	s.season, s.seasonLoadedAt = season, time.Now()
+	return season, nil
+}
+
+// InvalidateSeason drops the cached active season, e.g. after a rollover or a new season.
+func (s *LeaderboardService) InvalidateSeason() {
+	s.seasonMu.Lock()
+	s.season, s.seasonLoadedAt = nil, time.Time{}
+	s.seasonMu.Unlock()
+}
+
+// window returns the window of period containing t; ErrNoActiveSeason for the season period
+// when no season is running.
+func (s *LeaderboardService) window(period Period, t time.Time) (PeriodWindow, error) {
+	if period != PeriodSeason {
+		return s.calendar.Window(period, t), nil
+	}
+	season, err := s.activeSeason(t)
+	if err != nil {
+		return PeriodWindow{}, err
+	}
+	if season == nil {
+		return PeriodWindow{}, ErrNoActiveSeason
+	}
+	return seasonWindow(season), nil
+}
+
+// activeWindows returns the current daily/weekly/monthly windows plus the active season's, if any.
+// A failing season lookup is logged and the season board skipped.
+func (s *LeaderboardService) activeWindows(t time.Time) []PeriodWindow {
+	windows := make([]PeriodWindow, 0, len(timedPeriods)+1)
+	for _, period := range timedPeriods {
+		windows = append(windows, s.calendar.Window(period, t))
+	}
+	w, err := s.window(PeriodSeason, t)
+	if err == nil {
+		windows = append(windows, w)
+	} else if err != ErrNoActiveSeason {
+		log.Printf("Failed to load the active season: %v", err)
+	}
+	return windows
+}
+
+// QueueUser queues writing the user's current values to every all-time board (and removing them
+// from the boards they do not qualify for); call Exec on the pipeline to run.
+func (s *LeaderboardService) QueueUser(pipe *redis.Pipeline, user *models.User) {
+	for _, kind := range allTimeBoards {
+		s.queueUserBoard(pipe, kind, user)
+	}
+}
+
+// QueueCurrentStreak queues writing the user's (decayed) streak to the current streak board.
+func (s *LeaderboardService) QueueCurrentStreak(pipe *redis.Pipeline, user *models.User) {
+	s.queueUserBoard(pipe, currentStreakBoard, user)
+}
+
+// queueUserBoard queues ZADD of the user's value on an all-time board, or ZREM if they do not qualify.
+func (s *LeaderboardService) queueUserBoard(pipe *redis.Pipeline, kind boardKind, user *models.User) {
+	key := boardKey(kind, PeriodWindow{Period: PeriodAllTime})
+	if v, ok := s.userBoardValue(kind, user); ok {
+		s.leaderboardRepo.QueueSet(pipe, key, v)
+	} else {
+		s.leaderboardRepo.QueueRemove(pipe, key, user.ID)
+	}
+}
+
+// DeleteSeasonBoards removes the Redis score and streak boards of a season window.
+func (s *LeaderboardService) DeleteSeasonBoards(w PeriodWindow) error {
+	return s.leaderboardRepo.Delete(boardKey(scoreBoard, w), boardKey(streakBoard, w))
+}
+
+// SubscribeUpdates subscribes to the ids of users whose leaderboard entries changed.
+func (s *LeaderboardService) SubscribeUpdates() *redis.PubSub {
+	return s.leaderboardRepo.SubscribeUpdates()
+}
+
+// QueueAnswer queues the leaderboard updates for one accepted answer: the all-time boards get the
+// user's new totals, the current daily/weekly/monthly and season boards get the score delta and
+// the streak. Live streams on every instance are notified once the writes are done.
+func (s *LeaderboardService) QueueAnswer(pipe *redis.Pipeline, user *models.User, scoreDelta int64, at time.Time) {
+	s.QueueUser(pipe, user)
+	for _, w := range s.activeWindows(at) {
+		expireAt := w.End.Add(periodKeyGrace)
+		s.leaderboardRepo.QueueIncrScore(pipe, boardKey(scoreBoard, w), user.ID, scoreDelta, at, expireAt)
+		s.leaderboardRepo.QueueMaxStreak(pipe, boardKey(streakBoard, w), user.ID, user.Streak, at, expireAt)
+	}
+	s.leaderboardRepo.QueuePublishUpdate(pipe, user.ID)
+}
+
+// QueueRemoveUser queues removal of the user from the all-time boards and the current period and
+// season boards.
+func (s *LeaderboardService) QueueRemoveUser(pipe *redis.Pipeline, userID int) {
+	var keys []string
+	for _, w := range s.activeWindows(time.Now()) {
+		keys = append(keys, boardKey(scoreBoard, w), boardKey(streakBoard, w))
+	}
+	s.leaderboardRepo.QueueRemoveUser(pipe, userID, keys...)
+}
+
+// LeaderboardQuery selects a slice of a leaderboard: Limit entries after the first Offset or, when
+// AroundUserID is set, the user plus Radius neighbours on each side.
+type LeaderboardQuery struct {
+	Period       Period
+	Offset       int
+	Limit        int
+	AroundUserID int
+	Radius       int
+}
+
+// boardKind distinguishes the boards, which share the paging logic. Only the score and streak
+// boards have period and season variants; the others are all-time only.
+type boardKind int
+
+const (
+	scoreBoard boardKind = iota
+	streakBoard
+	accuracyBoard
+	currentStreakBoard
+	difficultyBoard
+)
+
+// allTimeBoards are the boards fed from the users table.
+var allTimeBoards = []boardKind{scoreBoard, streakBoard, accuracyBoard, currentStreakBoard, difficultyBoard}
+
+// hasPeriods reports whether kind has daily/weekly/monthly and season boards.
+func (kind boardKind) hasPeriods() bool {
+	return kind == scoreBoard || kind == streakBoard
+}
+
+// GetLeaderboardEntriesByScore returns leaderboard entries (userId, score, rank) for the current
+// window of q.Period from Redis; fallback to DB. For timed periods the score is the points earned
+// in the window.
+func (s *LeaderboardService) GetLeaderboardEntriesByScore(q LeaderboardQuery) ([]repository.LeaderboardEntry, error) {
+	return s.entries(scoreBoard, q)
+}
+
+// GetLeaderboardEntriesByStreak returns streak leaderboard entries (userId, streak, rank) for the
+// current window of q.Period. For timed periods the streak is the longest one reached in the window.
+func (s *LeaderboardService) GetLeaderboardEntriesByStreak(q LeaderboardQuery) ([]repository.StreakLeaderboardEntry, error) {
+	entries, err := s.entries(streakBoard, q)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]repository.StreakLeaderboardEntry, len(entries))
+	for i, e := range entries {
+		out[i] = repository.StreakLeaderboardEntry{UserID: e.UserID, Streak: int(e.Score), Rank: e.Rank, LeaderboardProfile: e.LeaderboardProfile}
+	}
+	return out, nil
+}
+
+// GetLeaderboardEntriesByAccuracy returns accuracy leaderboard entries (userId, accuracy percent,
+// rank) of users with at least the configured number of answers; all-time only.
+func (s *LeaderboardService) GetLeaderboardEntriesByAccuracy(q LeaderboardQuery) ([]repository.AccuracyLeaderboardEntry, error) {
+	entries, err := s.entries(accuracyBoard, q)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]repository.AccuracyLeaderboardEntry, len(entries))
+	for i, e := range entries {
+		out[i] = repository.AccuracyLeaderboardEntry{UserID: e.UserID, Accuracy: float64(e.Score) / 100, Rank: e.Rank, LeaderboardProfile: e.LeaderboardProfile}
+	}
+	return out, nil
+}
+
+// GetLeaderboardEntriesByCurrentStreak returns current streak leaderboard entries (userId, streak,
+// rank); streaks decay while a player is away (see UserService.DecayStreaks). All-time only.
+func (s *LeaderboardService) GetLeaderboardEntriesByCurrentStreak(q LeaderboardQuery) ([]repository.StreakLeaderboardEntry, error) {
+	entries, err := s.entries(currentStreakBoard, q)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]repository.StreakLeaderboardEntry, len(entries))
+	for i, e := range entries {
+		out[i] = repository.StreakLeaderboardEntry{UserID: e.UserID, Streak: int(e.Score), Rank: e.Rank, LeaderboardProfile: e.LeaderboardProfile}
 	}
+	return out, nil
+}
+
+// GetLeaderboardEntriesByDifficulty returns highest-difficulty leaderboard entries (userId,
+// maxDifficulty, rank); all-time only.
+func (s *LeaderboardService) GetLeaderboardEntriesByDifficulty(q LeaderboardQuery) ([]repository.DifficultyLeaderboardEntry, error) {
+	entries, err := s.entries(difficultyBoard, q)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]repository.DifficultyLeaderboardEntry, len(entries))
+	for i, e := range entries {
+		out[i] = repository.DifficultyLeaderboardEntry{UserID: e.UserID, MaxDifficulty: int(e.Score), Rank: e.Rank, LeaderboardProfile: e.LeaderboardProfile}
+	}
+	return out, nil
 }
 
-// GetLeaderboardEntriesByScore returns leaderboard entries (userId, score, rank) from Redis; fallback to DB.
-func (s *LeaderboardService) GetLeaderboardEntriesByScore(limit int) ([]repository.LeaderboardEntry, error) {
-	entries, err := s.leaderboardRepo.GetTopByScore(int64(limit))
+// entries serves one page of a board from its Redis ZSET. If Redis fails, the whole query
+// (including the around-user rank) is answered from MySQL instead, so ranks never mix backends.
+func (s *LeaderboardService) entries(kind boardKind, q LeaderboardQuery) ([]repository.LeaderboardEntry, error) {
+	if !kind.hasPeriods() && q.Period != PeriodAllTime {
+		return nil, ErrPeriodNotSupported
+	}
+	w, err := s.window(q.Period, time.Now())
 	if err != nil {
-		users, err2 := s.userRepo.GetLeaderboardByScore(limit)
-		if err2 != nil {
-			return nil, err
+		return nil, err
+	}
+	return s.entriesIn(kind, w, q)
+}
+
+// entriesIn is entries for a given window (q.Period is ignored).
+func (s *LeaderboardService) entriesIn(kind boardKind, w PeriodWindow, q LeaderboardQuery) ([]repository.LeaderboardEntry, error) {
+	key := boardKey(kind, w)
+
+	offset, limit := q.Offset, q.Limit
+	if q.AroundUserID > 0 {
+		rank, err := s.leaderboardRepo.GetUserRank(key, q.AroundUserID)
+		if err != nil {
+			return s.entriesFromDB(kind, w, q, err)
 		}
-		entries = make([]repository.LeaderboardEntry, len(users))
-		for i, u := range users {
-			entries[i] = repository.LeaderboardEntry{UserID: u.ID, Score: u.Score, Rank: int64(i + 1)}
+		if rank == 0 {
+			return nil, ErrUserNotRanked
 		}
-		return entries, nil
+		offset, limit = aroundWindow(int(rank), q.Radius)
+	}
+
+	entries, err := s.leaderboardRepo.GetRange(key, int64(offset), int64(limit))
+	if err != nil {
+		return s.entriesFromDB(kind, w, q, err)
 	}
+	s.hydrate(entries)
 	return entries, nil
 }
 
-// GetLeaderboardEntriesByStreak returns streak leaderboard entries (userId, streak, rank).
-func (s *LeaderboardService) GetLeaderboardEntriesByStreak(limit int) ([]repository.StreakLeaderboardEntry, error) {
-	entries, err := s.leaderboardRepo.GetTopByStreak(int64(limit))
-	if err != nil {
-		users, err2 := s.userRepo.GetLeaderboardByStreak(limit)
-		if err2 != nil {
-			return nil, err
+// hydrate fills in the profile of each entry: one MGET against the user cache, then a single
+// GetUsersByIDs query for the misses (which are written back to the cache). Lookup failures
+// leave the profile empty rather than failing the leaderboard.
+func (s *LeaderboardService) hydrate(entries []repository.LeaderboardEntry) {
+	ids := make([]int, 0, len(entries))
+	for _, e := range entries {
+		if e.Username == "" {
+			ids = append(ids, e.UserID)
 		}
-		out := make([]repository.StreakLeaderboardEntry, len(users))
-		for i, u := range users {
-			out[i] = repository.StreakLeaderboardEntry{UserID: u.ID, Streak: u.MaxStreak, Rank: int64(i + 1)}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	users := map[int]*models.User{}
+	if s.userCacheRepo != nil {
+		if cached, err := s.userCacheRepo.GetMany(ids); err == nil {
+			users = cached
 		}
-		return out, nil
 	}
-	out := make([]repository.StreakLeaderboardEntry, len(entries))
-	for i, e := range entries {
-		out[i] = repository.StreakLeaderboardEntry{UserID: e.UserID, Streak: int(e.Score), Rank: e.Rank}
+	var missing []int
+	for _, id := range ids {
+		if users[id] == nil {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		fromDB, err := s.userRepo.GetUsersByIDs(missing)
+		if err != nil {
+			log.Printf("Failed to load leaderboard profiles: %v", err)
+		}
+		var pipe *redis.Pipeline
+		if s.userCacheRepo != nil && len(fromDB) > 0 {
+			pipe = s.leaderboardRepo.Pipeline()
+		}
+		for i := range fromDB {
+			u := &fromDB[i]
+			users[u.ID] = u
+			if pipe != nil {
+				_ = s.userCacheRepo.QueueSet(pipe, u.ID, u)
+			}
+		}
+		if pipe != nil {
+			if _, err := pipe.Exec(context.Background()); err != nil {
+				log.Printf("Redis pipeline Exec failed caching leaderboard profiles: %v", err)
+			}
+		}
+	}
+
+	for i := range entries {
+		if u := users[entries[i].UserID]; u != nil {
+			entries[i].LeaderboardProfile = profileOf(u)
+		}
 	}
-	return out, nil
 }
 
-// GetUserRankByScore gets user's rank by score.
-func (s *LeaderboardService) GetUserRankByScore(userID int) (int, error) {
-	rank, err := s.leaderboardRepo.GetUserRankByScore(userID)
+// profileOf extracts the leaderboard profile fields from a user.
+func profileOf(u *models.User) repository.LeaderboardProfile {
+	accuracy := 0.0
+	if u.TotalAnswered > 0 {
+		accuracy = float64(u.TotalCorrect) / float64(u.TotalAnswered) * 100
+	}
+	return repository.LeaderboardProfile{
+		Username:          u.Username,
+		Accuracy:          &accuracy,
+		CurrentDifficulty: u.CurrentDifficulty,
+	}
+}
+
+// aroundWindow returns the offset and limit of rank's neighbourhood.
+func aroundWindow(rank, radius int) (int, int) {
+	offset := rank - 1 - radius
+	if offset < 0 {
+		offset = 0
+	}
+	return offset, rank + radius - offset
+}
+
+// entriesFromDB answers a board query from MySQL: users for all-time, user_answers for timed
+// periods. redisErr is returned if MySQL fails too.
+func (s *LeaderboardService) entriesFromDB(kind boardKind, w PeriodWindow, q LeaderboardQuery, redisErr error) ([]repository.LeaderboardEntry, error) {
+	offset, limit := q.Offset, q.Limit
+	if q.AroundUserID > 0 {
+		rank, err := s.rankFromDB(kind, w, q.AroundUserID)
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		if err != nil {
+			return nil, redisErr
+		}
+		if rank == 0 {
+			return nil, ErrUserNotRanked
+		}
+		offset, limit = aroundWindow(rank, q.Radius)
+	}
+
+	if w.Period != PeriodAllTime {
+		var entries []repository.LeaderboardEntry
+		var err error
+		if kind == scoreBoard {
+			entries, err = s.historyRepo.GetScoreLeaderboardBetween(w.Start, w.End, offset, limit)
+		} else {
+			entries, err = s.historyRepo.GetStreakLeaderboardBetween(w.Start, w.End, offset, limit)
+		}
+		if err != nil {
+			return nil, redisErr
+		}
+		s.hydrate(entries)
+		return entries, nil
+	}
+
+	var users []models.User
+	var err error
+	switch kind {
+	case scoreBoard:
+		users, err = s.userRepo.GetLeaderboardByScore(offset, limit)
+	case streakBoard:
+		users, err = s.userRepo.GetLeaderboardByStreak(offset, limit)
+	case accuracyBoard:
+		users, err = s.userRepo.GetLeaderboardByAccuracy(s.accuracyMinAnswers, offset, limit)
+	case currentStreakBoard:
+		users, err = s.userRepo.GetLeaderboardByCurrentStreak(offset, limit)
+	default:
+		users, err = s.userRepo.GetLeaderboardByDifficulty(offset, limit)
+	}
 	if err != nil {
-		return s.userRepo.GetUserRankByScore(userID)
+		return nil, redisErr
 	}
-	if rank == 0 {
-		return s.userRepo.GetUserRankByScore(userID)
+	entries := make([]repository.LeaderboardEntry, len(users))
+	for i, u := range users {
+		v, _ := s.userBoardValue(kind, &u)
+		entries[i] = repository.LeaderboardEntry{UserID: u.ID, Score: v.Value, Rank: int64(offset + i + 1), LeaderboardProfile: profileOf(&u)}
 	}
-	return int(rank), nil
+	return entries, nil
 }
 
-// GetUserRankByStreak gets user's rank by streak.
-func (s *LeaderboardService) GetUserRankByStreak(userID int) (int, error) {
-	rank, err := s.leaderboardRepo.GetUserRankByStreak(userID)
+// rankFromDB returns the user's rank on a board from MySQL (0 if they are not on a timed board or
+// do not qualify for the accuracy board).
+func (s *LeaderboardService) rankFromDB(kind boardKind, w PeriodWindow, userID int) (int, error) {
+	user, err := s.userRepo.GetUserByID(userID)
 	if err != nil {
-		return s.userRepo.GetUserRankByStreak(userID)
+		return 0, err
 	}
-	if rank == 0 {
+	switch {
+	case w.Period != PeriodAllTime && kind == scoreBoard:
+		return s.historyRepo.GetUserScoreRankBetween(userID, w.Start, w.End)
+	case w.Period != PeriodAllTime:
+		return s.historyRepo.GetUserStreakRankBetween(userID, w.Start, w.End)
+	case kind == scoreBoard:
+		return s.userRepo.GetUserRankByScore(userID)
+	case kind == streakBoard:
 		return s.userRepo.GetUserRankByStreak(userID)
+	case kind == accuracyBoard:
+		if _, ok := s.userBoardValue(kind, user); !ok {
+			return 0, nil
+		}
+		return s.userRepo.GetUserRankByAccuracy(s.accuracyMinAnswers, userID)
+	case kind == currentStreakBoard:
+		return s.userRepo.GetUserRankByCurrentStreak(userID)
+	default:
+		return s.userRepo.GetUserRankByDifficulty(userID)
+	}
+}
+
+// GetUserRankByScore gets user's rank by score. A user missing from the ZSET is re-added from
+// MySQL first, so the rank always comes from Redis unless Redis itself is failing.
+func (s *LeaderboardService) GetUserRankByScore(userID int) (int, error) {
+	return s.userRank(repository.LeaderboardScoreKey, userID, s.userRepo.GetUserRankByScore)
+}
+
+// GetUserRankByStreak gets user's rank by streak (see GetUserRankByScore).
+func (s *LeaderboardService) GetUserRankByStreak(userID int) (int, error) {
+	return s.userRank(repository.LeaderboardStreakKey, userID, s.userRepo.GetUserRankByStreak)
+}
+
+// userRank reads a rank from Redis, repairing a missing member once before falling back to MySQL.
+func (s *LeaderboardService) userRank(key string, userID int, fromDB func(int) (int, error)) (int, error) {
+	rank, err := s.leaderboardRepo.GetUserRank(key, userID)
+	if err == nil && rank == 0 {
+		if _, err = s.repairUser(userID); err == nil {
+			rank, err = s.leaderboardRepo.GetUserRank(key, userID)
+		}
+	}
+	if err != nil || rank == 0 {
+		return fromDB(userID)
 	}
 	return int(rank), nil
 }
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// streamBuffer is how many undelivered events a stream may hold before a slow client starts
+// missing intermediate updates (it always catches up with the latest state).
+const streamBuffer = 8
+
+// streamBoards maps the ?board= names of live streams to boards.
+var streamBoards = map[string]boardKind{
+	"score":          scoreBoard,
+	"streak":         streakBoard,
+	"accuracy":       accuracyBoard,
+	"current-streak": currentStreakBoard,
+	"difficulty":     difficultyBoard,
+}
+
+// StreamQuery selects what a live leaderboard stream watches: the top Limit entries of Board for
+// Period and, if UserID is set, that user's rank.
+type StreamQuery struct {
+	Board  string
+	Period Period
+	Limit  int
+	UserID int
+}
+
+// StreamEvent is one event pushed to a stream subscriber; Data is sent as JSON.
+type StreamEvent struct {
+	Name string
+	Data interface{}
+}
+
+// TopEvent ("top") carries the new top entries of a board, in the same shape as its REST endpoint.
+type TopEvent struct {
+	Board   string          `json:"board"`
+	Period  Period          `json:"period"`
+	Entries json.RawMessage `json:"entries"`
+}
+
+// RankEvent ("rank") tells a subscriber their rank changed; Rank 0 means they are not on the board.
+type RankEvent struct {
+	Board        string `json:"board"`
+	Period       Period `json:"period"`
+	UserID       int    `json:"userId"`
+	Rank         int64  `json:"rank"`
+	PreviousRank int64  `json:"previousRank"`
+}
+
+// StreamSubscription is one live stream client. Only the stream's refresh loop touches the
+// last* fields.
+type StreamSubscription struct {
+	query    StreamQuery
+	kind     boardKind
+	events   chan StreamEvent
+	lastTop  []byte
+	lastRank int64
+	ranked   bool
+}
+
+// Events returns the subscriber's event channel; it is never closed.
+func (sub *StreamSubscription) Events() <-chan StreamEvent {
+	return sub.events
+}
+
+// LeaderboardStream pushes leaderboard changes to live subscribers. Every leaderboard write is
+// announced on Redis pub/sub, so each instance refreshes its own subscribers whichever instance
+// accepted the answer; refreshes are coalesced to at most one per interval, and each distinct
+// board query is read once per refresh however many clients watch it.
+type LeaderboardStream struct {
+	leaderboards *LeaderboardService
+	interval     time.Duration
+	maxClients   int
+
+	mu    sync.Mutex
+	subs  map[*StreamSubscription]bool
+	dirty atomic.Bool
+}
+
+// NewLeaderboardStream creates a leaderboard stream hub; call Run to start it.
+func NewLeaderboardStream(leaderboards *LeaderboardService, interval time.Duration, maxClients int) *LeaderboardStream {
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+	return &LeaderboardStream{
+		leaderboards: leaderboards,
+		interval:     interval,
+		maxClients:   maxClients,
+		subs:         map[*StreamSubscription]bool{},
+	}
+}
+
+// Subscribe registers a live stream; the current top entries (and rank) follow within one interval.
+func (s *LeaderboardStream) Subscribe(q StreamQuery) (*StreamSubscription, error) {
+	kind, ok := streamBoards[q.Board]
+	if !ok {
+		return nil, ErrUnknownBoard
+	}
+	if !kind.hasPeriods() && q.Period != PeriodAllTime {
+		return nil, ErrPeriodNotSupported
+	}
+	if _, err := s.leaderboards.window(q.Period, time.Now()); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.maxClients > 0 && len(s.subs) >= s.maxClients {
+		return nil, ErrTooManyStreams
+	}
+	sub := &StreamSubscription{query: q, kind: kind, events: make(chan StreamEvent, streamBuffer)}
+	s.subs[sub] = true
+	s.dirty.Store(true)
+	return sub, nil
+}
+
+// Unsubscribe removes a live stream.
+func (s *LeaderboardStream) Unsubscribe(sub *StreamSubscription) {
+	s.mu.Lock()
+	delete(s.subs, sub)
+	s.mu.Unlock()
+}
+
+// Run listens for leaderboard updates and refreshes the subscribers; it never returns.
+func (s *LeaderboardStream) Run() {
+	pubsub := s.leaderboards.SubscribeUpdates()
+	defer pubsub.Close()
+	go func() {
+		for range pubsub.Channel() {
+			s.dirty.Store(true)
+		}
+	}()
+
+	for range time.Tick(s.interval) {
+		if s.dirty.Swap(false) {
+			s.refresh()
+		}
+	}
+}
+
+// topKey identifies a distinct top-N query shared by subscribers.
+type topKey struct {
+	kind   boardKind
+	period Period
+	limit  int
+}
+
+// refresh sends every subscriber whose top entries or rank changed the new state.
+func (s *LeaderboardStream) refresh() {
+	s.mu.Lock()
+	subs := make([]*StreamSubscription, 0, len(s.subs))
+	for sub := range s.subs {
+		subs = append(subs, sub)
+	}
+	s.mu.Unlock()
+
+	tops := map[topKey][]byte{}
+	for _, sub := range subs {
+		q := sub.query
+		key := topKey{sub.kind, q.Period, q.Limit}
+		top, ok := tops[key]
+		if !ok {
+			entries, err := s.leaderboards.boardEntries(sub.kind, LeaderboardQuery{Period: q.Period, Limit: q.Limit})
+			if err == nil {
+				top, err = json.Marshal(entries)
+			}
+			if err != nil {
+				log.Printf("Leaderboard stream: failed to read %s top %d: %v", q.Board, q.Limit, err)
+			}
+			tops[key] = top
+		}
+		if top != nil && !bytes.Equal(top, sub.lastTop) {
+			if s.send(sub, StreamEvent{Name: "top", Data: TopEvent{Board: q.Board, Period: q.Period, Entries: top}}) {
+				sub.lastTop = top
+			}
+		}
+
+		if q.UserID == 0 {
+			continue
+		}
+		rank, err := s.leaderboards.liveRank(sub.kind, q.Period, q.UserID)
+		if err != nil {
+			continue
+		}
+		if !sub.ranked || rank != sub.lastRank {
+			event := RankEvent{Board: q.Board, Period: q.Period, UserID: q.UserID, Rank: rank, PreviousRank: sub.lastRank}
+			if s.send(sub, StreamEvent{Name: "rank", Data: event}) {
+				sub.lastRank, sub.ranked = rank, true
+			}
+		}
+	}
+}
+
+// send delivers an event without blocking; a full buffer marks the stream dirty so the client gets
+// the latest state on the next refresh.
+func (s *LeaderboardStream) send(sub *StreamSubscription, event StreamEvent) bool {
+	select {
+	case sub.events <- event:
+		return true
+	default:
+		s.dirty.Store(true)
+		return false
+	}
+}
+
+// boardEntries returns a page of any board in the shape of its REST endpoint.
+func (s *LeaderboardService) boardEntries(kind boardKind, q LeaderboardQuery) (interface{}, error) {
+	switch kind {
+	case streakBoard:
+		return s.GetLeaderboardEntriesByStreak(q)
+	case accuracyBoard:
+		return s.GetLeaderboardEntriesByAccuracy(q)
+	case currentStreakBoard:
+		return s.GetLeaderboardEntriesByCurrentStreak(q)
+	case difficultyBoard:
+		return s.GetLeaderboardEntriesByDifficulty(q)
+	}
+	return s.GetLeaderboardEntriesByScore(q)
+}
+
+// liveRank returns the user's rank on the current window of a board from Redis (0 if absent).
+func (s *LeaderboardService) liveRank(kind boardKind, period Period, userID int) (int64, error) {
+	w, err := s.window(period, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return s.leaderboardRepo.GetUserRank(boardKey(kind, w), userID)
+}
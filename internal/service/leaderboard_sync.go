@@ -0,0 +1,321 @@
+package service
+
+import (
+	"brainbolt/internal/models"
+	"brainbolt/internal/repository"
+	"context"
+	"time"
+)
+
+// syncBatchSize is how many users (or period entries) are read from MySQL per round-trip when
+// rebuilding or reconciling the Redis leaderboards.
+const syncBatchSize = 1000
+
+// RebuildResult summarises a leaderboard rebuild.
+type RebuildResult struct {
+	Users int `json:"users"`
+	// Periods is the number of players written to each current period board.
+	Periods map[Period]int `json:"periods"`
+}
+
+// ReconcileReport describes the drift found between MySQL and the all-time Redis leaderboards.
+// A user on the accuracy board without enough answers to qualify counts as mismatched.
+type ReconcileReport struct {
+	CheckedAt               time.Time `json:"checkedAt"`
+	Repaired                bool      `json:"repaired"`
+	Users                   int       `json:"users"`
+	ScoreMissing            int       `json:"scoreMissing"`
+	ScoreMismatched         int       `json:"scoreMismatched"`
+	StreakMissing           int       `json:"streakMissing"`
+	StreakMismatched        int       `json:"streakMismatched"`
+	AccuracyMissing         int       `json:"accuracyMissing"`
+	AccuracyMismatched      int       `json:"accuracyMismatched"`
+	CurrentStreakMissing    int       `json:"currentStreakMissing"`
+	CurrentStreakMismatched int       `json:"currentStreakMismatched"`
+	DifficultyMissing       int       `json:"difficultyMissing"`
+	DifficultyMismatched    int       `json:"difficultyMismatched"`
+	// Extra counts ZSET members with no users row (e.g. deleted while Redis was unreachable).
+	Extra int `json:"extra"`
+}
+
+// Drift is the total number of discrepancies found.
+func (r *ReconcileReport) Drift() int {
+	drift := r.Extra
+	for _, kind := range allTimeBoards {
+		missing, mismatched := r.counters(kind)
+		drift += *missing + *mismatched
+	}
+	return drift
+}
+
+// counters returns the missing and mismatched counters of an all-time board.
+func (r *ReconcileReport) counters(kind boardKind) (missing, mismatched *int) {
+	switch kind {
+	case streakBoard:
+		return &r.StreakMissing, &r.StreakMismatched
+	case accuracyBoard:
+		return &r.AccuracyMissing, &r.AccuracyMismatched
+	case currentStreakBoard:
+		return &r.CurrentStreakMissing, &r.CurrentStreakMismatched
+	case difficultyBoard:
+		return &r.DifficultyMissing, &r.DifficultyMismatched
+	}
+	return &r.ScoreMissing, &r.ScoreMismatched
+}
+
+// boardKey returns the Redis key of a board for a period window.
+func boardKey(kind boardKind, w PeriodWindow) string {
+	switch kind {
+	case streakBoard:
+		return repository.PeriodKey(repository.LeaderboardStreakKey, w.KeySuffix)
+	case accuracyBoard:
+		return repository.LeaderboardAccuracyKey
+	case currentStreakBoard:
+		return repository.LeaderboardCurrentStreakKey
+	case difficultyBoard:
+		return repository.LeaderboardDifficultyKey
+	}
+	return repository.PeriodKey(repository.LeaderboardScoreKey, w.KeySuffix)
+}
+
+// WarmUp rebuilds the leaderboards if the all-time score board is empty (e.g. after Redis was
+// flushed or restarted) or was written in an older score format. It reports whether a rebuild ran.
+func (s *LeaderboardService) WarmUp() (bool, *RebuildResult, error) {
+	format, err := s.leaderboardRepo.GetFormat()
+	if err != nil {
+		return false, nil, err
+	}
+	n, err := s.leaderboardRepo.Count(repository.LeaderboardScoreKey)
+	if err != nil || (n > 0 && format == repository.BoardFormatVersion) {
+		return false, nil, err
+	}
+	result, err := s.Rebuild()
+	return err == nil, result, err
+}
+
+// Rebuild refills the all-time boards from users and the current period and season boards from
+// user_answers. Each board is written to a staging key and renamed over the live one, so
+// readers never see a half-filled board. Answers accepted while the rebuild runs may be
+// missing from the snapshot; the reconciler picks them up on its next pass.
+func (s *LeaderboardService) Rebuild() (*RebuildResult, error) {
+	tmpKeys := make([]string, len(allTimeBoards))
+	for i, kind := range allTimeBoards {
+		tmpKeys[i] = boardKey(kind, PeriodWindow{Period: PeriodAllTime}) + ":rebuild"
+	}
+	if err := s.leaderboardRepo.Delete(tmpKeys...); err != nil {
+		return nil, err
+	}
+
+	result := &RebuildResult{Periods: map[Period]int{}}
+	afterID := 0
+	for {
+		users, err := s.userRepo.ListUsersAfter(afterID, syncBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(users) == 0 {
+			break
+		}
+		pipe := s.leaderboardRepo.Pipeline()
+		for _, u := range users {
+			for i, kind := range allTimeBoards {
+				if v, ok := s.userBoardValue(kind, &u); ok {
+					s.leaderboardRepo.QueueSet(pipe, tmpKeys[i], v)
+				}
+			}
+		}
+		if _, err := pipe.Exec(context.Background()); err != nil {
+			return nil, err
+		}
+		result.Users += len(users)
+		afterID = users[len(users)-1].ID
+	}
+	for i, kind := range allTimeBoards {
+		if err := s.leaderboardRepo.Replace(tmpKeys[i], boardKey(kind, PeriodWindow{Period: PeriodAllTime})); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, w := range s.activeWindows(time.Now()) {
+		n, err := s.rebuildPeriodBoard(scoreBoard, w)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.rebuildPeriodBoard(streakBoard, w); err != nil {
+			return nil, err
+		}
+		result.Periods[w.Period] = n
+	}
+
+	pipe := s.leaderboardRepo.Pipeline()
+	s.leaderboardRepo.QueueSetFormat(pipe)
+	if _, err := pipe.Exec(context.Background()); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// scoreValue / streakValue are the all-time board values of a user.
+func scoreValue(u *models.User) repository.BoardValue {
+	return repository.BoardValue{UserID: u.ID, Value: u.Score, ReachedAt: u.ScoreReachedAt}
+}
+
+func streakValue(u *models.User) repository.BoardValue {
+	return repository.BoardValue{UserID: u.ID, Value: int64(u.MaxStreak), ReachedAt: u.MaxStreakReachedAt}
+}
+
+// userBoardValue returns the user's value on an all-time board, and false if they do not qualify
+// for it. It must match the users ordering in the MySQL fallback (see user_repo.go).
+func (s *LeaderboardService) userBoardValue(kind boardKind, u *models.User) (repository.BoardValue, bool) {
+	switch kind {
+	case streakBoard:
+		return streakValue(u), true
+	case accuracyBoard:
+		if u.TotalAnswered < s.accuracyMinAnswers {
+			return repository.BoardValue{}, false
+		}
+		// Basis points, so the packed score stays an integer.
+		bp := int64(u.TotalCorrect) * 10000 / int64(u.TotalAnswered)
+		return repository.BoardValue{UserID: u.ID, Value: bp, ReachedAt: u.LastAnsweredAt}, true
+	case currentStreakBoard:
+		reachedAt := u.LastAnsweredAt
+		if u.StreakDecayedAt != nil && (reachedAt == nil || u.StreakDecayedAt.After(*reachedAt)) {
+			reachedAt = u.StreakDecayedAt
+		}
+		return repository.BoardValue{UserID: u.ID, Value: int64(u.Streak), ReachedAt: reachedAt}, true
+	case difficultyBoard:
+		return repository.BoardValue{UserID: u.ID, Value: int64(u.MaxDifficulty), ReachedAt: u.MaxDifficultyReachedAt}, true
+	}
+	return scoreValue(u), true
+}
+
+// rebuildPeriodBoard refills one period board from user_answers and returns its size.
+func (s *LeaderboardService) rebuildPeriodBoard(kind boardKind, w PeriodWindow) (int, error) {
+	key := boardKey(kind, w)
+	tmp := key + ":rebuild"
+	if err := s.leaderboardRepo.Delete(tmp); err != nil {
+		return 0, err
+	}
+	written := 0
+	for {
+		var values []repository.BoardValue
+		var err error
+		if kind == scoreBoard {
+			values, err = s.historyRepo.GetScoreValuesBetween(w.Start, w.End, written, syncBatchSize)
+		} else {
+			values, err = s.historyRepo.GetStreakValuesBetween(w.Start, w.End, written, syncBatchSize)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if len(values) == 0 {
+			break
+		}
+		pipe := s.leaderboardRepo.Pipeline()
+		for _, v := range values {
+			s.leaderboardRepo.QueueSet(pipe, tmp, v)
+		}
+		s.leaderboardRepo.QueueExpireAt(pipe, tmp, w.End.Add(periodKeyGrace))
+		if _, err := pipe.Exec(context.Background()); err != nil {
+			return 0, err
+		}
+		written += len(values)
+	}
+	return written, s.leaderboardRepo.Replace(tmp, key)
+}
+
+// Reconcile compares every user's values on the all-time boards (with their tie-break times) in
+// MySQL with the ZSETs and, if repair is set, rewrites the Redis side. Drift caused by answers in
+// flight during the scan is corrected on the next pass.
+func (s *LeaderboardService) Reconcile(repair bool) (*ReconcileReport, error) {
+	report := &ReconcileReport{CheckedAt: time.Now(), Repaired: repair}
+	seen := map[int]bool{}
+	afterID := 0
+	for {
+		users, err := s.userRepo.ListUsersAfter(afterID, syncBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(users) == 0 {
+			break
+		}
+		ids := make([]int, len(users))
+		for i, u := range users {
+			ids[i] = u.ID
+			seen[u.ID] = true
+		}
+
+		pipe := s.leaderboardRepo.Pipeline()
+		queued := false
+		for _, kind := range allTimeBoards {
+			key := boardKey(kind, PeriodWindow{Period: PeriodAllTime})
+			packed, err := s.leaderboardRepo.GetPackedScores(key, ids)
+			if err != nil {
+				return nil, err
+			}
+			missing, mismatched := report.counters(kind)
+			for i := range users {
+				u := &users[i]
+				want, qualifies := s.userBoardValue(kind, u)
+				got, onBoard := packed[u.ID]
+				switch {
+				case !qualifies && onBoard:
+					*mismatched++
+					s.leaderboardRepo.QueueRemove(pipe, key, u.ID)
+				case qualifies && !onBoard:
+					*missing++
+					s.leaderboardRepo.QueueSet(pipe, key, want)
+				case qualifies && got != want.Encode():
+					*mismatched++
+					s.leaderboardRepo.QueueSet(pipe, key, want)
+				default:
+					continue
+				}
+				queued = true
+			}
+		}
+		if repair && queued {
+			if _, err := pipe.Exec(context.Background()); err != nil {
+				return nil, err
+			}
+		}
+		report.Users += len(users)
+		afterID = users[len(users)-1].ID
+	}
+
+	// Members without a users row. Users created after the scan have higher ids and are skipped.
+	extra := map[int]bool{}
+	for _, kind := range allTimeBoards {
+		err := s.leaderboardRepo.ScanMembers(boardKey(kind, PeriodWindow{Period: PeriodAllTime}), func(userID int) {
+			if !seen[userID] && userID <= afterID {
+				extra[userID] = true
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	report.Extra = len(extra)
+	if repair && len(extra) > 0 {
+		pipe := s.leaderboardRepo.Pipeline()
+		for userID := range extra {
+			s.leaderboardRepo.QueueRemoveUser(pipe, userID)
+		}
+		if _, err := pipe.Exec(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// repairUser re-adds one user to the all-time boards from MySQL.
+func (s *LeaderboardService) repairUser(userID int) (*models.User, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	pipe := s.leaderboardRepo.Pipeline()
+	s.QueueUser(pipe, user)
+	_, err = pipe.Exec(context.Background())
+	return user, err
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// typographicPunctuation folds the curly quotes and dashes keyboards and word processors insert
+// to the ASCII characters players type for them; NFKC keeps them as they are.
+var typographicPunctuation = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'",
+	"“", "\"", "”", "\"", "„", "\"",
+	"‐", "-", "‑", "-", "‒", "-", "–", "-", "—", "-", "−", "-",
+)
+
+// normalizeAnswer puts a submitted answer (or an option it is compared with) in canonical form:
+// Unicode NFKC (composed accents, full-width and other compatibility forms folded), typographic
+// punctuation folded to ASCII, invisible format characters removed and whitespace collapsed and
+// trimmed. Case is kept; foldAnswer is the case-insensitive form.
+func normalizeAnswer(s string) string {
+	s = typographicPunctuation.Replace(norm.NFKC.String(s))
+	s = strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// foldAnswer is normalizeAnswer followed by Unicode case folding, for comparing answers
+// regardless of case.
+func foldAnswer(s string) string {
+	return cases.Fold().String(normalizeAnswer(s))
+}
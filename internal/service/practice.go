@@ -0,0 +1,79 @@
+package service
+
+import (
+	"brainbolt/internal/models"
+	"brainbolt/internal/repository"
+	"math"
+	"time"
+)
+
+// PracticeMode is the study quiz mode: /v1/quiz/next?mode=practice serves the user's questions
+// that are due for review first (most overdue first) and falls back to new questions at their
+// level. Practice answers score nothing and leave stats, levels and leaderboards alone.
+const PracticeMode = repository.PracticeMode
+
+// SM-2 tunables. A question starts at the default ease factor, which each review moves by its
+// quality and never takes below the minimum. A missed question comes back after the relearn
+// delay, within the same session, before its intervals start growing again.
+const (
+	memoryDefaultEase    = 2.5
+	memoryMinEase        = 1.3
+	memoryRelearnDelay   = 10 * time.Minute
+	memoryQuickAnswer    = 10 * time.Second
+	memoryFirstInterval  = 1 // days
+	memorySecondInterval = 6 // days
+)
+
+// reviewQuality grades an answer on SM-2's 0-5 scale: 5 for a correct answer given within
+// memoryQuickAnswer, 4 for a slower correct one, 2 for a partly right one and 1 for a wrong one.
+// Anything below 3 is a lapse.
+func reviewQuality(grade Grade, timeToAnswer time.Duration) int {
+	switch {
+	case grade.Correct && timeToAnswer < memoryQuickAnswer:
+		return 5
+	case grade.Correct:
+		return 4
+	case grade.Credit > 0:
+		return 2
+	}
+	return 1
+}
+
+// tracksMemory reports whether an answer starts or updates the user's memory state for the
+// question: questions enter it when missed or answered in practice mode, and once in it every
+// answer to them (in any mode) updates it.
+func tracksMemory(prev *models.MemoryState, mode string, correct bool) bool {
+	return prev != nil || !correct || mode == PracticeMode
+}
+
+// nextMemory applies one review of quality 0-5 to prev (nil for a question not yet in memory)
+// with the SM-2 algorithm and returns the new state. A lapse resets the repetitions and makes
+// the question due again after memoryRelearnDelay; a success schedules it 1 day, 6 days and
+// then the previous interval times the ease factor ahead.
+func nextMemory(prev *models.MemoryState, userID int, questionID int, quality int, now time.Time) *models.MemoryState {
+	m := &models.MemoryState{UserID: userID, QuestionID: questionID, EaseFactor: memoryDefaultEase}
+	if prev != nil {
+		*m = *prev
+	}
+	if quality < 3 {
+		m.Repetitions = 0
+		m.IntervalDays = 0
+		m.Lapses++
+		m.DueAt = now.Add(memoryRelearnDelay)
+	} else {
+		switch m.Repetitions {
+		case 0:
+			m.IntervalDays = memoryFirstInterval
+		case 1:
+			m.IntervalDays = memorySecondInterval
+		default:
+			m.IntervalDays = int(math.Round(float64(m.IntervalDays) * m.EaseFactor))
+		}
+		m.Repetitions++
+		m.DueAt = now.AddDate(0, 0, m.IntervalDays)
+	}
+	miss := float64(5 - quality)
+	m.EaseFactor = math.Max(memoryMinEase, m.EaseFactor+0.1-miss*(0.08+miss*0.02))
+	m.ReviewedAt = now
+	return m
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"brainbolt/internal/models"
+)
+
+func TestReviewQuality(t *testing.T) {
+	tests := []struct {
+		name  string
+		grade Grade
+		dur   time.Duration
+		want  int
+	}{
+		{"quick and correct", Grade{Correct: true, Credit: 1}, 3 * time.Second, 5},
+		{"slow and correct", Grade{Correct: true, Credit: 1}, memoryQuickAnswer, 4},
+		{"partly right", Grade{Credit: 0.5}, time.Second, 2},
+		{"wrong", Grade{}, time.Second, 1},
+	}
+	for _, tt := range tests {
+		if got := reviewQuality(tt.grade, tt.dur); got != tt.want {
+			t.Errorf("%s: reviewQuality = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTracksMemory(t *testing.T) {
+	tracked := &models.MemoryState{}
+	tests := []struct {
+		name    string
+		prev    *models.MemoryState
+		mode    string
+		correct bool
+		want    bool
+	}{
+		{"new question answered right", nil, "", true, false},
+		{"new question missed", nil, "", false, true},
+		{"new question in practice", nil, PracticeMode, true, true},
+		{"tracked question answered right", tracked, "", true, true},
+	}
+	for _, tt := range tests {
+		if got := tracksMemory(tt.prev, tt.mode, tt.correct); got != tt.want {
+			t.Errorf("%s: tracksMemory = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNextMemory(t *testing.T) {
+	// review is one answer and the state it leaves; due is how long after the review the
+	// question is due again.
+	type review struct {
+		quality      int
+		repetitions  int
+		intervalDays int
+		ease         float64
+		lapses       int
+		due          time.Duration
+	}
+	const day = 24 * time.Hour
+	tests := []struct {
+		name    string
+		reviews []review
+	}{
+		{
+			name: "intervals grow 1, 6, then by the ease factor",
+			reviews: []review{
+				{4, 1, 1, 2.5, 0, day},
+				{5, 2, 6, 2.6, 0, 6 * day},
+				{4, 3, 16, 2.6, 0, 16 * day},
+				{3, 4, 42, 2.46, 0, 42 * day},
+			},
+		},
+		{
+			name: "a wrong answer resets the repetitions",
+			reviews: []review{
+				{5, 1, 1, 2.6, 0, day},
+				{5, 2, 6, 2.7, 0, 6 * day},
+				{1, 0, 0, 2.16, 1, memoryRelearnDelay},
+				{4, 1, 1, 2.16, 1, day},
+				{4, 2, 6, 2.16, 1, 6 * day},
+			},
+		},
+		{
+			name: "a partly right answer is a lapse",
+			reviews: []review{
+				{2, 0, 0, 2.18, 1, memoryRelearnDelay},
+				{2, 0, 0, 1.86, 2, memoryRelearnDelay},
+			},
+		},
+		{
+			name: "the ease factor stops at its minimum",
+			reviews: []review{
+				{1, 0, 0, 1.96, 1, memoryRelearnDelay},
+				{1, 0, 0, 1.42, 2, memoryRelearnDelay},
+				{0, 0, 0, memoryMinEase, 3, memoryRelearnDelay},
+				{3, 1, 1, memoryMinEase, 3, day},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+			var m *models.MemoryState
+			for i, r := range tt.reviews {
+				prev := m
+				m = nextMemory(prev, 7, 42, r.quality, now)
+				if m == prev {
+					t.Fatalf("review %d: nextMemory changed the previous state in place", i+1)
+				}
+				if m.UserID != 7 || m.QuestionID != 42 {
+					t.Errorf("review %d: user %d question %d, want 7 and 42", i+1, m.UserID, m.QuestionID)
+				}
+				if m.Repetitions != r.repetitions || m.IntervalDays != r.intervalDays || m.Lapses != r.lapses {
+					t.Errorf("review %d: repetitions %d, interval %d, lapses %d; want %d, %d, %d", i+1,
+						m.Repetitions, m.IntervalDays, m.Lapses, r.repetitions, r.intervalDays, r.lapses)
+				}
+				if math.Abs(m.EaseFactor-r.ease) > 1e-9 {
+					t.Errorf("review %d: ease factor %g, want %g", i+1, m.EaseFactor, r.ease)
+				}
+				if !m.DueAt.Equal(now.Add(r.due)) || !m.ReviewedAt.Equal(now) {
+					t.Errorf("review %d: due %v reviewed %v, want due %v", i+1, m.DueAt, m.ReviewedAt, now.Add(r.due))
+				}
+				now = m.DueAt
+			}
+		})
+	}
+}
@@ -0,0 +1,389 @@
+package service
+
+import (
+	"brainbolt/internal/models"
+	"brainbolt/internal/repository"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Question bank limits enforced on every created or edited question.
+const (
+	MinQuestionOptions    = 2
+	MaxQuestionOptions    = 6
+	maxQuestionLength     = 1000
+	maxOptionLength       = 255
+	maxQuestionTags       = 10
+	maxExplanationLength  = 2000
+	maxQuestionReferences = 5
+	maxReferenceLength    = 500
+)
+
+// QuestionInput is the editable content of a question, as sent to the admin API.
+type QuestionInput struct {
+	// Type is one of the QuestionType constants; empty means single choice.
+	Type       string   `json:"type"`
+	Difficulty int      `json:"difficulty"`
+	Question   string   `json:"question"`
+	Options    []string `json:"options"`
+	// Answer is the letter of the correct option ("A" is the first) or the option's text; for
+	// multi-select questions a comma-separated list of them, for numeric questions the number
+	// and for text questions the expected text.
+	Answer        string   `json:"answer"`
+	Tolerance     float64  `json:"tolerance"`
+	PartialCredit bool     `json:"partialCredit"`
+	Aliases       []string `json:"aliases"`
+	Category      string   `json:"category"`
+	Tags          []string `json:"tags"`
+	// Explanation and References are optional; they are shown after the question is answered.
+	Explanation string   `json:"explanation"`
+	References  []string `json:"references"`
+}
+
+// InvalidQuestionError lists everything wrong with a QuestionInput.
+type InvalidQuestionError struct {
+	Problems []string
+}
+
+func (e *InvalidQuestionError) Error() string {
+	return "invalid question: " + strings.Join(e.Problems, "; ")
+}
+
+// optionLetter is the answer letter of the i-th option.
+func optionLetter(i int) string {
+	return string(rune('A' + i))
+}
+
+// buildQuestion validates in and returns the question it describes, trimmed and normalized: the
+// type's grader checks options and answer (a choice answer becomes an option letter), category
+// and tags are lowercased ("general" if no category).
+func buildQuestion(in QuestionInput) (*models.Question, error) {
+	var problems []string
+	q := &models.Question{
+		Type:       strings.ToLower(strings.TrimSpace(in.Type)),
+		Difficulty: in.Difficulty,
+		Question:   strings.TrimSpace(in.Question),
+		Options:    []string{},
+		Category:   strings.ToLower(strings.TrimSpace(in.Category)),
+		Tags:       []string{},
+	}
+	if q.Type == "" {
+		q.Type = QuestionTypeSingle
+	}
+	if q.Difficulty < MinDifficulty || q.Difficulty > MaxDifficulty {
+		problems = append(problems, "difficulty must be between 1 and 10")
+	}
+	if q.Question == "" || len(q.Question) > maxQuestionLength {
+		problems = append(problems, "question must be 1-1000 characters")
+	}
+
+	if grader, ok := questionGraders[q.Type]; ok {
+		problems = append(problems, grader.Validate(q, in)...)
+	} else {
+		problems = append(problems, "type must be single, truefalse, multi, numeric or text")
+	}
+	if in.Tolerance != 0 && q.Type != QuestionTypeNumeric {
+		problems = append(problems, "tolerance only applies to numeric questions")
+	}
+	if in.PartialCredit && q.Type != QuestionTypeMulti {
+		problems = append(problems, "partial credit only applies to multi-select questions")
+	}
+	if len(in.Aliases) > 0 && q.Type != QuestionTypeText {
+		problems = append(problems, "aliases only apply to text questions")
+	}
+
+	if q.Category == "" {
+		q.Category = DefaultCategory
+	} else if !categoryPattern.MatchString(q.Category) {
+		problems = append(problems, "category must be 1-32 characters of letters, digits or '-'")
+	}
+	tags := map[string]bool{}
+	for _, tag := range in.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tags[tag] {
+			continue
+		}
+		if !categoryPattern.MatchString(tag) {
+			problems = append(problems, "tag "+tag+" must be 1-32 characters of letters, digits or '-'")
+			continue
+		}
+		tags[tag] = true
+		q.Tags = append(q.Tags, tag)
+	}
+	if len(q.Tags) > maxQuestionTags {
+		problems = append(problems, "a question can have at most 10 tags")
+	}
+
+	q.Explanation = strings.TrimSpace(in.Explanation)
+	if len(q.Explanation) > maxExplanationLength {
+		problems = append(problems, "explanation must be at most 2000 characters")
+	}
+	refs := map[string]bool{}
+	for _, ref := range in.References {
+		ref = strings.TrimSpace(ref)
+		if ref == "" || refs[ref] {
+			continue
+		}
+		if len(ref) > maxReferenceLength {
+			problems = append(problems, "references must be at most 500 characters")
+			continue
+		}
+		refs[ref] = true
+		q.References = append(q.References, ref)
+	}
+	if len(q.References) > maxQuestionReferences {
+		problems = append(problems, "a question can have at most 5 references")
+	}
+
+	if len(problems) > 0 {
+		return nil, &InvalidQuestionError{Problems: problems}
+	}
+	return q, nil
+}
+
+// QuestionBankService manages the question bank for the admin API and serves questions to the
+// answer path through a Redis cache, which every edit invalidates.
+type QuestionBankService struct {
+	questionRepo      *repository.QuestionRepository
+	questionCacheRepo *repository.QuestionCacheRepository
+}
+
+// NewQuestionBankService creates a new question bank service.
+func NewQuestionBankService(
+	questionRepo *repository.QuestionRepository,
+	questionCacheRepo *repository.QuestionCacheRepository,
+) *QuestionBankService {
+	return &QuestionBankService{
+		questionRepo:      questionRepo,
+		questionCacheRepo: questionCacheRepo,
+	}
+}
+
+// GetQuestion returns a question, retired ones included, from the cache or else MySQL (then
+// caching it). Returns ErrQuestionNotFound if there is none.
+func (s *QuestionBankService) GetQuestion(questionID int) (*models.Question, error) {
+	if s.questionCacheRepo != nil {
+		if q, err := s.questionCacheRepo.Get(questionID); err == nil && q != nil {
+			return q, nil
+		}
+	}
+	q, err := s.questionRepo.GetQuestionByID(questionID)
+	if err != nil {
+		return nil, err
+	}
+	if q == nil {
+		return nil, ErrQuestionNotFound
+	}
+	if s.questionCacheRepo != nil {
+		_ = s.questionCacheRepo.Set(q)
+	}
+	return q, nil
+}
+
+// ListQuestions returns a page of the question bank, by id.
+func (s *QuestionBankService) ListQuestions(filter repository.QuestionListFilter) ([]models.Question, error) {
+	return s.questionRepo.ListQuestions(filter)
+}
+
+// CreateQuestion validates and adds a question; it is servable right away.
+func (s *QuestionBankService) CreateQuestion(in QuestionInput) (*models.Question, error) {
+	q, err := buildQuestion(in)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.questionRepo.CreateQuestion(q); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// UpdateQuestion validates and replaces the content of a question (retired or not). Answers
+// already given keep their recorded correctness; pending answers are graded against the new content.
+func (s *QuestionBankService) UpdateQuestion(questionID int, in QuestionInput) (*models.Question, error) {
+	q, err := buildQuestion(in)
+	if err != nil {
+		return nil, err
+	}
+	q.ID = questionID
+	if err := s.questionRepo.UpdateQuestion(q); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrQuestionNotFound
+		}
+		return nil, err
+	}
+	s.Invalidate(questionID)
+	return s.questionRepo.GetQuestionByID(questionID)
+}
+
+// RetireQuestion stops serving a question. It stays in the bank so answer history keeps
+// pointing at it, and questions already served can still be answered.
+func (s *QuestionBankService) RetireQuestion(questionID int) (*models.Question, error) {
+	if err := s.questionRepo.RetireQuestion(questionID, time.Now()); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrQuestionNotFound
+		}
+		return nil, err
+	}
+	s.Invalidate(questionID)
+	return s.questionRepo.GetQuestionByID(questionID)
+}
+
+// Invalidate drops cached copies of questions after their rows changed. A failure is only
+// logged; the stale copy expires with the cache TTL.
+func (s *QuestionBankService) Invalidate(questionIDs ...int) {
+	if s.questionCacheRepo == nil {
+		return
+	}
+	if err := s.questionCacheRepo.Delete(questionIDs...); err != nil {
+		log.Printf("Failed to invalidate cached questions %v: %v", questionIDs, err)
+	}
+}
+
+// queueInvalidate queues the invalidation of a cached question on pipe.
+func (s *QuestionBankService) queueInvalidate(pipe *redis.Pipeline, questionID int) {
+	if s.questionCacheRepo != nil {
+		s.questionCacheRepo.QueueDelete(pipe, questionID)
+	}
+}
+
+// ImportReport is the outcome of a question import. Nothing is written when Errors is not empty
+// or on a dry run; duplicates and skipped questions are left out either way.
+type ImportReport struct {
+	Format string `json:"format"`
+	DryRun bool   `json:"dryRun"`
+	// Total is the number of questions read, New how many are valid and not duplicates, and
+	// Imported how many were written (New, or 0 on a dry run or with errors).
+	Total      int           `json:"total"`
+	New        int           `json:"new"`
+	Imported   int           `json:"imported"`
+	Duplicates []ImportIssue `json:"duplicates"`
+	// Skipped lists questions BrainBolt cannot represent (e.g. essay or matching questions).
+	Skipped []ImportIssue `json:"skipped"`
+	// Warnings lists what was dropped or approximated in the new questions (feedback, partial credit).
+	Warnings []ImportIssue `json:"warnings"`
+	Errors   []ImportIssue `json:"errors"`
+}
+
+// questionKey is the form under which question texts are compared for duplicates: lowercase
+// with whitespace collapsed.
+func questionKey(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+// ImportQuestions reads a question file (one of the QuestionFormat constants) and, unless dryRun,
+// adds its questions in one transaction. Questions whose text is already in the bank (retired
+// ones included) or earlier in the file are reported as duplicates and skipped; any invalid
+// question aborts the whole import.
+func (s *QuestionBankService) ImportQuestions(format string, data []byte, dryRun bool) (*ImportReport, error) {
+	rows, issues, err := parseQuestions(format, data)
+	if err != nil {
+		return nil, err
+	}
+	report := &ImportReport{
+		Format:     format,
+		DryRun:     dryRun,
+		Total:      len(rows),
+		Duplicates: []ImportIssue{},
+		Skipped:    []ImportIssue{},
+		Warnings:   []ImportIssue{},
+		Errors:     append([]ImportIssue{}, issues...),
+	}
+
+	texts, err := s.questionRepo.ListQuestionTexts()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]string, len(texts))
+	for id, text := range texts {
+		known[questionKey(text)] = fmt.Sprintf("question %d", id)
+	}
+
+	questions, err := reviewImport(report, rows, known)
+	if err != nil {
+		return nil, err
+	}
+	report.New = len(questions)
+
+	if dryRun || len(report.Errors) > 0 || len(questions) == 0 {
+		return report, nil
+	}
+	if err := s.questionRepo.CreateQuestions(questions); err != nil {
+		return nil, err
+	}
+	report.Imported = len(questions)
+	return report, nil
+}
+
+// reviewImport validates the rows of an import, adding their problems, duplicates (of known:
+// question key -> what it duplicates), skips and warnings to report, and returns the new
+// questions in file order.
+func reviewImport(report *ImportReport, rows []importRow, known map[string]string) ([]*models.Question, error) {
+	var questions []*models.Question
+	for _, row := range rows {
+		if row.Skip != "" {
+			report.Skipped = append(report.Skipped, ImportIssue{Line: row.Line, Message: row.Skip})
+			continue
+		}
+		// Format problems come first; validating a half-read question would only repeat them.
+		problems := row.Problems
+		var q *models.Question
+		if len(problems) == 0 {
+			var err error
+			q, err = buildQuestion(row.Input)
+			if invalid, ok := err.(*InvalidQuestionError); ok {
+				problems = invalid.Problems
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		if len(problems) > 0 {
+			for _, p := range problems {
+				report.Errors = append(report.Errors, ImportIssue{Line: row.Line, Message: p})
+			}
+			continue
+		}
+		key := questionKey(q.Question)
+		if dup, ok := known[key]; ok {
+			report.Duplicates = append(report.Duplicates, ImportIssue{Line: row.Line, Message: "duplicate of " + dup})
+			continue
+		}
+		known[key] = fmt.Sprintf("line %d", row.Line)
+		questions = append(questions, q)
+		for _, w := range row.Warnings {
+			report.Warnings = append(report.Warnings, ImportIssue{Line: row.Line, Message: w})
+		}
+	}
+	sort.SliceStable(report.Errors, func(i, j int) bool { return report.Errors[i].Line < report.Errors[j].Line })
+	return questions, nil
+}
+
+// ExportQuestions writes the questions matching filter (Offset and Limit are ignored) to w in a
+// question file format. It returns how many were written and how many were left out because
+// the format cannot hold their type.
+func (s *QuestionBankService) ExportQuestions(format string, w io.Writer, filter repository.QuestionListFilter) (int, int, error) {
+	if !questionFormats[format] {
+		return 0, 0, ErrUnknownQuestionFormat
+	}
+	questions := []models.Question{}
+	filter.Offset, filter.Limit = 0, syncBatchSize
+	for {
+		page, err := s.questionRepo.ListQuestions(filter)
+		if err != nil {
+			return 0, 0, err
+		}
+		questions = append(questions, page...)
+		if len(page) < filter.Limit {
+			break
+		}
+		filter.Offset += len(page)
+	}
+	written, err := writeQuestions(format, w, questions)
+	return written, len(questions) - written, err
+}
@@ -0,0 +1,475 @@
+package service
+
+import (
+	"brainbolt/internal/models"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Question file formats understood by ImportQuestions and ExportQuestions.
+const (
+	// QuestionFormatJSON is an array of questions shaped like the admin API body.
+	QuestionFormatJSON = "json"
+	// QuestionFormatCSV has a header row naming the columns: question, difficulty, answer,
+	// option_a ... option_f, and optionally type, tolerance, partial_credit, aliases, category,
+	// tags, explanation and references (lists separated by ';').
+	QuestionFormatCSV = "csv"
+	// QuestionFormatOpenTDB is an Open Trivia DB API response (default HTML-entity encoding).
+	QuestionFormatOpenTDB = "opentdb"
+	// QuestionFormatGIFT is Moodle's GIFT text format, see question_formats_moodle.go.
+	QuestionFormatGIFT = "gift"
+	// QuestionFormatMoodleXML is the Moodle XML question format.
+	QuestionFormatMoodleXML = "moodlexml"
+)
+
+// questionFormats are the formats ImportQuestions and ExportQuestions understand.
+var questionFormats = map[string]bool{
+	QuestionFormatJSON:      true,
+	QuestionFormatCSV:       true,
+	QuestionFormatOpenTDB:   true,
+	QuestionFormatGIFT:      true,
+	QuestionFormatMoodleXML: true,
+}
+
+// csvOptionColumns are the option columns of the CSV format, in answer letter order.
+var csvOptionColumns = []string{"option_a", "option_b", "option_c", "option_d", "option_e", "option_f"}
+
+// utf8BOM is the byte order mark some editors put at the start of text files.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// openTDBDifficulties maps Open Trivia DB difficulties onto levels, and back via openTDBDifficulty.
+var openTDBDifficulties = map[string]int{"easy": 2, "medium": 5, "hard": 8}
+
+// ImportIssue is one problem found in an import file, at the line its question starts on.
+type ImportIssue struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// importRow is one question read from an import file; Problems are format errors (e.g. a
+// difficulty that is not a number) found before validation. Skip is set, with the reason, for
+// questions the file format can hold but BrainBolt cannot (e.g. essay questions), and Warnings
+// list parts of the question that were dropped or approximated.
+type importRow struct {
+	Line     int
+	Input    QuestionInput
+	Problems []string
+	Skip     string
+	Warnings []string
+}
+
+// warn records a part of the question that could not be imported as is.
+func (r *importRow) warn(format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// parseQuestions reads every question of an import file. Issues that stop the file from being
+// read at all (malformed JSON or CSV, missing columns) are returned next to the rows read so far.
+func parseQuestions(format string, data []byte) ([]importRow, []ImportIssue, error) {
+	switch format {
+	case QuestionFormatJSON:
+		rows, issue := parseJSONQuestions(data)
+		return rows, issue, nil
+	case QuestionFormatCSV:
+		rows, issue := parseCSVQuestions(data)
+		return rows, issue, nil
+	case QuestionFormatOpenTDB:
+		rows, issue := parseOpenTDBQuestions(data)
+		return rows, issue, nil
+	case QuestionFormatGIFT:
+		rows, issue := parseGIFTQuestions(data)
+		return rows, issue, nil
+	case QuestionFormatMoodleXML:
+		rows, issue := parseMoodleXMLQuestions(data)
+		return rows, issue, nil
+	}
+	return nil, nil, ErrUnknownQuestionFormat
+}
+
+// lineAt returns the 1-based line of a byte offset in data.
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return 1 + bytes.Count(data[:offset], []byte("\n"))
+}
+
+// jsonIssue turns a JSON decoding error into an issue at the line it happened.
+func jsonIssue(data []byte, err error) ImportIssue {
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		return ImportIssue{Line: lineAt(data, e.Offset), Message: "malformed JSON: " + e.Error()}
+	case *json.UnmarshalTypeError:
+		return ImportIssue{Line: lineAt(data, e.Offset), Message: fmt.Sprintf("%s must be a %s, not a %s", e.Field, e.Type, e.Value)}
+	}
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		return ImportIssue{Line: lineAt(data, int64(len(data))), Message: "malformed JSON: unexpected end of input"}
+	}
+	return ImportIssue{Line: 1, Message: "malformed JSON: " + err.Error()}
+}
+
+// decodeJSONArray calls fn with each element of the array dec is positioned at and the line the
+// element starts on.
+func decodeJSONArray(dec *json.Decoder, data []byte, fn func(line int, raw json.RawMessage)) *ImportIssue {
+	tok, err := dec.Token()
+	if err != nil {
+		issue := jsonIssue(data, err)
+		return &issue
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		issue := ImportIssue{Line: lineAt(data, dec.InputOffset()), Message: "expected a JSON array of questions"}
+		return &issue
+	}
+	for dec.More() {
+		// The decoder stops right after the previous value; skip the separator to find the start.
+		start := dec.InputOffset()
+		for start < int64(len(data)) && strings.ContainsRune(" \t\r\n,", rune(data[start])) {
+			start++
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			issue := jsonIssue(data, err)
+			return &issue
+		}
+		fn(lineAt(data, start), raw)
+	}
+	if _, err := dec.Token(); err != nil {
+		issue := jsonIssue(data, err)
+		return &issue
+	}
+	return nil
+}
+
+// parseJSONQuestions reads the json format.
+func parseJSONQuestions(data []byte) ([]importRow, []ImportIssue) {
+	var rows []importRow
+	issue := decodeJSONArray(json.NewDecoder(bytes.NewReader(data)), data, func(line int, raw json.RawMessage) {
+		row := importRow{Line: line}
+		if err := json.Unmarshal(raw, &row.Input); err != nil {
+			row.Problems = append(row.Problems, jsonIssue(raw, err).Message)
+		}
+		rows = append(rows, row)
+	})
+	if issue != nil {
+		return rows, []ImportIssue{*issue}
+	}
+	return rows, nil
+}
+
+// parseCSVQuestions reads the csv format.
+func parseCSVQuestions(data []byte) ([]importRow, []ImportIssue) {
+	data = bytes.TrimPrefix(data, utf8BOM) // spreadsheet exports often start with a BOM
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, []ImportIssue{{Line: 1, Message: "missing CSV header row"}}
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"question", "difficulty", "answer"} {
+		if _, ok := columns[name]; !ok {
+			return nil, []ImportIssue{{Line: 1, Message: "CSV header has no " + name + " column"}}
+		}
+	}
+
+	var rows []importRow
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			line := 0
+			if pe, ok := err.(*csv.ParseError); ok {
+				line = pe.StartLine
+			}
+			return rows, []ImportIssue{{Line: line, Message: "malformed CSV: " + err.Error()}}
+		}
+		line, _ := r.FieldPos(0)
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row := importRow{Line: line}
+		row.Input.Type = cell("type")
+		row.Input.Question = cell("question")
+		row.Input.Answer = cell("answer")
+		row.Input.Category = cell("category")
+		row.Input.Aliases = splitList(cell("aliases"))
+		row.Input.Tags = splitList(cell("tags"))
+		row.Input.Explanation = cell("explanation")
+		row.Input.References = splitList(cell("references"))
+		for _, name := range csvOptionColumns {
+			if option := cell(name); option != "" {
+				row.Input.Options = append(row.Input.Options, option)
+			}
+		}
+		if row.Input.Difficulty, err = strconv.Atoi(cell("difficulty")); err != nil {
+			row.Problems = append(row.Problems, "difficulty must be a number")
+		}
+		if tolerance := cell("tolerance"); tolerance != "" {
+			if row.Input.Tolerance, err = strconv.ParseFloat(tolerance, 64); err != nil {
+				row.Problems = append(row.Problems, "tolerance must be a number")
+			}
+		}
+		if partial := cell("partial_credit"); partial != "" {
+			if row.Input.PartialCredit, err = strconv.ParseBool(partial); err != nil {
+				row.Problems = append(row.Problems, "partial_credit must be true or false")
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+// splitList splits a ';'-separated CSV cell, dropping empty entries.
+func splitList(cell string) []string {
+	var list []string
+	for _, item := range strings.Split(cell, ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// openTDBQuestion is one entry of an Open Trivia DB response.
+type openTDBQuestion struct {
+	Type             string   `json:"type"`
+	Difficulty       string   `json:"difficulty"`
+	Category         string   `json:"category"`
+	Question         string   `json:"question"`
+	CorrectAnswer    string   `json:"correct_answer"`
+	IncorrectAnswers []string `json:"incorrect_answers"`
+}
+
+// parseOpenTDBQuestions reads the opentdb format. "Science: Computers" becomes category science
+// with tag computers; multiple-choice options are sorted so the answer letter does not give the
+// answer away, true/false questions keep True before False.
+func parseOpenTDBQuestions(data []byte) ([]importRow, []ImportIssue) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, []ImportIssue{{Line: 1, Message: "expected an Open Trivia DB response object"}}
+	}
+	var rows []importRow
+	found := false
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return rows, []ImportIssue{jsonIssue(data, err)}
+		}
+		switch tok {
+		case "response_code":
+			var code int
+			if err := dec.Decode(&code); err != nil {
+				return rows, []ImportIssue{jsonIssue(data, err)}
+			}
+			if code != 0 {
+				return rows, []ImportIssue{{Line: lineAt(data, dec.InputOffset()),
+					Message: fmt.Sprintf("Open Trivia DB response_code is %d, not 0", code)}}
+			}
+		case "results":
+			found = true
+			issue := decodeJSONArray(dec, data, func(line int, raw json.RawMessage) {
+				row := importRow{Line: line}
+				var q openTDBQuestion
+				if err := json.Unmarshal(raw, &q); err != nil {
+					row.Problems = append(row.Problems, jsonIssue(raw, err).Message)
+				} else {
+					row.Input, row.Problems = openTDBInput(q)
+				}
+				rows = append(rows, row)
+			})
+			if issue != nil {
+				return rows, []ImportIssue{*issue}
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return rows, []ImportIssue{jsonIssue(data, err)}
+			}
+		}
+	}
+	if !found {
+		return nil, []ImportIssue{{Line: 1, Message: "Open Trivia DB response has no results"}}
+	}
+	return rows, nil
+}
+
+// openTDBInput maps an Open Trivia DB question onto a QuestionInput.
+func openTDBInput(q openTDBQuestion) (QuestionInput, []string) {
+	var problems []string
+	in := QuestionInput{
+		Question: html.UnescapeString(q.Question),
+		Answer:   html.UnescapeString(q.CorrectAnswer),
+	}
+	difficulty, ok := openTDBDifficulties[q.Difficulty]
+	if !ok {
+		problems = append(problems, "difficulty must be easy, medium or hard")
+	}
+	in.Difficulty = difficulty
+
+	category, subcategory, _ := strings.Cut(html.UnescapeString(q.Category), ":")
+	in.Category = slugify(category)
+	if tag := slugify(subcategory); tag != "" {
+		in.Tags = []string{tag}
+	}
+
+	in.Options = []string{in.Answer}
+	for _, option := range q.IncorrectAnswers {
+		in.Options = append(in.Options, html.UnescapeString(option))
+	}
+	switch q.Type {
+	case "boolean":
+		in.Type = QuestionTypeTrueFalse
+		in.Options = []string{"True", "False"}
+	case "multiple":
+		sort.Strings(in.Options)
+	default:
+		problems = append(problems, "type must be multiple or boolean")
+	}
+	return in, problems
+}
+
+// slugify turns a free-form name into a category or tag: lowercase letters and digits joined by
+// '-', at most 32 characters.
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	slug := b.String()
+	if len(slug) > 32 {
+		slug = strings.TrimRight(slug[:32], "-")
+	}
+	return slug
+}
+
+// writeQuestions encodes questions in one of the file formats and returns how many were
+// written; questions of a type the format cannot hold are left out.
+func writeQuestions(format string, w io.Writer, questions []models.Question) (int, error) {
+	switch format {
+	case QuestionFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return len(questions), enc.Encode(questions)
+	case QuestionFormatCSV:
+		return len(questions), writeCSVQuestions(w, questions)
+	case QuestionFormatOpenTDB:
+		return writeOpenTDBQuestions(w, questions)
+	case QuestionFormatGIFT:
+		return len(questions), writeGIFTQuestions(w, questions)
+	case QuestionFormatMoodleXML:
+		return len(questions), writeMoodleXMLQuestions(w, questions)
+	}
+	return 0, ErrUnknownQuestionFormat
+}
+
+// writeCSVQuestions writes the csv format, with an extra id column.
+func writeCSVQuestions(w io.Writer, questions []models.Question) error {
+	cw := csv.NewWriter(w)
+	header := append([]string{"id", "type", "difficulty", "category", "tags", "question", "answer",
+		"tolerance", "partial_credit", "aliases", "explanation", "references"}, csvOptionColumns...)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, q := range questions {
+		tolerance, partial := "", ""
+		if q.Tolerance != 0 {
+			tolerance = strconv.FormatFloat(q.Tolerance, 'g', -1, 64)
+		}
+		if q.PartialCredit {
+			partial = "true"
+		}
+		record := []string{strconv.Itoa(q.ID), questionType(&q), strconv.Itoa(q.Difficulty), q.Category,
+			strings.Join(q.Tags, ";"), q.Question, q.Answer, tolerance, partial, strings.Join(q.Aliases, ";"),
+			q.Explanation, strings.Join(q.References, ";")}
+		for i := range csvOptionColumns {
+			option := ""
+			if i < len(q.Options) {
+				option = q.Options[i]
+			}
+			record = append(record, option)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeOpenTDBQuestions writes an Open Trivia DB response. Category and first tag are joined
+// back as "category: tag"; levels map to easy (1-3), medium (4-7) and hard (8-10). Open Trivia
+// DB only has single-choice and true/false questions; the other types are left out.
+func writeOpenTDBQuestions(w io.Writer, questions []models.Question) (int, error) {
+	results := make([]openTDBQuestion, 0, len(questions))
+	for _, q := range questions {
+		if t := questionType(&q); t != QuestionTypeSingle && t != QuestionTypeTrueFalse {
+			continue
+		}
+		out := openTDBQuestion{
+			Type:             "multiple",
+			Difficulty:       openTDBDifficulty(q.Difficulty),
+			Category:         html.EscapeString(q.Category),
+			Question:         html.EscapeString(q.Question),
+			IncorrectAnswers: []string{},
+		}
+		if len(q.Tags) > 0 {
+			out.Category += ": " + html.EscapeString(q.Tags[0])
+		}
+		if len(q.Options) == 2 && strings.EqualFold(q.Options[0], "true") && strings.EqualFold(q.Options[1], "false") {
+			out.Type = "boolean"
+		}
+		for i, option := range q.Options {
+			if optionLetter(i) == q.Answer {
+				out.CorrectAnswer = html.EscapeString(option)
+			} else {
+				out.IncorrectAnswers = append(out.IncorrectAnswers, html.EscapeString(option))
+			}
+		}
+		results = append(results, out)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false) // already entity-encoded, as Open Trivia DB does
+	enc.SetIndent("", "  ")
+	return len(results), enc.Encode(struct {
+		ResponseCode int               `json:"response_code"`
+		Results      []openTDBQuestion `json:"results"`
+	}{0, results})
+}
+
+// openTDBDifficulty maps a level onto an Open Trivia DB difficulty.
+func openTDBDifficulty(level int) string {
+	switch {
+	case level <= 3:
+		return "easy"
+	case level <= 7:
+		return "medium"
+	}
+	return "hard"
+}
@@ -0,0 +1,802 @@
+package service
+
+import (
+	"brainbolt/internal/models"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GIFT and Moodle XML have no difficulty, so it travels as a "difficulty:N" tag; questions
+// without one are imported at lmsDefaultDifficulty. Categories travel as Moodle category paths
+// ("$course$/top/geography").
+const (
+	lmsDifficultyTag     = "difficulty:"
+	lmsDefaultDifficulty = 5
+	lmsCategoryPrefix    = "$course$/top/"
+)
+
+// lmsAnswer is one answer of a GIFT or Moodle XML question; Fraction is the percentage of the
+// grade it earns (100 for the correct answer).
+type lmsAnswer struct {
+	Text     string
+	Fraction float64
+	Feedback bool
+}
+
+// setLMSAnswers maps the answers of a multiple-choice question onto row: one fully correct
+// answer makes a single-choice question, several correct or only partly correct answers a
+// multi-select question with partial credit. Penalties, the exact weights of multi-select
+// answers and feedback have no BrainBolt equivalent and are reported as warnings.
+func setLMSAnswers(row *importRow, answers []lmsAnswer) {
+	var positive []string
+	full := ""
+	fullCount := 0
+	for i, a := range answers {
+		letter := optionLetter(i)
+		row.Input.Options = append(row.Input.Options, a.Text)
+		if a.Fraction >= 100 {
+			full = letter
+			fullCount++
+		}
+		if a.Fraction > 0 {
+			positive = append(positive, letter)
+		}
+		if a.Feedback {
+			row.warn("feedback of option %s dropped", letter)
+		}
+	}
+	switch {
+	case len(positive) == 0:
+		row.Skip = "no option is marked correct"
+	case fullCount == 1:
+		row.Input.Answer = full
+		for i, a := range answers {
+			switch {
+			case a.Fraction > 0 && a.Fraction < 100:
+				row.warn("partial credit (%g%%) of option %s dropped, it counts as wrong", a.Fraction, optionLetter(i))
+			case a.Fraction < 0:
+				row.warn("penalty (%g%%) of option %s dropped", a.Fraction, optionLetter(i))
+			}
+		}
+	default:
+		row.Input.Type = QuestionTypeMulti
+		row.Input.Answer = strings.Join(positive, ",")
+		row.Input.PartialCredit = true
+		row.warn("option weights replaced by partial credit (right minus wrong picks)")
+	}
+}
+
+// setLMSBoolean maps a true/false question onto row.
+func setLMSBoolean(row *importRow, truth bool) {
+	row.Input.Type = QuestionTypeTrueFalse
+	row.Input.Options = []string{"True", "False"}
+	row.Input.Answer = "A"
+	if !truth {
+		row.Input.Answer = "B"
+	}
+}
+
+// setLMSText maps the answers of a short-answer question onto a text question: the first fully
+// correct answer is the answer, the other ones aliases. Answers worth less are dropped.
+func setLMSText(row *importRow, answers []lmsAnswer) {
+	row.Input.Type = QuestionTypeText
+	for _, a := range answers {
+		switch {
+		case a.Fraction < 100:
+			row.warn("answer %q worth %g%% dropped", a.Text, a.Fraction)
+		case row.Input.Answer == "":
+			row.Input.Answer = a.Text
+		default:
+			row.Input.Aliases = append(row.Input.Aliases, a.Text)
+		}
+		if a.Feedback {
+			row.warn("feedback of answer %q dropped", a.Text)
+		}
+		if strings.Contains(a.Text, "*") {
+			row.warn("wildcard in answer %q is matched literally", a.Text)
+		}
+	}
+	if row.Input.Answer == "" {
+		row.Skip = "no answer is marked correct"
+	}
+}
+
+// setLMSNumeric maps the answer of a numerical question onto a numeric question.
+func setLMSNumeric(row *importRow, value string, tolerance string) {
+	row.Input.Type = QuestionTypeNumeric
+	row.Input.Answer = strings.TrimSpace(value) // checked by the numeric grader
+	if tolerance = strings.TrimSpace(tolerance); tolerance != "" {
+		t, err := strconv.ParseFloat(tolerance, 64)
+		if err != nil {
+			row.Problems = append(row.Problems, "tolerance must be a number")
+		}
+		row.Input.Tolerance = math.Abs(t)
+	}
+}
+
+// lmsWeight is the Moodle fraction of an option of a multi-select question with correct
+// correct options: the correct ones share 100%, each wrong pick costs as much as a right one
+// earns, like BrainBolt's partial credit.
+func lmsWeight(right bool, correct int) float64 {
+	w := math.Round(100/float64(correct)*1e5) / 1e5
+	if !right {
+		return -w
+	}
+	return w
+}
+
+// lmsCorrectLetters returns the set of correct option letters of a choice question.
+func lmsCorrectLetters(q *models.Question) map[string]bool {
+	letters := map[string]bool{}
+	for _, letter := range strings.Split(q.Answer, ",") {
+		letters[letter] = true
+	}
+	return letters
+}
+
+// setLMSCategory maps a Moodle category path like an Open Trivia DB category: the first real
+// category becomes the BrainBolt category and the ones below it tags. Moodle's "top" and
+// "Default for ..." categories are skipped.
+func setLMSCategory(row *importRow, path string) {
+	first := true
+	for _, name := range strings.Split(path, "/") {
+		name = strings.TrimSpace(name)
+		lower := strings.ToLower(name)
+		if name == "" || strings.HasPrefix(name, "$") || lower == "top" || strings.HasPrefix(lower, "default for ") {
+			continue
+		}
+		slug := slugify(name)
+		if slug == "" {
+			row.warn("category %q dropped", name)
+			continue
+		}
+		if first {
+			row.Input.Category = slug
+			first = false
+		} else {
+			row.Input.Tags = append(row.Input.Tags, slug)
+		}
+	}
+}
+
+// setLMSTags maps Moodle tags onto row: "difficulty:N" sets the difficulty, other tags are
+// slugified.
+func setLMSTags(row *importRow, tags []string) {
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if level, ok := strings.CutPrefix(strings.ToLower(tag), lmsDifficultyTag); ok {
+			n, err := strconv.Atoi(strings.TrimSpace(level))
+			if err != nil {
+				row.Problems = append(row.Problems, "tag "+tag+": difficulty must be a number")
+			}
+			row.Input.Difficulty = n
+			continue
+		}
+		if slug := slugify(tag); slug != "" {
+			row.Input.Tags = append(row.Input.Tags, slug)
+		} else if tag != "" {
+			row.warn("tag %q dropped", tag)
+		}
+	}
+}
+
+// finishLMSRow gives a question without a difficulty tag the default difficulty.
+func finishLMSRow(row *importRow) {
+	if row.Input.Difficulty == 0 && row.Skip == "" && len(row.Problems) == 0 {
+		row.Input.Difficulty = lmsDefaultDifficulty
+		row.warn("no %sN tag, imported at difficulty %d", lmsDifficultyTag, lmsDefaultDifficulty)
+	}
+}
+
+// htmlTag matches an HTML tag; group 1 is its name.
+var htmlTag = regexp.MustCompile(`</?([a-zA-Z][a-zA-Z0-9]*)[^>]*>`)
+
+// lmsText turns text in a Moodle text format ("html", "plain_text", "moodle_auto_format",
+// "markdown") into plain text with whitespace collapsed. HTML markup is removed; anything beyond
+// paragraphs, line breaks and inline emphasis (images, tables, sub- and superscripts) is
+// reported since the text may no longer read the same.
+func lmsText(row *importRow, text, format string) string {
+	if strings.EqualFold(format, "html") {
+		var dropped []string
+		text = htmlTag.ReplaceAllStringFunc(text, func(tag string) string {
+			name := strings.ToLower(htmlTag.FindStringSubmatch(tag)[1])
+			switch name {
+			case "p", "br", "div":
+				return " "
+			case "span", "b", "strong", "i", "em", "u":
+				return ""
+			}
+			if !strings.HasPrefix(tag, "</") {
+				dropped = append(dropped, "<"+name+">")
+			}
+			return " "
+		})
+		text = html.UnescapeString(text)
+		if len(dropped) > 0 {
+			row.warn("HTML %s removed", strings.Join(dropped, ", "))
+		}
+	}
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// lmsBoolean reports whether a question is a true/false question, or a single-choice one with
+// the options True and False.
+func lmsBoolean(q *models.Question) bool {
+	if questionType(q) == QuestionTypeTrueFalse {
+		return true
+	}
+	return questionType(q) == QuestionTypeSingle && len(q.Options) == 2 &&
+		strings.EqualFold(q.Options[0], "true") && strings.EqualFold(q.Options[1], "false")
+}
+
+// lmsTags are the Moodle tags of a question: its difficulty, then its tags.
+func lmsTags(q *models.Question) []string {
+	return append([]string{lmsDifficultyTag + strconv.Itoa(q.Difficulty)}, q.Tags...)
+}
+
+// giftSpecial are the characters GIFT escapes with a backslash.
+const giftSpecial = "~=#{}:\\"
+
+// giftIndex returns the index of the first unescaped occurrence of sub in s, or -1.
+func giftIndex(s, sub string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+// giftUnescape resolves GIFT escapes: "\n" is a line break, "\x" the character x.
+func giftUnescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			if s[i] == 'n' {
+				b.WriteByte('\n')
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// giftEscape escapes text for GIFT.
+func giftEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\n':
+			b.WriteString(`\n`)
+		case strings.ContainsRune(giftSpecial, r):
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// giftTag matches Moodle's tag markers in GIFT comments: "// [tag:geography] [tag:difficulty:3]".
+var giftTag = regexp.MustCompile(`\[tag:([^\]]+)\]`)
+
+// parseGIFTQuestions reads the gift format: questions separated by blank lines, "//" comments
+// (the ones right before a question may hold "[tag:...]" markers) and "$CATEGORY:" lines.
+func parseGIFTQuestions(data []byte) ([]importRow, []ImportIssue) {
+	text := strings.ReplaceAll(string(bytes.TrimPrefix(data, utf8BOM)), "\r\n", "\n")
+	var rows []importRow
+	category := ""
+	var block, tags []string
+	start := 0
+	flush := func() {
+		if len(block) > 0 {
+			row := importRow{Line: start}
+			setLMSCategory(&row, category)
+			setLMSTags(&row, tags)
+			parseGIFTQuestion(&row, strings.Join(block, "\n"))
+			finishLMSRow(&row)
+			rows = append(rows, row)
+		}
+		block, tags = nil, nil
+	}
+	for i, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "//"):
+			for _, m := range giftTag.FindAllStringSubmatch(trimmed, -1) {
+				tags = append(tags, m[1])
+			}
+		case len(block) == 0 && strings.HasPrefix(trimmed, "$CATEGORY:"):
+			category = strings.TrimSpace(strings.TrimPrefix(trimmed, "$CATEGORY:"))
+			tags = nil
+		default:
+			if len(block) == 0 {
+				start = i + 1
+			}
+			block = append(block, line)
+		}
+	}
+	flush()
+	return rows, nil
+}
+
+// parseGIFTQuestion reads one GIFT question: an optional ::title:: (dropped) and [format], the
+// text and an {answer block}. Text after the block makes a missing-word question, whose gap is
+// shown as "_____".
+func parseGIFTQuestion(row *importRow, src string) {
+	s := strings.TrimSpace(src)
+	if strings.HasPrefix(s, "::") {
+		end := giftIndex(s[2:], "::")
+		if end < 0 {
+			row.Problems = append(row.Problems, "unterminated ::title::")
+			return
+		}
+		s = strings.TrimSpace(s[end+4:])
+	}
+	format := "moodle_auto_format"
+	if strings.HasPrefix(s, "[") {
+		if end := strings.IndexByte(s, ']'); end > 0 {
+			format, s = strings.ToLower(s[1:end]), s[end+1:]
+		}
+	}
+	open := giftIndex(s, "{")
+	if open < 0 {
+		row.Skip = "descriptions (text without an answer block) are not questions"
+		return
+	}
+	end := giftIndex(s[open:], "}")
+	if end < 0 {
+		row.Problems = append(row.Problems, "unterminated {answer block}")
+		return
+	}
+	text, answers, after := s[:open], s[open+1:open+end], strings.TrimSpace(s[open+end+1:])
+	if after != "" {
+		text = strings.TrimSpace(text) + " _____ " + after
+	}
+	row.Input.Question = lmsText(row, giftUnescape(text), format)
+	parseGIFTAnswers(row, answers, format)
+}
+
+// parseGIFTAnswers reads an answer block: true/false ({T}), multiple choice ({=right ~wrong},
+// with several correct or weighted answers a multi-select question), numerical ({#3.14:0.01} or
+// {#1..5}) and short answer ({=answer =alias}). Essay and matching questions are skipped.
+// General feedback (####text) becomes the explanation.
+func parseGIFTAnswers(row *importRow, block, format string) {
+	block = strings.TrimSpace(block)
+	if i := giftIndex(block, "####"); i >= 0 {
+		row.Input.Explanation = lmsText(row, giftUnescape(block[i+4:]), format)
+		block = strings.TrimSpace(block[:i])
+	}
+	head, feedback := block, ""
+	if i := giftIndex(block, "#"); i >= 0 {
+		head, feedback = block[:i], block[i:]
+	}
+	switch strings.ToUpper(strings.TrimSpace(head)) {
+	case "T", "TRUE", "F", "FALSE":
+		setLMSBoolean(row, strings.HasPrefix(strings.ToUpper(strings.TrimSpace(head)), "T"))
+		if strings.Trim(feedback, "# \n") != "" {
+			row.warn("feedback dropped")
+		}
+		return
+	case "":
+		if block == "" {
+			row.Skip = "essay questions are not supported"
+		} else {
+			parseGIFTNumeric(row, block[1:])
+		}
+		return
+	}
+
+	// Split at every unescaped = (correct) or ~ (wrong) marker.
+	var answers []lmsAnswer
+	var texts []string
+	wrong := false
+	from := -1
+	for i := 0; i < len(block); i++ {
+		c := block[i]
+		switch {
+		case c == '\\':
+			i++
+			continue
+		case c != '=' && c != '~':
+			if from < 0 && !strings.ContainsRune(" \t\n", rune(c)) {
+				row.Problems = append(row.Problems, "answers must start with = or ~")
+				return
+			}
+			continue
+		}
+		if from >= 0 {
+			texts = append(texts, block[from:i])
+		}
+		from = i + 1
+		if c == '=' {
+			answers = append(answers, lmsAnswer{Fraction: 100})
+		} else {
+			answers = append(answers, lmsAnswer{})
+			wrong = true
+		}
+	}
+	if from >= 0 {
+		texts = append(texts, block[from:])
+	}
+	for i, text := range texts {
+		if giftIndex(text, "->") >= 0 {
+			row.Skip = "matching questions are not supported"
+			return
+		}
+		if strings.HasPrefix(text, "%") {
+			if end := strings.IndexByte(text[1:], '%'); end >= 0 {
+				fraction, err := strconv.ParseFloat(text[1:end+1], 64)
+				if err != nil {
+					row.Problems = append(row.Problems, "option "+optionLetter(i)+" has an invalid weight")
+				}
+				answers[i].Fraction, text = fraction, text[end+2:]
+			}
+		}
+		if f := giftIndex(text, "#"); f >= 0 {
+			answers[i].Feedback = strings.TrimSpace(text[f+1:]) != ""
+			text = text[:f]
+		}
+		answers[i].Text = lmsText(row, giftUnescape(strings.TrimSpace(text)), format)
+	}
+	if !wrong {
+		setLMSText(row, answers)
+		return
+	}
+	setLMSAnswers(row, answers)
+}
+
+// parseGIFTNumeric reads the answer of a numerical question: "3.14:0.01" (value and
+// tolerance) or "1..5" (a range), optionally followed by #feedback.
+func parseGIFTNumeric(row *importRow, spec string) {
+	if strings.HasPrefix(strings.TrimSpace(spec), "=") {
+		row.Skip = "numerical questions with several answers are not supported"
+		return
+	}
+	if i := giftIndex(spec, "#"); i >= 0 {
+		if strings.TrimSpace(spec[i+1:]) != "" {
+			row.warn("feedback dropped")
+		}
+		spec = spec[:i]
+	}
+	spec = strings.TrimSpace(spec)
+	if low, high, ok := strings.Cut(spec, ".."); ok {
+		lo, errLow := strconv.ParseFloat(strings.TrimSpace(low), 64)
+		hi, errHigh := strconv.ParseFloat(strings.TrimSpace(high), 64)
+		if errLow != nil || errHigh != nil || hi < lo {
+			row.Input.Type = QuestionTypeNumeric
+			row.Problems = append(row.Problems, "invalid numeric range "+spec)
+			return
+		}
+		setLMSNumeric(row, strconv.FormatFloat((lo+hi)/2, 'g', -1, 64), strconv.FormatFloat((hi-lo)/2, 'g', -1, 64))
+		return
+	}
+	value, tolerance, _ := strings.Cut(spec, ":")
+	setLMSNumeric(row, value, tolerance)
+}
+
+// writeGIFTQuestions writes the gift format. A $CATEGORY line starts each run of questions of
+// the same category; difficulty and tags go in a "// [tag:...]" comment above each question.
+// Multi-select questions get weighted answers, which Moodle always grades with partial credit.
+// The explanation is written as general feedback; GIFT has no place for references.
+func writeGIFTQuestions(w io.Writer, questions []models.Question) error {
+	var b strings.Builder
+	category := ""
+	for _, q := range questions {
+		if q.Category != category {
+			category = q.Category
+			fmt.Fprintf(&b, "$CATEGORY: %s%s\n\n", lmsCategoryPrefix, category)
+		}
+		b.WriteString("//")
+		for _, tag := range lmsTags(&q) {
+			fmt.Fprintf(&b, " [tag:%s]", tag)
+		}
+		fmt.Fprintf(&b, "\n::brainbolt-%d::%s {", q.ID, giftEscape(q.Question))
+		feedback := ""
+		if q.Explanation != "" {
+			feedback = "####" + giftEscape(q.Explanation)
+		}
+		switch {
+		case lmsBoolean(&q):
+			if q.Answer == "A" {
+				fmt.Fprintf(&b, "T%s}\n\n", feedback)
+			} else {
+				fmt.Fprintf(&b, "F%s}\n\n", feedback)
+			}
+			continue
+		case q.Type == QuestionTypeNumeric:
+			fmt.Fprintf(&b, "#%s:%s%s}\n\n", q.Answer, strconv.FormatFloat(q.Tolerance, 'g', -1, 64), feedback)
+			continue
+		}
+		b.WriteString("\n")
+		switch q.Type {
+		case QuestionTypeText:
+			for _, answer := range append([]string{q.Answer}, q.Aliases...) {
+				fmt.Fprintf(&b, "\t=%s\n", giftEscape(answer))
+			}
+		case QuestionTypeMulti:
+			correct := lmsCorrectLetters(&q)
+			for i, option := range q.Options {
+				weight := lmsWeight(correct[optionLetter(i)], len(correct))
+				fmt.Fprintf(&b, "\t~%%%s%%%s\n", strconv.FormatFloat(weight, 'f', -1, 64), giftEscape(option))
+			}
+		default:
+			for i, option := range q.Options {
+				marker := "~"
+				if optionLetter(i) == q.Answer {
+					marker = "="
+				}
+				fmt.Fprintf(&b, "\t%s%s\n", marker, giftEscape(option))
+			}
+		}
+		if feedback != "" {
+			fmt.Fprintf(&b, "\t%s\n", feedback)
+		}
+		b.WriteString("}\n\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// moodleText is a Moodle XML text element, optionally with a text format.
+type moodleText struct {
+	Format string `xml:"format,attr,omitempty"`
+	Text   string `xml:"text"`
+}
+
+// moodleAnswer is a Moodle XML answer; Fraction is the percentage of the grade it earns.
+type moodleAnswer struct {
+	Fraction  float64     `xml:"fraction,attr"`
+	Format    string      `xml:"format,attr,omitempty"`
+	Text      string      `xml:"text"`
+	Feedback  *moodleText `xml:"feedback,omitempty"`
+	Tolerance string      `xml:"tolerance,omitempty"` // numerical questions
+}
+
+// moodleQuestion is a Moodle XML question, or a category marker when Type is "category".
+type moodleQuestion struct {
+	Type            string         `xml:"type,attr"`
+	Category        *moodleText    `xml:"category,omitempty"`
+	Name            *moodleText    `xml:"name,omitempty"`
+	QuestionText    *moodleText    `xml:"questiontext,omitempty"`
+	GeneralFeedback *moodleText    `xml:"generalfeedback,omitempty"`
+	Single          string         `xml:"single,omitempty"`
+	UseCase         string         `xml:"usecase,omitempty"`
+	Answers         []moodleAnswer `xml:"answer"`
+	Tags            *moodleTags    `xml:"tags,omitempty"`
+}
+
+// moodleTags is the tag list of a Moodle XML question.
+type moodleTags struct {
+	Tags []moodleText `xml:"tag"`
+}
+
+// xmlIssue turns an XML decoding error into an issue at the line it happened.
+func xmlIssue(dec *xml.Decoder, err error) ImportIssue {
+	line, _ := dec.InputPos()
+	if e, ok := err.(*xml.SyntaxError); ok {
+		line = e.Line
+	}
+	return ImportIssue{Line: line, Message: "malformed XML: " + err.Error()}
+}
+
+// parseMoodleXMLQuestions reads the moodlexml format. Category markers set the category of the
+// questions after them; multichoice, truefalse, numerical and shortanswer questions map onto
+// BrainBolt question types, the others are skipped.
+func parseMoodleXMLQuestions(data []byte) ([]importRow, []ImportIssue) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var rows []importRow
+	category := ""
+	quiz := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return rows, []ImportIssue{xmlIssue(dec, err)}
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if !quiz {
+			if start.Name.Local != "quiz" {
+				line, _ := dec.InputPos()
+				return nil, []ImportIssue{{Line: line, Message: "expected a Moodle XML <quiz> element"}}
+			}
+			quiz = true
+			continue
+		}
+		line, _ := dec.InputPos()
+		if start.Name.Local != "question" {
+			if err := dec.Skip(); err != nil {
+				return rows, []ImportIssue{xmlIssue(dec, err)}
+			}
+			continue
+		}
+		var q moodleQuestion
+		if err := dec.DecodeElement(&q, &start); err != nil {
+			return rows, []ImportIssue{xmlIssue(dec, err)}
+		}
+		if q.Type == "category" {
+			if q.Category != nil {
+				category = q.Category.Text
+			}
+			continue
+		}
+		row := importRow{Line: line}
+		moodleInput(&row, &q, category)
+		rows = append(rows, row)
+	}
+	if !quiz {
+		return nil, []ImportIssue{{Line: 1, Message: "expected a Moodle XML <quiz> element"}}
+	}
+	return rows, nil
+}
+
+// moodleInput maps a Moodle XML question onto row.
+func moodleInput(row *importRow, q *moodleQuestion, category string) {
+	if q.QuestionText != nil {
+		row.Input.Question = lmsText(row, q.QuestionText.Text, q.QuestionText.Format)
+	}
+	answers := make([]lmsAnswer, len(q.Answers))
+	for i, a := range q.Answers {
+		answers[i] = lmsAnswer{
+			Text:     lmsText(row, a.Text, a.Format),
+			Fraction: a.Fraction,
+			Feedback: a.Feedback != nil && strings.TrimSpace(a.Feedback.Text) != "",
+		}
+	}
+	switch q.Type {
+	case "multichoice":
+		setLMSAnswers(row, answers)
+	case "shortanswer":
+		setLMSText(row, answers)
+		if strings.TrimSpace(q.UseCase) == "1" {
+			row.warn("case-sensitive matching dropped, answers are compared ignoring case")
+		}
+	case "numerical":
+		found := false
+		for i, a := range q.Answers {
+			switch {
+			case a.Fraction >= 100 && !found:
+				setLMSNumeric(row, answers[i].Text, a.Tolerance)
+				found = true
+			default:
+				row.warn("answer %s worth %g%% dropped", answers[i].Text, a.Fraction)
+			}
+			if answers[i].Feedback {
+				row.warn("feedback of answer %s dropped", answers[i].Text)
+			}
+		}
+		if !found {
+			row.Skip = "no answer is marked correct"
+		}
+	case "truefalse":
+		truth, found := false, false
+		for _, a := range q.Answers {
+			if a.Fraction >= 100 {
+				truth, found = strings.EqualFold(strings.TrimSpace(a.Text), "true"), true
+			}
+			if a.Feedback != nil && strings.TrimSpace(a.Feedback.Text) != "" {
+				row.warn("feedback dropped")
+			}
+		}
+		if !found {
+			row.Problems = append(row.Problems, "true/false question has no correct answer")
+		}
+		setLMSBoolean(row, truth)
+	case "description":
+		row.Skip = "descriptions are not questions"
+		return
+	default:
+		row.Skip = q.Type + " questions are not supported"
+		return
+	}
+	if q.GeneralFeedback != nil {
+		row.Input.Explanation = lmsText(row, q.GeneralFeedback.Text, q.GeneralFeedback.Format)
+	}
+	setLMSCategory(row, category)
+	if q.Tags != nil {
+		tags := make([]string, len(q.Tags.Tags))
+		for i, tag := range q.Tags.Tags {
+			tags[i] = tag.Text
+		}
+		setLMSTags(row, tags)
+	}
+	finishLMSRow(row)
+}
+
+// writeMoodleXMLQuestions writes the moodlexml format: a category marker before each run of
+// questions of the same category, difficulty and tags as Moodle tags and plain-text texts. The
+// explanation is the general feedback; Moodle XML has no place for references.
+func writeMoodleXMLQuestions(w io.Writer, questions []models.Question) error {
+	quiz := struct {
+		XMLName   xml.Name         `xml:"quiz"`
+		Questions []moodleQuestion `xml:"question"`
+	}{}
+	category := ""
+	for _, q := range questions {
+		if q.Category != category {
+			category = q.Category
+			quiz.Questions = append(quiz.Questions, moodleQuestion{Type: "category",
+				Category: &moodleText{Text: lmsCategoryPrefix + category}})
+		}
+		out := moodleQuestion{
+			Type:         "multichoice",
+			Name:         &moodleText{Text: fmt.Sprintf("brainbolt-%d", q.ID)},
+			QuestionText: &moodleText{Format: "plain_text", Text: q.Question},
+			Single:       "true",
+		}
+		if q.Explanation != "" {
+			out.GeneralFeedback = &moodleText{Format: "plain_text", Text: q.Explanation}
+		}
+		switch {
+		case lmsBoolean(&q):
+			out.Type, out.Single = "truefalse", ""
+			for i, option := range q.Options {
+				a := moodleAnswer{Text: strings.ToLower(option)}
+				if optionLetter(i) == q.Answer {
+					a.Fraction = 100
+				}
+				out.Answers = append(out.Answers, a)
+			}
+		case q.Type == QuestionTypeNumeric:
+			out.Type, out.Single = "numerical", ""
+			out.Answers = []moodleAnswer{{Fraction: 100, Text: q.Answer,
+				Tolerance: strconv.FormatFloat(q.Tolerance, 'g', -1, 64)}}
+		case q.Type == QuestionTypeText:
+			out.Type, out.Single, out.UseCase = "shortanswer", "", "0"
+			for _, answer := range append([]string{q.Answer}, q.Aliases...) {
+				out.Answers = append(out.Answers, moodleAnswer{Fraction: 100, Format: "plain_text", Text: answer})
+			}
+		case q.Type == QuestionTypeMulti:
+			out.Single = "false"
+			correct := lmsCorrectLetters(&q)
+			for i, option := range q.Options {
+				out.Answers = append(out.Answers, moodleAnswer{Fraction: lmsWeight(correct[optionLetter(i)], len(correct)),
+					Format: "plain_text", Text: option})
+			}
+		default:
+			for i, option := range q.Options {
+				a := moodleAnswer{Format: "plain_text", Text: option}
+				if optionLetter(i) == q.Answer {
+					a.Fraction = 100
+				}
+				out.Answers = append(out.Answers, a)
+			}
+		}
+		out.Tags = &moodleTags{}
+		for _, tag := range lmsTags(&q) {
+			out.Tags.Tags = append(out.Tags.Tags, moodleText{Text: tag})
+		}
+		quiz.Questions = append(quiz.Questions, out)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(quiz); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,284 @@
+package service
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"brainbolt/internal/models"
+)
+
+func TestGIFTEscape(t *testing.T) {
+	tests := []struct {
+		text, escaped string
+	}{
+		{"plain text", "plain text"},
+		{"a=b: {x} ~ #1", `a\=b\: \{x\} \~ \#1`},
+		{`back\slash`, `back\\slash`},
+		{"two\nlines", `two\nlines`},
+	}
+	for _, tt := range tests {
+		if got := giftEscape(tt.text); got != tt.escaped {
+			t.Errorf("giftEscape(%q) = %q, want %q", tt.text, got, tt.escaped)
+		}
+		if got := giftUnescape(tt.escaped); got != tt.text {
+			t.Errorf("giftUnescape(%q) = %q, want %q", tt.escaped, got, tt.text)
+		}
+	}
+}
+
+func TestParseGIFTQuestions(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []importRow
+	}{
+		{
+			name: "escapes",
+			data: "// [tag:difficulty:3]\n::t\\:1::Is 1\\=1 \\: yes\\~no? {=yes\\~really ~no\\=way}\n",
+			want: []importRow{{Line: 2, Input: QuestionInput{Difficulty: 3, Question: "Is 1=1 : yes~no?",
+				Options: []string{"yes~really", "no=way"}, Answer: "A"}}},
+		},
+		{
+			name: "category, tags and feedback",
+			data: "$CATEGORY: $course$/top/Default for Quiz/Science/Space\n\n" +
+				"// [tag:difficulty:4] [tag:Solar System]\nWhich planet is red? {\n" +
+				"\t~Venus#Too hot\n\t=Mars\n\t####Iron oxide.\n}\n",
+			want: []importRow{{Line: 4, Input: QuestionInput{Difficulty: 4, Question: "Which planet is red?",
+				Options: []string{"Venus", "Mars"}, Answer: "B", Category: "science",
+				Tags: []string{"space", "solar-system"}, Explanation: "Iron oxide."},
+				Warnings: []string{"feedback of option A dropped"}}},
+		},
+		{
+			name: "weights make a multi-select question",
+			data: "// [tag:difficulty:5]\nPrimes? {~%50%2 ~%50%3 ~%-100%4}\n",
+			want: []importRow{{Line: 2, Input: QuestionInput{Type: QuestionTypeMulti, Difficulty: 5, Question: "Primes?",
+				Options: []string{"2", "3", "4"}, Answer: "A,B", PartialCredit: true},
+				Warnings: []string{"option weights replaced by partial credit (right minus wrong picks)"}}},
+		},
+		{
+			name: "partial weights of a single-choice question",
+			data: "// [tag:difficulty:5]\nCapital of Australia? {=Canberra ~%25%Sydney ~%-10%Perth}\n",
+			want: []importRow{{Line: 2, Input: QuestionInput{Difficulty: 5, Question: "Capital of Australia?",
+				Options: []string{"Canberra", "Sydney", "Perth"}, Answer: "A"},
+				Warnings: []string{"partial credit (25%) of option B dropped, it counts as wrong",
+					"penalty (-10%) of option C dropped"}}},
+		},
+		{
+			name: "invalid weight",
+			data: "// [tag:difficulty:5]\nPrimes? {~%half%2 ~%50%3 ~4}\n",
+			want: []importRow{{Line: 2, Input: QuestionInput{Type: QuestionTypeMulti, Difficulty: 5, Question: "Primes?",
+				Options: []string{"2", "3", "4"}, Answer: "B", PartialCredit: true},
+				Problems: []string{"option A has an invalid weight"},
+				Warnings: []string{"option weights replaced by partial credit (right minus wrong picks)"}}},
+		},
+		{
+			name: "numeric value and tolerance",
+			data: "// [tag:difficulty:2]\nPi? {#3.14:0.01}\n",
+			want: []importRow{{Line: 2, Input: QuestionInput{Type: QuestionTypeNumeric, Difficulty: 2, Question: "Pi?",
+				Answer: "3.14", Tolerance: 0.01}}},
+		},
+		{
+			name: "numeric range",
+			data: "// [tag:difficulty:2]\nA number from 1 to 5? {#1..5#Any will do}\n",
+			want: []importRow{{Line: 2, Input: QuestionInput{Type: QuestionTypeNumeric, Difficulty: 2,
+				Question: "A number from 1 to 5?", Answer: "3", Tolerance: 2},
+				Warnings: []string{"feedback dropped"}}},
+		},
+		{
+			name: "reversed numeric range",
+			data: "// [tag:difficulty:2]\nBackwards? {#5..1}\n",
+			want: []importRow{{Line: 2, Input: QuestionInput{Type: QuestionTypeNumeric, Difficulty: 2, Question: "Backwards?"},
+				Problems: []string{"invalid numeric range 5..1"}}},
+		},
+		{
+			name: "short answer and missing word",
+			data: "The largest planet is {=Jupiter =Planet Jupiter =%50%Saturn} by far.\n",
+			want: []importRow{{Line: 1, Input: QuestionInput{Type: QuestionTypeText, Difficulty: lmsDefaultDifficulty,
+				Question: "The largest planet is _____ by far.", Answer: "Jupiter", Aliases: []string{"Planet Jupiter"}},
+				Warnings: []string{`answer "Saturn" worth 50% dropped`, "no difficulty:N tag, imported at difficulty 5"}}},
+		},
+		{
+			name: "true/false",
+			data: "// [tag:difficulty:1]\n[html]The <b>Sun</b> is a star. {T}\n\n// [tag:difficulty:1]\nIce is hot. {FALSE}\n",
+			want: []importRow{
+				{Line: 2, Input: QuestionInput{Type: QuestionTypeTrueFalse, Difficulty: 1, Question: "The Sun is a star.",
+					Options: []string{"True", "False"}, Answer: "A"}},
+				{Line: 5, Input: QuestionInput{Type: QuestionTypeTrueFalse, Difficulty: 1, Question: "Ice is hot.",
+					Options: []string{"True", "False"}, Answer: "B"}},
+			},
+		},
+		{
+			name: "unsupported questions",
+			data: "Describe a sunset. {}\n\nMatch them. {=cat -> meow =dog -> woof}\n\nJust some text.\n",
+			want: []importRow{
+				{Line: 1, Input: QuestionInput{Question: "Describe a sunset."}, Skip: "essay questions are not supported"},
+				{Line: 3, Input: QuestionInput{Question: "Match them."}, Skip: "matching questions are not supported"},
+				{Line: 5, Skip: "descriptions (text without an answer block) are not questions"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, issues, err := parseQuestions(QuestionFormatGIFT, []byte(tt.data))
+			if err != nil || len(issues) > 0 {
+				t.Fatalf("parseQuestions: %v %v", err, issues)
+			}
+			if !reflect.DeepEqual(rows, tt.want) {
+				t.Errorf("rows:\n got %+v\nwant %+v", rows, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMoodleXMLQuestions(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		want   []importRow
+		issues []ImportIssue
+	}{
+		{
+			name: "cdata and html",
+			data: `<?xml version="1.0" encoding="UTF-8"?>
+<quiz>
+  <question type="category"><category><text>$course$/top/Math</text></category></question>
+  <question type="multichoice">
+    <questiontext format="html"><text><![CDATA[<p>What is <b>2 + 2</b>?</p><img src="sum.png">]]></text></questiontext>
+    <generalfeedback format="html"><text><![CDATA[Count &amp; see.]]></text></generalfeedback>
+    <answer fraction="0" format="html"><text><![CDATA[<i>3</i>]]></text></answer>
+    <answer fraction="100" format="html"><text><![CDATA[4]]></text><feedback><text>Yes</text></feedback></answer>
+    <tags><tag><text>difficulty:1</text></tag><tag><text>Arithmetic</text></tag></tags>
+  </question>
+</quiz>`,
+			want: []importRow{{Line: 4, Input: QuestionInput{Difficulty: 1, Question: "What is 2 + 2?",
+				Options: []string{"3", "4"}, Answer: "B", Category: "math", Tags: []string{"arithmetic"},
+				Explanation: "Count & see."},
+				Warnings: []string{"HTML <img> removed", "feedback of option B dropped"}}},
+		},
+		{
+			name: "numerical, shortanswer and truefalse",
+			data: `<quiz>
+<question type="numerical">
+  <questiontext format="plain_text"><text>Pi?</text></questiontext>
+  <answer fraction="100"><text>3.14</text><tolerance>-0.01</tolerance></answer>
+  <answer fraction="50"><text>3</text><tolerance>0</tolerance></answer>
+  <tags><tag><text>difficulty:3</text></tag></tags>
+</question>
+<question type="shortanswer">
+  <questiontext format="plain_text"><text>Largest planet?</text></questiontext>
+  <usecase>1</usecase>
+  <answer fraction="100" format="plain_text"><text>Jupiter</text></answer>
+  <answer fraction="100" format="plain_text"><text>Planet Jupiter</text></answer>
+  <tags><tag><text>difficulty:2</text></tag></tags>
+</question>
+<question type="truefalse">
+  <questiontext format="plain_text"><text>Ice is hot.</text></questiontext>
+  <answer fraction="0"><text>true</text></answer>
+  <answer fraction="100"><text>false</text></answer>
+  <tags><tag><text>difficulty:1</text></tag></tags>
+</question>
+</quiz>`,
+			want: []importRow{
+				{Line: 2, Input: QuestionInput{Type: QuestionTypeNumeric, Difficulty: 3, Question: "Pi?",
+					Answer: "3.14", Tolerance: 0.01},
+					Warnings: []string{"answer 3 worth 50% dropped"}},
+				{Line: 8, Input: QuestionInput{Type: QuestionTypeText, Difficulty: 2, Question: "Largest planet?",
+					Answer: "Jupiter", Aliases: []string{"Planet Jupiter"}},
+					Warnings: []string{"case-sensitive matching dropped, answers are compared ignoring case"}},
+				{Line: 15, Input: QuestionInput{Type: QuestionTypeTrueFalse, Difficulty: 1, Question: "Ice is hot.",
+					Options: []string{"True", "False"}, Answer: "B"}},
+			},
+		},
+		{
+			name: "unsupported questions",
+			data: `<quiz>
+<question type="essay"><questiontext><text>Why?</text></questiontext></question>
+<question type="description"><questiontext><text>Read this.</text></questiontext></question>
+</quiz>`,
+			want: []importRow{
+				{Line: 2, Input: QuestionInput{Question: "Why?"}, Skip: "essay questions are not supported"},
+				{Line: 3, Input: QuestionInput{Question: "Read this."}, Skip: "descriptions are not questions"},
+			},
+		},
+		{
+			name:   "not a quiz",
+			data:   `<questions/>`,
+			issues: []ImportIssue{{Line: 1, Message: "expected a Moodle XML <quiz> element"}},
+		},
+		{
+			name: "malformed",
+			data: "<quiz>\n<question type=\"truefalse\">\n</quiz>",
+			issues: []ImportIssue{{Line: 3,
+				Message: "malformed XML: XML syntax error on line 3: element <question> closed by </quiz>"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, issues, err := parseQuestions(QuestionFormatMoodleXML, []byte(tt.data))
+			if err != nil {
+				t.Fatalf("parseQuestions: %v", err)
+			}
+			if !reflect.DeepEqual(rows, tt.want) {
+				t.Errorf("rows:\n got %+v\nwant %+v", rows, tt.want)
+			}
+			if !reflect.DeepEqual(issues, tt.issues) {
+				t.Errorf("issues = %v, want %v", issues, tt.issues)
+			}
+		})
+	}
+}
+
+func TestLMSFormatRoundTrip(t *testing.T) {
+	for _, format := range []string{QuestionFormatGIFT, QuestionFormatMoodleXML} {
+		t.Run(format, func(t *testing.T) {
+			questions := sampleQuestions(t)
+			var buf bytes.Buffer
+			if _, err := writeQuestions(format, &buf, questions); err != nil {
+				t.Fatalf("writeQuestions: %v", err)
+			}
+			// Neither format has references, and texts come back with whitespace collapsed.
+			want := make([]models.Question, len(questions))
+			for i, q := range questions {
+				q.Explanation = strings.Join(strings.Fields(q.Explanation), " ")
+				q.References = nil
+				want[i] = q
+			}
+			got := reimport(t, format, buf.Bytes())
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip:\n got %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+// TestWriteQuestionsLeftOut checks the counts "brainbolt questions export" reports: what
+// writeQuestions wrote, and the rest as left out because the format cannot hold their type.
+func TestWriteQuestionsLeftOut(t *testing.T) {
+	tests := []struct {
+		format           string
+		written, leftOut int
+	}{
+		{QuestionFormatJSON, 5, 0},
+		{QuestionFormatCSV, 5, 0},
+		{QuestionFormatOpenTDB, 2, 3},
+		{QuestionFormatGIFT, 5, 0},
+		{QuestionFormatMoodleXML, 5, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			questions := sampleQuestions(t)
+			written, err := writeQuestions(tt.format, &bytes.Buffer{}, questions)
+			if err != nil {
+				t.Fatalf("writeQuestions: %v", err)
+			}
+			if written != tt.written || len(questions)-written != tt.leftOut {
+				t.Errorf("written %d, left out %d; want %d and %d", written, len(questions)-written, tt.written, tt.leftOut)
+			}
+		})
+	}
+	if _, err := writeQuestions("yaml", &bytes.Buffer{}, nil); err != ErrUnknownQuestionFormat {
+		t.Errorf("unknown format: err = %v, want ErrUnknownQuestionFormat", err)
+	}
+}
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"brainbolt/internal/models"
+)
+
+// sampleInputs covers every question type, with the commas, quotes and line breaks that
+// trip up file formats.
+var sampleInputs = []QuestionInput{
+	{Difficulty: 2, Question: `Which city is called "the City of Light"?`, Options: []string{"Rome", "Paris, France", "Vienna"},
+		Answer: "B", Category: "geography", Tags: []string{"europe", "capitals"},
+		Explanation: "Paris was among the first cities\nto light its streets.", References: []string{"https://example.com/paris"}},
+	{Type: QuestionTypeTrueFalse, Difficulty: 1, Question: "The Sun is a star.", Answer: "True", Category: "science"},
+	{Type: QuestionTypeMulti, Difficulty: 5, Question: "Which of these are prime?", Options: []string{"2", "4", "5", "9"},
+		Answer: "A,C", PartialCredit: true, Category: "math"},
+	{Type: QuestionTypeNumeric, Difficulty: 4, Question: "What is pi to two decimals?", Answer: "3.14", Tolerance: 0.01,
+		Category: "math"},
+	{Type: QuestionTypeText, Difficulty: 3, Question: "What is the largest planet?", Answer: "Jupiter",
+		Aliases: []string{"Planet Jupiter"}, Category: "science", Tags: []string{"space"}},
+}
+
+// sampleQuestions validates sampleInputs the way an import would.
+func sampleQuestions(t *testing.T) []models.Question {
+	t.Helper()
+	questions := make([]models.Question, 0, len(sampleInputs))
+	for _, in := range sampleInputs {
+		q, err := buildQuestion(in)
+		if err != nil {
+			t.Fatalf("buildQuestion(%q): %v", in.Question, err)
+		}
+		questions = append(questions, *q)
+	}
+	return questions
+}
+
+// reimport reads a file back and validates every question in it.
+func reimport(t *testing.T, format string, data []byte) []models.Question {
+	t.Helper()
+	rows, issues, err := parseQuestions(format, data)
+	if err != nil {
+		t.Fatalf("parseQuestions: %v", err)
+	}
+	if len(issues) > 0 {
+		t.Fatalf("parseQuestions issues: %v", issues)
+	}
+	var questions []models.Question
+	for _, row := range rows {
+		if len(row.Problems) > 0 || row.Skip != "" {
+			t.Fatalf("line %d: problems %v, skip %q", row.Line, row.Problems, row.Skip)
+		}
+		q, err := buildQuestion(row.Input)
+		if err != nil {
+			t.Fatalf("line %d: %v", row.Line, err)
+		}
+		questions = append(questions, *q)
+	}
+	return questions
+}
+
+func TestQuestionFormatRoundTrip(t *testing.T) {
+	tests := []struct {
+		format string
+		// keep reports which sample questions the format can hold.
+		keep func(q models.Question) bool
+	}{
+		{QuestionFormatJSON, func(models.Question) bool { return true }},
+		{QuestionFormatCSV, func(models.Question) bool { return true }},
+		{QuestionFormatOpenTDB, func(q models.Question) bool {
+			return q.Type == QuestionTypeSingle || q.Type == QuestionTypeTrueFalse
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			questions := sampleQuestions(t)
+			var buf bytes.Buffer
+			written, err := writeQuestions(tt.format, &buf, questions)
+			if err != nil {
+				t.Fatalf("writeQuestions: %v", err)
+			}
+
+			var want []models.Question
+			for _, q := range questions {
+				if !tt.keep(q) {
+					continue
+				}
+				if tt.format == QuestionFormatOpenTDB {
+					// Open Trivia DB has three difficulties and no explanations, references or
+					// second tags, and multiple-choice options come back sorted.
+					q.Difficulty = openTDBDifficulties[openTDBDifficulty(q.Difficulty)]
+					q.Explanation, q.References = "", nil
+					q.Tags = q.Tags[:min(len(q.Tags), 1)]
+					if q.Type == QuestionTypeSingle {
+						q.Options = []string{"Paris, France", "Rome", "Vienna"}
+						q.Answer = "A"
+					}
+				}
+				want = append(want, q)
+			}
+			if written != len(want) {
+				t.Errorf("written = %d, want %d", written, len(want))
+			}
+			got := reimport(t, tt.format, buf.Bytes())
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip:\n got %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseCSVQuestionsIssues(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		// rows is how many rows are read before the issue.
+		rows int
+		want []ImportIssue
+	}{
+		{"empty file", "", 0, []ImportIssue{{Line: 1, Message: "missing CSV header row"}}},
+		{"no question column", "difficulty,answer\n1,A\n", 0,
+			[]ImportIssue{{Line: 1, Message: "CSV header has no question column"}}},
+		{"no answer column", "question,difficulty,option_a\nWhy?,1,Because\n", 0,
+			[]ImportIssue{{Line: 1, Message: "CSV header has no answer column"}}},
+		{"bare quote", "question,difficulty,answer\nOne?,1,A\nSay \"hi\",1,A\n", 1,
+			[]ImportIssue{{Line: 3, Message: `malformed CSV: parse error on line 3, column 5: bare " in non-quoted-field`}}},
+		{"unterminated quote", "question,difficulty,answer\nOne?,1,A\n\"Two?,1,A\n", 1,
+			[]ImportIssue{{Line: 3, Message: `malformed CSV: parse error on line 3, column 11: extraneous or missing " in quoted-field`}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, issues, err := parseQuestions(QuestionFormatCSV, []byte(tt.data))
+			if err != nil {
+				t.Fatalf("parseQuestions: %v", err)
+			}
+			if len(rows) != tt.rows {
+				t.Errorf("read %d rows, want %d", len(rows), tt.rows)
+			}
+			if !reflect.DeepEqual(issues, tt.want) {
+				t.Errorf("issues = %v, want %v", issues, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCSVQuestionsRows(t *testing.T) {
+	data := "\ufeffQuestion, Difficulty ,Answer,Option_A,Option_B,Tags\n" +
+		"\"Is \"\"this\"\" quoted,\nacross lines?\",2,B,No,Yes,a; b;;\n" +
+		"Level?,hard,A,One,Two,\n"
+	rows, issues, err := parseQuestions(QuestionFormatCSV, []byte(data))
+	if err != nil || len(issues) > 0 {
+		t.Fatalf("parseQuestions: %v %v", err, issues)
+	}
+	want := []importRow{
+		{Line: 2, Input: QuestionInput{Difficulty: 2, Question: "Is \"this\" quoted,\nacross lines?", Answer: "B",
+			Options: []string{"No", "Yes"}, Tags: []string{"a", "b"}}},
+		{Line: 4, Input: QuestionInput{Question: "Level?", Answer: "A", Options: []string{"One", "Two"}},
+			Problems: []string{"difficulty must be a number"}},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows:\n got %+v\nwant %+v", rows, want)
+	}
+}
+
+func TestParseOpenTDBQuestions(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		want   []importRow
+		issues []ImportIssue
+	}{
+		{
+			name: "entities",
+			data: `{"response_code":0,"results":[
+{"type":"multiple","difficulty":"medium","category":"Science: Computers",
+ "question":"What does &quot;HTML&quot; stand for &amp; who&#039;s behind it?",
+ "correct_answer":"Hypertext Markup Language","incorrect_answers":["Hyper &lt;Text&gt;","Home Tool Markup Language"]},
+{"type":"boolean","difficulty":"hard","category":"Entertainment: Japanese Anime &amp; Manga",
+ "question":"&Eacute;clair is French.","correct_answer":"True","incorrect_answers":["False"]}
+]}`,
+			want: []importRow{
+				{Line: 2, Input: QuestionInput{Difficulty: 5, Question: `What does "HTML" stand for & who's behind it?`,
+					Options:  []string{"Home Tool Markup Language", "Hyper <Text>", "Hypertext Markup Language"},
+					Answer:   "Hypertext Markup Language",
+					Category: "science", Tags: []string{"computers"}}},
+				{Line: 5, Input: QuestionInput{Type: QuestionTypeTrueFalse, Difficulty: 8, Question: "Éclair is French.",
+					Options: []string{"True", "False"}, Answer: "True",
+					Category: "entertainment", Tags: []string{"japanese-anime-manga"}}},
+			},
+		},
+		{
+			name: "unknown type and difficulty",
+			data: `{"response_code":0,"results":[{"type":"essay","difficulty":"brutal","category":"General Knowledge",
+"question":"Why?","correct_answer":"Because","incorrect_answers":[]}]}`,
+			want: []importRow{
+				{Line: 1, Input: QuestionInput{Question: "Why?", Answer: "Because", Options: []string{"Because"},
+					Category: "general-knowledge"},
+					Problems: []string{"difficulty must be easy, medium or hard", "type must be multiple or boolean"}},
+			},
+		},
+		{
+			name:   "response code",
+			data:   `{"response_code":1,"results":[]}`,
+			issues: []ImportIssue{{Line: 1, Message: "Open Trivia DB response_code is 1, not 0"}},
+		},
+		{
+			name:   "no results",
+			data:   `{"response_code":0}`,
+			issues: []ImportIssue{{Line: 1, Message: "Open Trivia DB response has no results"}},
+		},
+		{
+			name:   "not an object",
+			data:   `[]`,
+			issues: []ImportIssue{{Line: 1, Message: "expected an Open Trivia DB response object"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, issues, err := parseQuestions(QuestionFormatOpenTDB, []byte(tt.data))
+			if err != nil {
+				t.Fatalf("parseQuestions: %v", err)
+			}
+			if !reflect.DeepEqual(rows, tt.want) {
+				t.Errorf("rows:\n got %+v\nwant %+v", rows, tt.want)
+			}
+			if !reflect.DeepEqual(issues, tt.issues) {
+				t.Errorf("issues = %v, want %v", issues, tt.issues)
+			}
+		})
+	}
+}
+
+func TestReviewImport(t *testing.T) {
+	valid := func(line int, question string) importRow {
+		return importRow{Line: line, Input: QuestionInput{Difficulty: 1, Question: question,
+			Options: []string{"Yes", "No"}, Answer: "A"}}
+	}
+	tests := []struct {
+		name string
+		rows []importRow
+		// new lists the questions kept, in file order.
+		new        []string
+		duplicates []ImportIssue
+		skipped    []ImportIssue
+		warnings   []ImportIssue
+		errors     []ImportIssue
+	}{
+		{
+			name: "duplicates of the bank and of earlier lines",
+			rows: []importRow{
+				valid(2, "Is water wet?"),
+				valid(3, "  IS   the sky blue? "),
+				valid(4, "is water   WET?"),
+				valid(5, "Is grass green?"),
+			},
+			new: []string{"Is water wet?", "Is grass green?"},
+			duplicates: []ImportIssue{
+				{Line: 3, Message: "duplicate of question 7"},
+				{Line: 4, Message: "duplicate of line 2"},
+			},
+		},
+		{
+			name: "every error is reported",
+			rows: []importRow{
+				{Line: 9, Input: QuestionInput{Difficulty: 11, Question: "Too hard?", Options: []string{"Yes", "No"}, Answer: "C"}},
+				valid(2, "Is water wet?"),
+				{Line: 4, Problems: []string{"difficulty must be a number"}},
+				valid(5, "Is the sky blue?"),
+			},
+			new: []string{"Is water wet?"},
+			duplicates: []ImportIssue{
+				{Line: 5, Message: "duplicate of question 7"},
+			},
+			errors: []ImportIssue{
+				{Line: 4, Message: "difficulty must be a number"},
+				{Line: 9, Message: "difficulty must be between 1 and 10"},
+				{Line: 9, Message: "answer must be an option letter or the text of an option"},
+			},
+		},
+		{
+			name: "skips and warnings",
+			rows: []importRow{
+				{Line: 2, Skip: "essay questions are not supported"},
+				func() importRow {
+					row := valid(3, "Is water wet?")
+					row.warn("feedback on option %s dropped", "B")
+					return row
+				}(),
+			},
+			new:      []string{"Is water wet?"},
+			skipped:  []ImportIssue{{Line: 2, Message: "essay questions are not supported"}},
+			warnings: []ImportIssue{{Line: 3, Message: "feedback on option B dropped"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := &ImportReport{}
+			known := map[string]string{questionKey("Is the sky blue?"): "question 7"}
+			questions, err := reviewImport(report, tt.rows, known)
+			if err != nil {
+				t.Fatalf("reviewImport: %v", err)
+			}
+			var got []string
+			for _, q := range questions {
+				got = append(got, q.Question)
+			}
+			if !reflect.DeepEqual(got, tt.new) {
+				t.Errorf("new = %q, want %q", got, tt.new)
+			}
+			for _, list := range []struct {
+				name      string
+				got, want []ImportIssue
+			}{
+				{"duplicates", report.Duplicates, tt.duplicates},
+				{"skipped", report.Skipped, tt.skipped},
+				{"warnings", report.Warnings, tt.warnings},
+				{"errors", report.Errors, tt.errors},
+			} {
+				if !reflect.DeepEqual(list.got, list.want) {
+					t.Errorf("%s = %v, want %v", list.name, list.got, list.want)
+				}
+			}
+		})
+	}
+}
+
+func TestQuestionKey(t *testing.T) {
+	for _, text := range []string{"What is 2+2?", "  what IS\t2+2? ", "WHAT is\n2+2?"} {
+		if got := questionKey(text); got != "what is 2+2?" {
+			t.Errorf("questionKey(%q) = %q", text, got)
+		}
+	}
+}
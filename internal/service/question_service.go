@@ -5,30 +5,74 @@ import (
 	"brainbolt/internal/repository"
 	"database/sql"
 	"log"
+	"time"
 )
 
+// ServedQuestion is a question handed out by GetNextQuestionForUser together with the
+// signed, single-use token the client must send back with its answer. Review is set in practice
+// mode when the question is one due for review rather than a new one.
+type ServedQuestion struct {
+	Question          *models.Question
+	CurrentDifficulty int
+	Mode              string
+	Review            bool
+	Token             string
+	ExpiresAt         time.Time
+}
+
 // QuestionService handles question-related business logic (next question, recording asked).
 type QuestionService struct {
 	questionRepo *repository.QuestionRepository
 	userRepo     *repository.UserRepository
 	userService  *UserService
+	issueRepo    *repository.QuestionIssueRepository
+	tokenSigner  *QuestionTokenSigner
+	difficulty   DifficultyStrategy
+	scoring      *ModeScoring
 }
 
 // NewQuestionService creates a new question service.
-func NewQuestionService(questionRepo *repository.QuestionRepository, userRepo *repository.UserRepository, userService *UserService) *QuestionService {
+func NewQuestionService(
+	questionRepo *repository.QuestionRepository,
+	userRepo *repository.UserRepository,
+	userService *UserService,
+	issueRepo *repository.QuestionIssueRepository,
+	tokenSigner *QuestionTokenSigner,
+	difficulty DifficultyStrategy,
+	scoring *ModeScoring,
+) *QuestionService {
 	return &QuestionService{
 		questionRepo: questionRepo,
 		userRepo:     userRepo,
 		userService:  userService,
+		issueRepo:    issueRepo,
+		tokenSigner:  tokenSigner,
+		difficulty:   difficulty,
+		scoring:      scoring,
 	}
 }
 
-// GetNextQuestionForUser returns the next question for a user.
-// Uses a single join query to return the question directly (no second lookup).
-func (s *QuestionService) GetNextQuestionForUser(userID int) (*models.Question, int, error) {
+// GetNextQuestionForUser returns the next question for a user in a quiz mode ("" = DefaultMode).
+// The serve is recorded as a question issue and comes with a signed, single-use token.
+func (s *QuestionService) GetNextQuestionForUser(userID int, mode string, filter QuestionFilter) (*ServedQuestion, error) {
+	if mode == "" {
+		mode = DefaultMode
+	}
+	if !s.scoring.Supports(mode) {
+		return nil, ErrUnknownMode
+	}
+
 	user, err := s.userService.GetUserByID(userID)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
+	}
+
+	if filter.Category != "" {
+		level, err := s.userRepo.GetCategoryLevel(userID, filter.Category)
+		if err != nil {
+			return nil, err
+		}
+		user = withCategoryLevel(user, level)
 	}
 
 	currentDifficulty := user.CurrentDifficulty
@@ -36,20 +80,72 @@ func (s *QuestionService) GetNextQuestionForUser(userID int) (*models.Question,
 		currentDifficulty = 1
 	}
 
-	question, err := s.questionRepo.GetRandomQuestionForUser(userID, currentDifficulty)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, 0, ErrQuestionNotFound
+	var question *models.Question
+	if mode == PracticeMode {
+		question, err = s.questionRepo.GetDueQuestionForUser(userID, time.Now(),
+			repository.QuestionSelection{Category: filter.Category, Tags: filter.Tags})
+		if err != nil {
+			return nil, err
 		}
-		return nil, 0, err
 	}
+	review := question != nil
 	if question == nil {
-		return nil, 0, ErrQuestionNotFound
+		sel := s.difficulty.Selection(user)
+		sel.Category, sel.Tags = filter.Category, filter.Tags
+		question, err = s.questionRepo.GetRandomQuestionForUser(userID, sel)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, ErrQuestionNotFound
+			}
+			return nil, err
+		}
+		if question == nil {
+			return nil, ErrQuestionNotFound
+		}
+	}
+
+	var shuffleSeed int64
+	if shuffles(question) {
+		shuffleSeed = newShuffleSeed()
+	}
+	now := time.Now()
+	expiresAt := now.Add(s.tokenSigner.TTL())
+	issueID, err := s.issueRepo.CreateIssue(userID, question.ID, mode, shuffleSeed, now, expiresAt)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := s.userRepo.RecordQuestionAsked(userID, question.ID); err != nil {
 		log.Printf("Failed to record question asked for userID %d, questionID %d: %v", userID, question.ID, err)
 	}
 
-	return question, currentDifficulty, nil
+	token := s.tokenSigner.Sign(QuestionTokenClaims{
+		IssueID:    issueID,
+		UserID:     userID,
+		QuestionID: question.ID,
+		ExpiresAt:  expiresAt,
+	})
+	return &ServedQuestion{
+		Question:          shuffledQuestion(question, shuffleSeed),
+		CurrentDifficulty: currentDifficulty,
+		Mode:              mode,
+		Review:            review,
+		Token:             token,
+		ExpiresAt:         expiresAt,
+	}, nil
+}
+
+// ListCategories returns every question category with its number of questions.
+func (s *QuestionService) ListCategories() ([]repository.CategoryCount, error) {
+	return s.questionRepo.ListCategories()
+}
+
+// Modes lists the quiz modes /v1/quiz/next accepts.
+func (s *QuestionService) Modes() []string {
+	return s.scoring.Modes()
+}
+
+// PurgeExpiredIssues deletes ledger rows whose tokens expired more than a day ago.
+func (s *QuestionService) PurgeExpiredIssues() (int64, error) {
+	return s.issueRepo.PurgeExpired(time.Now().Add(-24 * time.Hour))
 }
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// QuestionTokenClaims is the payload of a question token: which issue, user and question it
+// covers and when it stops being answerable.
+type QuestionTokenClaims struct {
+	IssueID    int64
+	UserID     int
+	QuestionID int
+	ExpiresAt  time.Time
+}
+
+// QuestionTokenSigner signs and verifies question tokens with HMAC-SHA256.
+// Token format: base64url("issueID:userID:questionID:expiresUnixMilli") + "." + base64url(mac).
+type QuestionTokenSigner struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewQuestionTokenSigner creates a signer; ttl is how long issued tokens stay valid.
+func NewQuestionTokenSigner(secret []byte, ttl time.Duration) *QuestionTokenSigner {
+	return &QuestionTokenSigner{secret: secret, ttl: ttl}
+}
+
+// TTL returns how long newly issued tokens stay valid.
+func (t *QuestionTokenSigner) TTL() time.Duration {
+	return t.ttl
+}
+
+// Sign encodes and signs the claims.
+func (t *QuestionTokenSigner) Sign(claims QuestionTokenClaims) string {
+	payload := fmt.Sprintf("%d:%d:%d:%d", claims.IssueID, claims.UserID, claims.QuestionID, claims.ExpiresAt.UnixMilli())
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(t.mac(encoded))
+}
+
+// Verify checks the signature and expiry and returns the claims.
+// Returns ErrInvalidQuestionToken for malformed or tampered tokens and ErrQuestionTokenExpired once expired.
+func (t *QuestionTokenSigner) Verify(token string, now time.Time) (*QuestionTokenClaims, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidQuestionToken
+	}
+	gotMAC, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(gotMAC, t.mac(encoded)) {
+		return nil, ErrInvalidQuestionToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidQuestionToken
+	}
+
+	var claims QuestionTokenClaims
+	var expiresMilli int64
+	if _, err := fmt.Sscanf(string(payload), "%d:%d:%d:%d", &claims.IssueID, &claims.UserID, &claims.QuestionID, &expiresMilli); err != nil {
+		return nil, ErrInvalidQuestionToken
+	}
+	claims.ExpiresAt = time.UnixMilli(expiresMilli)
+	if !now.Before(claims.ExpiresAt) {
+		return nil, ErrQuestionTokenExpired
+	}
+	return &claims, nil
+}
+
+func (t *QuestionTokenSigner) mac(encodedPayload string) []byte {
+	m := hmac.New(sha256.New, t.secret)
+	m.Write([]byte(encodedPayload))
+	return m.Sum(nil)
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"brainbolt/internal/models"
+	"brainbolt/internal/repository"
+	"time"
+)
+
+const (
+	// maxRankHistoryPoints caps one rank-history response (the most recent points win).
+	maxRankHistoryPoints = 1000
+	// defaultRankHistoryRange is the window returned when the client gives no from.
+	defaultRankHistoryRange = 30 * 24 * time.Hour
+)
+
+// RankHistory is a user's rank time series over [From, To).
+type RankHistory struct {
+	UserID int                   `json:"userId"`
+	From   time.Time             `json:"from"`
+	To     time.Time             `json:"to"`
+	Points []models.RankSnapshot `json:"points"`
+}
+
+// RankHistoryService takes periodic snapshots of every user's score and ranks and serves them
+// back as a per-user time series.
+type RankHistoryService struct {
+	rankHistoryRepo *repository.RankHistoryRepository
+	userService     *UserService
+	interval        time.Duration
+	retention       time.Duration
+}
+
+// NewRankHistoryService creates a rank history service. Snapshots are aligned to interval and
+// kept for retention (0 = forever).
+func NewRankHistoryService(
+	rankHistoryRepo *repository.RankHistoryRepository,
+	userService *UserService,
+	interval time.Duration,
+	retention time.Duration,
+) *RankHistoryService {
+	return &RankHistoryService{
+		rankHistoryRepo: rankHistoryRepo,
+		userService:     userService,
+		interval:        interval,
+		retention:       retention,
+	}
+}
+
+// Snapshot records the ranks of all users for the interval slot containing now, unless another
+// instance already did, then drops snapshots older than the retention. Returns the rows written
+// and purged.
+func (s *RankHistoryService) Snapshot(now time.Time) (int64, int64, error) {
+	takenAt := now.Truncate(time.Second)
+	if s.interval > 0 {
+		takenAt = now.Truncate(s.interval)
+	}
+	var taken int64
+	exists, err := s.rankHistoryRepo.HasSnapshot(takenAt)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !exists {
+		if taken, err = s.rankHistoryRepo.TakeSnapshot(takenAt); err != nil {
+			return 0, 0, err
+		}
+	}
+	var purged int64
+	if s.retention > 0 {
+		if purged, err = s.rankHistoryRepo.PurgeBefore(now.Add(-s.retention)); err != nil {
+			return taken, 0, err
+		}
+	}
+	return taken, purged, nil
+}
+
+// GetRankHistory returns the user's snapshots in [from, to) oldest first; to defaults to now and
+// from to 30 days before to. At most the latest 1000 points are returned.
+func (s *RankHistoryService) GetRankHistory(userID int, from, to *time.Time) (*RankHistory, error) {
+	if _, err := s.userService.GetUserByID(userID); err != nil {
+		return nil, err
+	}
+	history := &RankHistory{UserID: userID, To: time.Now()}
+	if to != nil {
+		history.To = *to
+	}
+	history.From = history.To.Add(-defaultRankHistoryRange)
+	if from != nil {
+		history.From = *from
+	}
+	if !history.From.Before(history.To) {
+		return nil, ErrInvalidTimeRange
+	}
+	points, err := s.rankHistoryRepo.ListSnapshots(userID, history.From, history.To, maxRankHistoryPoints)
+	if err != nil {
+		return nil, err
+	}
+	history.Points = points
+	return history, nil
+}
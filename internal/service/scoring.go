@@ -0,0 +1,252 @@
+package service
+
+import (
+	"brainbolt/internal/models"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultMode is the quiz mode used when /v1/quiz/next is called without ?mode=.
+const DefaultMode = "classic"
+
+// ScoringContext is everything a ScoringPolicy may look at. User already reflects this answer's
+// counters (TotalAnswered, TotalCorrect and Streak are updated before scoring). Credit is the
+// share of full marks the answer earned (see Grade); it is 1 when Correct.
+type ScoringContext struct {
+	User         *models.User
+	Question     *models.Question
+	Correct      bool
+	Credit       float64
+	TimeToAnswer time.Duration
+	Mode         string
+}
+
+// ScoreBreakdown explains how a score delta was computed; Total is what is added to the user's score.
+type ScoreBreakdown struct {
+	Policy             string  `json:"policy"`
+	Base               int64   `json:"base"`
+	StreakMultiplier   float64 `json:"streakMultiplier"`
+	AccuracyMultiplier float64 `json:"accuracyMultiplier"`
+	Credit             float64 `json:"credit,omitempty"` // share of the base earned by a partly right answer
+	TimeBonus          int64   `json:"timeBonus,omitempty"`
+	Penalty            int64   `json:"penalty,omitempty"`
+	Total              int64   `json:"total"`
+}
+
+// ScoringPolicy turns an answer into a score delta. Implementations must be safe for concurrent use.
+type ScoringPolicy interface {
+	// Name identifies the policy in config and in the breakdown.
+	Name() string
+	// Score returns the breakdown for one answer.
+	Score(ctx ScoringContext) ScoreBreakdown
+}
+
+// ScoringConfig holds the tunables for the built-in policies.
+type ScoringConfig struct {
+	TimeBonusWindow   time.Duration // answers faster than this earn a bonus
+	TimeBonusMaxRatio float64       // bonus for an instant answer, as a share of the base score
+	NegativeRatio     float64       // penalty for a wrong answer, as a share of the base score
+}
+
+// NewScoringPolicy builds a policy from a name such as "standard", "timed", "negative" or a
+// "+"-joined combination like "timed+negative". Every policy starts from the standard formula.
+func NewScoringPolicy(name string, cfg ScoringConfig) (ScoringPolicy, error) {
+	var policy ScoringPolicy = StandardScoring{}
+	if name == "" || name == "standard" {
+		return policy, nil
+	}
+	for _, part := range strings.Split(name, "+") {
+		switch strings.TrimSpace(part) {
+		case "standard":
+		case "timed":
+			if cfg.TimeBonusWindow <= 0 || cfg.TimeBonusMaxRatio < 0 {
+				return nil, fmt.Errorf("timed scoring needs a positive window and non-negative bonus ratio")
+			}
+			policy = TimeBonusScoring{Inner: policy, Window: cfg.TimeBonusWindow, MaxRatio: cfg.TimeBonusMaxRatio}
+		case "negative":
+			if cfg.NegativeRatio < 0 {
+				return nil, fmt.Errorf("negative scoring needs a non-negative penalty ratio")
+			}
+			policy = NegativeMarkingScoring{Inner: policy, Ratio: cfg.NegativeRatio}
+		default:
+			return nil, fmt.Errorf("unknown scoring policy %q (want standard, timed or negative)", part)
+		}
+	}
+	return policy, nil
+}
+
+// baseScore is the points a question is worth before multipliers.
+func baseScore(difficulty int) int64 {
+	return int64(difficulty * 10)
+}
+
+// StandardScoring is the original formula: difficulty*10, times a streak multiplier
+// (1 + 0.1 per streak, capped at 2.0), times an accuracy multiplier (0.5 + accuracy).
+// Wrong answers score nothing; partly right ones the earned share of the base, times the
+// accuracy multiplier (their streak is 0).
+type StandardScoring struct{}
+
+// Name implements ScoringPolicy.
+func (StandardScoring) Name() string { return "standard" }
+
+// Score implements ScoringPolicy.
+func (StandardScoring) Score(ctx ScoringContext) ScoreBreakdown {
+	b := ScoreBreakdown{Policy: "standard", Base: baseScore(ctx.Question.Difficulty), StreakMultiplier: 1, AccuracyMultiplier: 1}
+	if !ctx.Correct && ctx.Credit <= 0 {
+		return b
+	}
+	credit := 1.0
+	if !ctx.Correct {
+		credit = ctx.Credit
+		b.Credit = credit
+	}
+	user := ctx.User
+	if user.Streak > 0 {
+		b.StreakMultiplier = 1.0 + float64(user.Streak)*0.1
+		if b.StreakMultiplier > 2.0 {
+			b.StreakMultiplier = 2.0
+		}
+	}
+	accuracy := 0.0
+	if user.TotalAnswered > 0 {
+		accuracy = float64(user.TotalCorrect) / float64(user.TotalAnswered)
+	}
+	b.AccuracyMultiplier = 0.5 + (accuracy * 1.0)
+	b.Total = int64(float64(b.Base) * credit * b.StreakMultiplier * b.AccuracyMultiplier)
+	return b
+}
+
+// TimeBonusScoring adds a bonus for correct answers given within Window of the question being
+// served, falling linearly from MaxRatio*base for an instant answer to 0 at Window.
+type TimeBonusScoring struct {
+	Inner    ScoringPolicy
+	Window   time.Duration
+	MaxRatio float64
+}
+
+// Name implements ScoringPolicy.
+func (t TimeBonusScoring) Name() string { return t.Inner.Name() + "+timed" }
+
+// Score implements ScoringPolicy.
+func (t TimeBonusScoring) Score(ctx ScoringContext) ScoreBreakdown {
+	b := t.Inner.Score(ctx)
+	b.Policy = t.Name()
+	if !ctx.Correct || ctx.TimeToAnswer >= t.Window {
+		return b
+	}
+	remaining := 1 - float64(ctx.TimeToAnswer)/float64(t.Window)
+	b.TimeBonus = int64(float64(b.Base) * t.MaxRatio * remaining)
+	b.Total += b.TimeBonus
+	return b
+}
+
+// NegativeMarkingScoring subtracts Ratio*base for a wrong answer that earned no credit. The
+// penalty never takes a user's score below zero.
+type NegativeMarkingScoring struct {
+	Inner ScoringPolicy
+	Ratio float64
+}
+
+// Name implements ScoringPolicy.
+func (n NegativeMarkingScoring) Name() string { return n.Inner.Name() + "+negative" }
+
+// Score implements ScoringPolicy.
+func (n NegativeMarkingScoring) Score(ctx ScoringContext) ScoreBreakdown {
+	b := n.Inner.Score(ctx)
+	b.Policy = n.Name()
+	if ctx.Correct || ctx.Credit > 0 {
+		return b
+	}
+	b.Penalty = int64(float64(b.Base) * n.Ratio)
+	if b.Penalty > ctx.User.Score {
+		b.Penalty = ctx.User.Score
+	}
+	b.Total -= b.Penalty
+	return b
+}
+
+// PracticeScoring is the fixed policy of PracticeMode: answers score nothing, so a question
+// missed and retried once its answer has been shown earns no points.
+type PracticeScoring struct{}
+
+// Name implements ScoringPolicy.
+func (PracticeScoring) Name() string { return PracticeMode }
+
+// Score implements ScoringPolicy.
+func (PracticeScoring) Score(ctx ScoringContext) ScoreBreakdown {
+	return ScoreBreakdown{Policy: PracticeMode, Base: baseScore(ctx.Question.Difficulty), StreakMultiplier: 1, AccuracyMultiplier: 1}
+}
+
+// ModeScoring dispatches to a per-mode policy, falling back to Default for DefaultMode.
+// PracticeMode always uses PracticeScoring.
+type ModeScoring struct {
+	Default ScoringPolicy
+	ByMode  map[string]ScoringPolicy
+}
+
+// NewModeScoring builds the default policy plus per-mode overrides from a spec such as
+// "blitz=timed,exam=timed+negative".
+func NewModeScoring(defaultPolicy string, modeSpec string, cfg ScoringConfig) (*ModeScoring, error) {
+	def, err := NewScoringPolicy(defaultPolicy, cfg)
+	if err != nil {
+		return nil, err
+	}
+	ms := &ModeScoring{Default: def, ByMode: map[string]ScoringPolicy{}}
+	for _, entry := range strings.Split(modeSpec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		mode, policyName, ok := strings.Cut(entry, "=")
+		if !ok || mode == "" {
+			return nil, fmt.Errorf("invalid scoring mode entry %q (want mode=policy)", entry)
+		}
+		if mode == PracticeMode {
+			return nil, fmt.Errorf("mode %s is not scored and cannot have a scoring policy", mode)
+		}
+		policy, err := NewScoringPolicy(policyName, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("mode %s: %w", mode, err)
+		}
+		ms.ByMode[mode] = policy
+	}
+	return ms, nil
+}
+
+// Name implements ScoringPolicy.
+func (m *ModeScoring) Name() string { return m.Default.Name() }
+
+// Score implements ScoringPolicy.
+func (m *ModeScoring) Score(ctx ScoringContext) ScoreBreakdown {
+	if ctx.Mode == PracticeMode {
+		return PracticeScoring{}.Score(ctx)
+	}
+	if policy, ok := m.ByMode[ctx.Mode]; ok {
+		return policy.Score(ctx)
+	}
+	return m.Default.Score(ctx)
+}
+
+// Supports reports whether mode may be requested from /v1/quiz/next: DefaultMode, PracticeMode
+// and the configured modes.
+func (m *ModeScoring) Supports(mode string) bool {
+	if mode == DefaultMode || mode == PracticeMode {
+		return true
+	}
+	_, ok := m.ByMode[mode]
+	return ok
+}
+
+// Modes lists the supported modes, DefaultMode first.
+func (m *ModeScoring) Modes() []string {
+	modes := []string{PracticeMode}
+	for mode := range m.ByMode {
+		if mode != DefaultMode {
+			modes = append(modes, mode)
+		}
+	}
+	sort.Strings(modes)
+	return append([]string{DefaultMode}, modes...)
+}
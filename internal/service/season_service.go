@@ -0,0 +1,319 @@
+package service
+
+import (
+	"brainbolt/internal/models"
+	"brainbolt/internal/repository"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Season boards: the archive keeps the final standings of both.
+const (
+	SeasonBoardScore  = "score"
+	SeasonBoardStreak = "streak"
+)
+
+// SeasonReward is granted to every player finishing a season with points at or above MaxRank on
+// the score board.
+type SeasonReward struct {
+	MaxRank int64
+	Name    string
+}
+
+// ParseSeasonRewards parses "1:gold,3:silver,10:bronze" (rank:reward pairs, any order).
+func ParseSeasonRewards(s string) ([]SeasonReward, error) {
+	var rewards []SeasonReward
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		rank, name, ok := strings.Cut(part, ":")
+		n, err := strconv.ParseInt(strings.TrimSpace(rank), 10, 64)
+		name = strings.TrimSpace(name)
+		if !ok || err != nil || n <= 0 || name == "" || len(name) > 32 {
+			return nil, fmt.Errorf("invalid season reward %q (want rank:reward)", part)
+		}
+		rewards = append(rewards, SeasonReward{MaxRank: n, Name: name})
+	}
+	sort.Slice(rewards, func(i, j int) bool { return rewards[i].MaxRank < rewards[j].MaxRank })
+	return rewards, nil
+}
+
+// rewardFor returns the reward of a final score rank ("" if none).
+func rewardFor(rewards []SeasonReward, rank int64) string {
+	for _, r := range rewards {
+		if rank <= r.MaxRank {
+			return r.Name
+		}
+	}
+	return ""
+}
+
+// SeasonStandings is one page of a season board.
+type SeasonStandings struct {
+	Season *models.Season `json:"season"`
+	Board  string         `json:"board"`
+	// Final is set once the season is archived; before that the standings are live.
+	Final   bool                    `json:"final"`
+	Entries []models.SeasonStanding `json:"entries"`
+}
+
+// RolloverResult lists the seasons archived and the season started by a rollover.
+type RolloverResult struct {
+	Archived []models.Season `json:"archived"`
+	Created  *models.Season  `json:"created,omitempty"`
+}
+
+// SeasonService runs leaderboard seasons: live season boards come from the LeaderboardService,
+// finished seasons are archived to MySQL at rollover. Lifetime stats on users are never touched;
+// each season simply has its own boards.
+type SeasonService struct {
+	seasonRepo   *repository.SeasonRepository
+	historyRepo  *repository.AnswerHistoryRepository
+	userRepo     *repository.UserRepository
+	leaderboards *LeaderboardService
+	calendar     *PeriodCalendar
+	length       Period // PeriodWeekly or PeriodMonthly; "" for manual seasons
+	rewards      []SeasonReward
+}
+
+// NewSeasonService creates a season service. length is "monthly", "weekly" or "manual";
+// rewards is in ParseSeasonRewards format.
+func NewSeasonService(
+	seasonRepo *repository.SeasonRepository,
+	historyRepo *repository.AnswerHistoryRepository,
+	userRepo *repository.UserRepository,
+	leaderboards *LeaderboardService,
+	calendar *PeriodCalendar,
+	length string,
+	rewards string,
+) (*SeasonService, error) {
+	s := &SeasonService{
+		seasonRepo:   seasonRepo,
+		historyRepo:  historyRepo,
+		userRepo:     userRepo,
+		leaderboards: leaderboards,
+		calendar:     calendar,
+	}
+	switch p := Period(strings.ToLower(length)); p {
+	case PeriodWeekly, PeriodMonthly:
+		s.length = p
+	case "manual":
+	default:
+		return nil, fmt.Errorf("invalid season length %q (want monthly, weekly or manual)", length)
+	}
+	var err error
+	if s.rewards, err = ParseSeasonRewards(rewards); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ListSeasons returns all seasons, newest first.
+func (s *SeasonService) ListSeasons() ([]models.Season, error) {
+	return s.seasonRepo.ListSeasons()
+}
+
+// CreateSeason schedules a season [startsAt, endsAt); it must not overlap another one.
+func (s *SeasonService) CreateSeason(name string, startsAt, endsAt time.Time) (*models.Season, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 64 || !startsAt.Before(endsAt) {
+		return nil, ErrInvalidSeason
+	}
+	season, err := s.seasonRepo.CreateSeason(name, startsAt, endsAt)
+	if err == repository.ErrSeasonOverlap {
+		return nil, ErrSeasonOverlap
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.leaderboards.InvalidateSeason()
+	return season, nil
+}
+
+// GetStandings returns a page of a season board ("score" or "streak"): the archived final
+// standings once the season is over, live standings (Redis, MySQL fallback) before that.
+func (s *SeasonService) GetStandings(seasonID int, board string, offset, limit int) (*SeasonStandings, error) {
+	kind := scoreBoard
+	switch board {
+	case SeasonBoardScore:
+	case SeasonBoardStreak:
+		kind = streakBoard
+	default:
+		return nil, ErrUnknownSeasonBoard
+	}
+	season, err := s.seasonRepo.GetSeason(seasonID)
+	if err != nil {
+		return nil, err
+	}
+	if season == nil {
+		return nil, ErrSeasonNotFound
+	}
+	result := &SeasonStandings{Season: season, Board: board, Final: season.ArchivedAt != nil}
+	if result.Final {
+		result.Entries, err = s.seasonRepo.ListStandings(seasonID, board, offset, limit)
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	var entries []repository.LeaderboardEntry
+	w := seasonWindow(season)
+	now := time.Now()
+	if !now.Before(season.StartsAt) && now.Before(season.EndsAt) {
+		entries, err = s.leaderboards.entriesIn(kind, w, LeaderboardQuery{Period: PeriodSeason, Offset: offset, Limit: limit})
+	} else {
+		// Not started yet, or ended and waiting for the next rollover.
+		entries, err = s.entriesFromHistory(kind, w, offset, limit)
+		s.leaderboards.hydrate(entries)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Entries = make([]models.SeasonStanding, len(entries))
+	for i, e := range entries {
+		result.Entries[i] = models.SeasonStanding{SeasonID: seasonID, Board: board, Rank: e.Rank,
+			UserID: e.UserID, Username: e.Username, Value: e.Score}
+	}
+	return result, nil
+}
+
+// entriesFromHistory reads a page of a season board from user_answers.
+func (s *SeasonService) entriesFromHistory(kind boardKind, w PeriodWindow, offset, limit int) ([]repository.LeaderboardEntry, error) {
+	if kind == streakBoard {
+		return s.historyRepo.GetStreakLeaderboardBetween(w.Start, w.End, offset, limit)
+	}
+	return s.historyRepo.GetScoreLeaderboardBetween(w.Start, w.End, offset, limit)
+}
+
+// Rollover archives every season that has ended, clears its Redis boards and, unless seasons are
+// manual, starts the season of the current calendar window. It is safe to run on every instance:
+// archiving locks the season row and creation refuses overlaps.
+func (s *SeasonService) Rollover(now time.Time) (*RolloverResult, error) {
+	result := &RolloverResult{Archived: []models.Season{}}
+	ended, err := s.seasonRepo.ListEndedUnarchived(now)
+	if err != nil {
+		return nil, err
+	}
+	for i := range ended {
+		season := &ended[i]
+		archived, err := s.archive(season, now)
+		if err != nil {
+			return nil, fmt.Errorf("archiving season %d: %w", season.ID, err)
+		}
+		if archived {
+			season.ArchivedAt = &now
+			result.Archived = append(result.Archived, *season)
+		}
+	}
+	if len(result.Archived) > 0 {
+		s.leaderboards.InvalidateSeason()
+	}
+
+	if result.Created, err = s.startNextSeason(now); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// archive writes the final standings of both boards and drops the season's Redis boards.
+func (s *SeasonService) archive(season *models.Season, now time.Time) (bool, error) {
+	var standings []models.SeasonStanding
+	for _, kind := range []boardKind{scoreBoard, streakBoard} {
+		board := SeasonBoardScore
+		if kind == streakBoard {
+			board = SeasonBoardStreak
+		}
+		rows, err := s.finalStandings(kind, seasonWindow(season))
+		if err != nil {
+			return false, err
+		}
+		for _, row := range rows {
+			row.SeasonID, row.Board = season.ID, board
+			if kind == scoreBoard && row.Value > 0 {
+				row.Reward = rewardFor(s.rewards, row.Rank)
+			}
+			standings = append(standings, row)
+		}
+	}
+	archived, err := s.seasonRepo.ArchiveSeason(season.ID, standings, now)
+	if err != nil || !archived {
+		return false, err
+	}
+
+	w := seasonWindow(season)
+	if err := s.leaderboards.DeleteSeasonBoards(w); err != nil {
+		log.Printf("Failed to delete Redis boards of season %d (they expire on their own): %v", season.ID, err)
+	}
+	return true, nil
+}
+
+// finalStandings reads a whole season board from user_answers, with usernames.
+func (s *SeasonService) finalStandings(kind boardKind, w PeriodWindow) ([]models.SeasonStanding, error) {
+	var standings []models.SeasonStanding
+	for {
+		entries, err := s.entriesFromHistory(kind, w, len(standings), syncBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			return standings, nil
+		}
+		ids := make([]int, len(entries))
+		for i, e := range entries {
+			ids[i] = e.UserID
+		}
+		users, err := s.userRepo.GetUsersByIDs(ids)
+		if err != nil {
+			return nil, err
+		}
+		names := make(map[int]string, len(users))
+		for _, u := range users {
+			names[u.ID] = u.Username
+		}
+		for _, e := range entries {
+			standings = append(standings, models.SeasonStanding{Rank: e.Rank, UserID: e.UserID,
+				Username: names[e.UserID], Value: e.Score})
+		}
+	}
+}
+
+// startNextSeason creates the season of the calendar window containing now, if seasons roll over
+// automatically and none is running. It starts where the latest season ended if that is later.
+func (s *SeasonService) startNextSeason(now time.Time) (*models.Season, error) {
+	if s.length == "" {
+		return nil, nil
+	}
+	active, err := s.seasonRepo.GetActiveSeason(now)
+	if err != nil || active != nil {
+		return nil, err
+	}
+	latest, err := s.seasonRepo.GetLatestSeason()
+	if err != nil {
+		return nil, err
+	}
+	w := s.calendar.Window(s.length, now)
+	start := w.Start
+	if latest != nil && latest.EndsAt.After(start) {
+		start = latest.EndsAt
+	}
+	if start.After(now) || !start.Before(w.End) {
+		return nil, nil
+	}
+
+	name := w.Start.Format("January 2006")
+	if s.length == PeriodWeekly {
+		name = "Week of " + w.Start.Format("2006-01-02")
+	}
+	season, err := s.CreateSeason(name, start, w.End)
+	if err == ErrSeasonOverlap {
+		return nil, nil // another instance got there first
+	}
+	return season, err
+}
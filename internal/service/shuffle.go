@@ -0,0 +1,99 @@
+package service
+
+import (
+	"brainbolt/internal/models"
+	"math/rand/v2"
+	"sort"
+	"strings"
+)
+
+// Options of single and multi-select questions are shuffled on every serve, so answer letters
+// say nothing about the answer ("it's always C at level 4"). The order is derived from a random
+// seed stored on the issue (and on the recorded answer), so it never reaches the client and any
+// serve can be reproduced with OptionOrder. True/false questions keep True before False.
+
+// shuffles reports whether questions of q's type have their options shuffled.
+func shuffles(q *models.Question) bool {
+	t := questionType(q)
+	return (t == QuestionTypeSingle || t == QuestionTypeMulti) && len(q.Options) > 1
+}
+
+// newShuffleSeed returns a random, non-zero shuffle seed (0 means options in stored order).
+func newShuffleSeed() int64 {
+	for {
+		if seed := rand.Int64(); seed != 0 {
+			return seed
+		}
+	}
+}
+
+// OptionOrder returns the order n options are served in for a shuffle seed: served option i is
+// stored option order[i]. It is a Fisher-Yates shuffle driven by PCG(seed, n), fixed so that
+// past serves can be reproduced; seed 0 is the stored order.
+func OptionOrder(seed int64, n int) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	if seed == 0 {
+		return order
+	}
+	pcg := rand.NewPCG(uint64(seed), uint64(n))
+	for i := n - 1; i > 0; i-- {
+		j := int(pcg.Uint64() % uint64(i+1))
+		order[i], order[j] = order[j], order[i]
+	}
+	return order
+}
+
+// shuffledQuestion returns a copy of q with its options (and answer letters) in the order they
+// were served with seed. q itself is left alone, as it may be shared through the cache.
+func shuffledQuestion(q *models.Question, seed int64) *models.Question {
+	if seed == 0 || !shuffles(q) {
+		return q
+	}
+	order := OptionOrder(seed, len(q.Options))
+	served := *q
+	served.Options = make([]string, len(order))
+	servedLetter := map[string]string{}
+	for i, stored := range order {
+		served.Options[i] = q.Options[stored]
+		servedLetter[optionLetter(stored)] = optionLetter(i)
+	}
+	served.Answer = mapLetters(q.Answer, servedLetter)
+	return &served
+}
+
+// storedAnswer maps a canonical answer in served letters back to the stored letters of q, the
+// form answers are recorded in.
+func storedAnswer(q *models.Question, seed int64, answer string) string {
+	if seed == 0 || !shuffles(q) || answer == "" {
+		return answer
+	}
+	storedLetter := map[string]string{}
+	for i, stored := range OptionOrder(seed, len(q.Options)) {
+		storedLetter[optionLetter(i)] = optionLetter(stored)
+	}
+	return mapLetters(answer, storedLetter)
+}
+
+// mapLetters rewrites comma-separated option letters through m and sorts them.
+func mapLetters(letters string, m map[string]string) string {
+	parts := strings.Split(letters, ",")
+	for i, letter := range parts {
+		parts[i] = m[letter]
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// GetServedQuestion returns a question as it was served with a shuffle seed (from a question
+// issue or a recorded answer), for audits. It uses the question's current options, so it only
+// matches the serve if they were not edited since.
+func (s *QuestionBankService) GetServedQuestion(questionID int, seed int64) (*models.Question, error) {
+	q, err := s.GetQuestion(questionID)
+	if err != nil {
+		return nil, err
+	}
+	return shuffledQuestion(q, seed), nil
+}
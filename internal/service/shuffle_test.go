@@ -0,0 +1,107 @@
+package service
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"brainbolt/internal/models"
+)
+
+func TestOptionOrder(t *testing.T) {
+	for n := 0; n <= MaxQuestionOptions; n++ {
+		for i, stored := range OptionOrder(0, n) {
+			if stored != i {
+				t.Errorf("OptionOrder(0, %d) = %v, want the stored order", n, OptionOrder(0, n))
+				break
+			}
+		}
+		for _, seed := range []int64{1, -7, 1 << 40} {
+			order := OptionOrder(seed, n)
+			if !reflect.DeepEqual(order, OptionOrder(seed, n)) {
+				t.Errorf("OptionOrder(%d, %d) is not reproducible", seed, n)
+			}
+			sorted := make([]int, n)
+			copy(sorted, order)
+			sort.Ints(sorted)
+			if !reflect.DeepEqual(sorted, OptionOrder(0, n)) {
+				t.Errorf("OptionOrder(%d, %d) = %v is not a permutation", seed, n, order)
+			}
+		}
+	}
+
+	// Recorded seeds must keep reproducing the serves they were used for.
+	pinned := []struct {
+		seed  int64
+		n     int
+		order []int
+	}{
+		{42, 4, []int{1, 3, 0, 2}},
+		{-3, 6, []int{3, 2, 0, 5, 4, 1}},
+	}
+	for _, p := range pinned {
+		if got := OptionOrder(p.seed, p.n); !reflect.DeepEqual(got, p.order) {
+			t.Errorf("OptionOrder(%d, %d) = %v, want %v", p.seed, p.n, got, p.order)
+		}
+	}
+}
+
+func TestShuffledQuestion(t *testing.T) {
+	questions := []*models.Question{
+		{Options: []string{"Paris", "Rome", "Vienna", "Madrid"}, Answer: "C"},
+		{Type: QuestionTypeMulti, Options: []string{"2", "4", "5", "9", "11", "12"}, Answer: "A,C,E"},
+		{Type: QuestionTypeMulti, Options: []string{"yes", "no"}, Answer: "A,B"},
+	}
+	for _, q := range questions {
+		for seed := int64(-20); seed <= 20; seed++ {
+			served := shuffledQuestion(q, seed)
+			if got := storedAnswer(q, seed, served.Answer); got != q.Answer {
+				t.Errorf("%q seed %d: storedAnswer(served answer %q) = %q, want %q", q.Options, seed, served.Answer, got, q.Answer)
+			}
+			// The served answer names the same options as the stored one.
+			if got, want := correctAnswer(served).Options, correctAnswer(q).Options; !sameStrings(got, want) {
+				t.Errorf("%q seed %d: served answer names %q, want %q", q.Options, seed, got, want)
+			}
+			if seed == 0 && served != q {
+				t.Errorf("%q: seed 0 must serve the stored question", q.Options)
+			}
+		}
+	}
+
+	q := questions[0]
+	before := *q
+	before.Options = append([]string(nil), q.Options...)
+	shuffledQuestion(q, 42)
+	if !reflect.DeepEqual(*q, before) {
+		t.Errorf("shuffledQuestion changed the question: %+v", q)
+	}
+}
+
+func TestShuffleKeepsOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		q    *models.Question
+	}{
+		{"true/false", &models.Question{Type: QuestionTypeTrueFalse, Options: []string{"True", "False"}, Answer: "B"}},
+		{"numeric", &models.Question{Type: QuestionTypeNumeric, Answer: "3.14"}},
+		{"text", &models.Question{Type: QuestionTypeText, Answer: "Jupiter"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if served := shuffledQuestion(tt.q, 42); served != tt.q {
+				t.Errorf("shuffledQuestion = %+v, want the stored question", served)
+			}
+			if got := storedAnswer(tt.q, 42, tt.q.Answer); got != tt.q.Answer {
+				t.Errorf("storedAnswer = %q, want %q", got, tt.q.Answer)
+			}
+		})
+	}
+}
+
+// sameStrings reports whether a and b hold the same strings in any order.
+func sameStrings(a, b []string) bool {
+	a, b = append([]string(nil), a...), append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	return reflect.DeepEqual(a, b)
+}
@@ -3,56 +3,130 @@ package service
 import (
 	"brainbolt/internal/models"
 	"brainbolt/internal/repository"
+	"context"
 	"database/sql"
+	"log"
+	"regexp"
+	"strings"
 	"time"
 )
 
 // StreakDecayWindow is the time after which streak starts degrading (e.g. lose 1 per full window since last answer).
 const StreakDecayWindow = 24 * time.Hour
 
-// UserService handles user-related business logic (cache, streak decay, metrics).
+// usernamePattern restricts usernames to 3-32 URL-safe characters.
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{3,32}$`)
+
+// UserService handles user-related business logic (cache, streak decay, metrics, profile lifecycle).
 type UserService struct {
-	userRepo      *repository.UserRepository
-	userCacheRepo *repository.UserCacheRepository
+	userRepo        *repository.UserRepository
+	userCacheRepo   *repository.UserCacheRepository
+	leaderboardRepo *repository.LeaderboardRepository
+	leaderboards    *LeaderboardService
 }
 
 // NewUserService creates a new user service.
-func NewUserService(userRepo *repository.UserRepository, userCacheRepo *repository.UserCacheRepository) *UserService {
+func NewUserService(
+	userRepo *repository.UserRepository,
+	userCacheRepo *repository.UserCacheRepository,
+	leaderboardRepo *repository.LeaderboardRepository,
+	leaderboards *LeaderboardService,
+) *UserService {
 	return &UserService{
-		userRepo:      userRepo,
-		userCacheRepo: userCacheRepo,
+		userRepo:        userRepo,
+		userCacheRepo:   userCacheRepo,
+		leaderboardRepo: leaderboardRepo,
+		leaderboards:    leaderboards,
 	}
 }
 
-// applyStreakDecay reduces user.Streak based on time since LastAnsweredAt (1 per full StreakDecayWindow). Returns true if streak was changed.
+// normalizeUsername trims the username and validates it against usernamePattern.
+func normalizeUsername(username string) (string, error) {
+	username = strings.TrimSpace(username)
+	if !usernamePattern.MatchString(username) {
+		return "", ErrInvalidUsername
+	}
+	return username, nil
+}
+
+// applyStreakDecay reduces user.Streak by 1 per full StreakDecayWindow since LastAnsweredAt that has
+// not been applied yet; StreakDecayedAt records how far decay has been applied, so repeated calls
+// do not decay the same window twice. Returns true if streak was changed.
 func (s *UserService) applyStreakDecay(user *models.User) bool {
-	if user.LastAnsweredAt == nil {
+	if user.LastAnsweredAt == nil || user.Streak == 0 {
 		return false
 	}
-	elapsed := time.Since(*user.LastAnsweredAt)
+	from := *user.LastAnsweredAt
+	if user.StreakDecayedAt != nil && user.StreakDecayedAt.After(from) {
+		from = *user.StreakDecayedAt
+	}
+	elapsed := time.Since(from)
 	if elapsed < StreakDecayWindow {
 		return false
 	}
 	periods := int(elapsed / StreakDecayWindow)
+	decayedAt := from.Add(time.Duration(periods) * StreakDecayWindow)
+	user.StreakDecayedAt = &decayedAt
 	newStreak := user.Streak - periods
 	if newStreak < 0 {
 		newStreak = 0
 	}
-	if newStreak == user.Streak {
-		return false
-	}
 	user.Streak = newStreak
 	return true
 }
 
+// persistStreakDecay stores a decayed streak in MySQL and, unless the user answered meanwhile,
+// in the cache and on the current streak leaderboard.
+func (s *UserService) persistStreakDecay(user *models.User) error {
+	updated, err := s.userRepo.UpdateUserStreak(user.ID, user.Streak, user.StreakDecayedAt, user.LastAnsweredAt)
+	if err != nil || !updated {
+		return err
+	}
+	pipe := s.leaderboardRepo.Pipeline()
+	if s.userCacheRepo != nil {
+		_ = s.userCacheRepo.QueueSet(pipe, user.ID, user)
+	}
+	s.leaderboards.QueueCurrentStreak(pipe, user)
+	if _, err := pipe.Exec(context.Background()); err != nil {
+		log.Printf("Redis pipeline Exec failed decaying streak of userID %d: %v", user.ID, err)
+	}
+	return nil
+}
+
+// DecayStreaks applies streak decay to every user whose streak is due for it, so the current
+// streak leaderboard reflects players who stopped answering. Returns the number of users decayed.
+func (s *UserService) DecayStreaks() (int, error) {
+	before := time.Now().Add(-StreakDecayWindow)
+	decayed, afterID := 0, 0
+	for {
+		users, err := s.userRepo.ListDecayableStreaksAfter(before, afterID, syncBatchSize)
+		if err != nil {
+			return decayed, err
+		}
+		if len(users) == 0 {
+			return decayed, nil
+		}
+		for i := range users {
+			u := &users[i]
+			if !s.applyStreakDecay(u) {
+				continue
+			}
+			if err := s.persistStreakDecay(u); err != nil {
+				return decayed, err
+			}
+			decayed++
+		}
+		afterID = users[len(users)-1].ID
+	}
+}
+
 // GetUserFromCacheOrDB returns the user from cache first, then DB; on DB hit populates cache.
 // Applies streak decay based on time since last answer; persists and updates cache if streak was decayed.
 func (s *UserService) GetUserFromCacheOrDB(userID int) (*models.User, error) {
 	if s.userCacheRepo != nil {
 		if user, err := s.userCacheRepo.Get(userID); err == nil && user != nil {
 			if s.applyStreakDecay(user) {
-				_ = s.userRepo.UpdateUserStreak(userID, user.Streak)
-				_ = s.userCacheRepo.Set(userID, user)
+				_ = s.persistStreakDecay(user)
 			}
 			return user, nil
 		}
@@ -65,7 +139,7 @@ func (s *UserService) GetUserFromCacheOrDB(userID int) (*models.User, error) {
 		return nil, err
 	}
 	if s.applyStreakDecay(user) {
-		_ = s.userRepo.UpdateUserStreak(userID, user.Streak)
+		_ = s.persistStreakDecay(user)
 	}
 	if s.userCacheRepo != nil {
 		_ = s.userCacheRepo.Set(userID, user)
@@ -82,3 +156,83 @@ func (s *UserService) GetUserByID(userID int) (*models.User, error) {
 func (s *UserService) GetUserMetrics(userID int) (*models.User, error) {
 	return s.GetUserByID(userID)
 }
+
+// GetCategoryLevels returns the user's difficulty level in every category they have answered.
+func (s *UserService) GetCategoryLevels(userID int) ([]models.CategoryLevel, error) {
+	return s.userRepo.ListCategoryLevels(userID)
+}
+
+// CreateUser validates the username, inserts the user and seeds the cache and the all-time leaderboards
+// so the new user is immediately servable by /v1/quiz/next and visible on the leaderboards.
+func (s *UserService) CreateUser(username string) (*models.User, error) {
+	username, err := normalizeUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.CreateUser(username)
+	if err != nil {
+		if err == repository.ErrDuplicateEntry {
+			return nil, ErrUsernameTaken
+		}
+		return nil, err
+	}
+
+	pipe := s.leaderboardRepo.Pipeline()
+	if s.userCacheRepo != nil {
+		_ = s.userCacheRepo.QueueSet(pipe, user.ID, user)
+	}
+	s.leaderboards.QueueUser(pipe, user)
+	if _, err := pipe.Exec(context.Background()); err != nil {
+		log.Printf("Redis pipeline Exec failed seeding new userID %d: %v", user.ID, err)
+	}
+	return user, nil
+}
+
+// RenameUser changes a user's username and refreshes the cached copy.
+func (s *UserService) RenameUser(userID int, username string) (*models.User, error) {
+	username, err := normalizeUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.UpdateUsername(userID, username); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		if err == repository.ErrDuplicateEntry {
+			return nil, ErrUsernameTaken
+		}
+		return nil, err
+	}
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if s.userCacheRepo != nil {
+		_ = s.userCacheRepo.Set(userID, user)
+	}
+	return user, nil
+}
+
+// DeleteUser removes the user from MySQL (cascading user_questions) and clears every Redis key
+// that refers to them: the cached profile, the all-time leaderboards and the current period boards.
+func (s *UserService) DeleteUser(userID int) error {
+	if err := s.userRepo.DeleteUser(userID); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	pipe := s.leaderboardRepo.Pipeline()
+	if s.userCacheRepo != nil {
+		s.userCacheRepo.QueueDelete(pipe, userID)
+	}
+	s.leaderboards.QueueRemoveUser(pipe, userID)
+	if _, err := pipe.Exec(context.Background()); err != nil {
+		log.Printf("Redis pipeline Exec failed deleting userID %d: %v", userID, err)
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+-- Columns for the highest-difficulty and current-streak leaderboards (for existing databases)
+-- Usage: mysql -u root -p brainbolt < scripts/add_accuracy_streak_difficulty_boards.sql
+-- max_difficulty is backfilled from the current level and the hardest question answered correctly
+-- (reached at NULL, i.e. before anyone else). Restart the app or run "brainbolt leaderboard rebuild"
+-- afterwards so Redis gets the new boards.
+
+ALTER TABLE users
+  ADD COLUMN max_difficulty            INT         NOT NULL DEFAULT 1 AFTER max_streak_reached_at,
+  ADD COLUMN max_difficulty_reached_at DATETIME(3) NULL AFTER max_difficulty,
+  ADD COLUMN streak_decayed_at         DATETIME(3) NULL AFTER max_difficulty_reached_at;
+
+UPDATE users u
+SET max_difficulty = GREATEST(
+  COALESCE(u.current_difficulty, 1),
+  COALESCE((SELECT MAX(a.difficulty) FROM user_answers a WHERE a.user_id = u.id AND a.is_correct = 1), 1)
+);
@@ -0,0 +1,4 @@
+-- Add quiz mode to user_answers (practice answers stay out of leaderboards and calibration, for existing databases)
+-- Usage: mysql -u root -p brainbolt < scripts/add_answer_mode.sql
+
+ALTER TABLE user_answers ADD COLUMN mode VARCHAR(32) NOT NULL DEFAULT 'classic' AFTER is_correct;
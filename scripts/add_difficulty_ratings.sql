@@ -0,0 +1,14 @@
+-- Add Elo/IRT ratings and hysteresis progress (for existing databases)
+-- NULL ratings default to 600 + 100 * difficulty in queries; this backfills them explicitly.
+-- Usage: mysql -u root -p brainbolt < scripts/add_difficulty_ratings.sql
+
+ALTER TABLE users
+  ADD COLUMN rating              DOUBLE NULL,
+  ADD COLUMN difficulty_progress INT    NOT NULL DEFAULT 0;
+
+ALTER TABLE questions
+  ADD COLUMN rating DOUBLE NULL,
+  ADD INDEX idx_questions_rating (rating);
+
+UPDATE users SET rating = 600 + 100 * COALESCE(current_difficulty, 1) WHERE rating IS NULL;
+UPDATE questions SET rating = 600 + 100 * difficulty WHERE rating IS NULL;
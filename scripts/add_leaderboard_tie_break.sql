@@ -0,0 +1,8 @@
+-- Record when each user's score and max streak were reached (leaderboard tie-break, for existing databases)
+-- Usage: mysql -u root -p brainbolt < scripts/add_leaderboard_tie_break.sql
+-- Existing rows keep NULL (treated as reached before anyone else); restart the app or run
+-- "brainbolt leaderboard rebuild" afterwards so Redis switches to the tie-break score format.
+
+ALTER TABLE users
+  ADD COLUMN score_reached_at      DATETIME(3) NULL AFTER difficulty_progress,
+  ADD COLUMN max_streak_reached_at DATETIME(3) NULL AFTER score_reached_at;
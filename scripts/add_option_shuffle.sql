@@ -0,0 +1,5 @@
+-- Add option shuffle seeds to question_issues and user_answers (per-serve option order, for existing databases)
+-- Usage: mysql -u root -p brainbolt < scripts/add_option_shuffle.sql
+
+ALTER TABLE question_issues ADD COLUMN shuffle_seed BIGINT NOT NULL DEFAULT 0 AFTER mode;
+ALTER TABLE user_answers ADD COLUMN shuffle_seed BIGINT NOT NULL DEFAULT 0 AFTER is_correct;
@@ -0,0 +1,6 @@
+-- Make question ids auto-increment and add soft retirement, for the admin question API (for existing databases)
+-- Usage: mysql -u root -p brainbolt < scripts/add_question_bank_admin.sql
+
+ALTER TABLE questions
+  MODIFY COLUMN id INT NOT NULL AUTO_INCREMENT,
+  ADD COLUMN retired_at DATETIME(3) NULL;
@@ -0,0 +1,42 @@
+-- Add question categories and tags, and per-category difficulty levels (for existing databases)
+-- Tags the seed questions of create_questions_table.sql; other questions stay in 'general'.
+-- Usage: mysql -u root -p brainbolt < scripts/add_question_categories.sql
+
+ALTER TABLE questions
+  ADD COLUMN category VARCHAR(32) NOT NULL DEFAULT 'general',
+  ADD COLUMN tags     JSON        NULL,
+  ADD INDEX idx_questions_category_difficulty (category, difficulty);
+
+CREATE TABLE IF NOT EXISTS user_category_levels (
+  user_id             INT         NOT NULL,
+  category            VARCHAR(32) NOT NULL,
+  current_difficulty  INT         NOT NULL DEFAULT 1,
+  difficulty_progress INT         NOT NULL DEFAULT 0,
+  rating              DOUBLE      NOT NULL,
+  total_answered      INT         NOT NULL DEFAULT 0,
+  total_correct       INT         NOT NULL DEFAULT 0,
+  PRIMARY KEY (user_id, category),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+UPDATE questions SET category = 'geography' WHERE id IN (1, 7, 11, 12, 13, 14, 29, 35, 36, 37, 38);
+UPDATE questions SET category = 'science' WHERE id IN (2, 4, 10, 15, 17, 23, 24, 25, 26, 47, 48, 49, 50);
+UPDATE questions SET category = 'math' WHERE id IN (3, 6, 19, 20, 21, 22, 31, 32, 33, 34);
+UPDATE questions SET category = 'history' WHERE id IN (5, 8, 27, 28, 30, 39, 40, 41, 42);
+UPDATE questions SET category = 'biology' WHERE id IN (9, 16, 18, 43, 44, 45, 46);
+UPDATE questions SET tags = '["animals"]' WHERE id IN (16, 46);
+UPDATE questions SET tags = '["arithmetic"]' WHERE id IN (3, 19, 20, 21, 22, 31, 33);
+UPDATE questions SET tags = '["art"]' WHERE id IN (5);
+UPDATE questions SET tags = '["capitals"]' WHERE id IN (1, 13);
+UPDATE questions SET tags = '["cells"]' WHERE id IN (44);
+UPDATE questions SET tags = '["chemistry"]' WHERE id IN (4, 15, 23, 24, 25);
+UPDATE questions SET tags = '["countries"]' WHERE id IN (14, 36, 37);
+UPDATE questions SET tags = '["geometry"]' WHERE id IN (6, 32, 34);
+UPDATE questions SET tags = '["human-body"]' WHERE id IN (9, 43, 45);
+UPDATE questions SET tags = '["landmarks"]' WHERE id IN (29, 30);
+UPDATE questions SET tags = '["literature"]' WHERE id IN (28);
+UPDATE questions SET tags = '["oceans"]' WHERE id IN (11);
+UPDATE questions SET tags = '["physics"]' WHERE id IN (10, 26, 47, 48, 49, 50);
+UPDATE questions SET tags = '["plants"]' WHERE id IN (18);
+UPDATE questions SET tags = '["space"]' WHERE id IN (2, 17, 39);
+UPDATE questions SET tags = '["wars"]' WHERE id IN (27, 40, 42);
@@ -0,0 +1,6 @@
+-- Add explanations and references to questions (shown after answering, for existing databases)
+-- Usage: mysql -u root -p brainbolt < scripts/add_question_explanations.sql
+
+ALTER TABLE questions
+  ADD COLUMN explanation TEXT NULL AFTER tags,
+  ADD COLUMN refs        JSON NULL AFTER explanation;
@@ -0,0 +1,4 @@
+-- Add quiz mode to question_issues (selects the scoring policy, for existing databases)
+-- Usage: mysql -u root -p brainbolt < scripts/add_question_issue_mode.sql
+
+ALTER TABLE question_issues ADD COLUMN mode VARCHAR(32) NOT NULL DEFAULT 'classic' AFTER question_id;
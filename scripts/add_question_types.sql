@@ -0,0 +1,10 @@
+-- Add question types (single, truefalse, multi, numeric, text) and their grading settings (for existing databases)
+-- Existing questions stay single choice.
+-- Usage: mysql -u root -p brainbolt < scripts/add_question_types.sql
+
+ALTER TABLE questions
+  ADD COLUMN type           VARCHAR(16) NOT NULL DEFAULT 'single' AFTER id,
+  MODIFY COLUMN answer      VARCHAR(255) NOT NULL,
+  ADD COLUMN tolerance      DOUBLE      NOT NULL DEFAULT 0 AFTER answer,
+  ADD COLUMN partial_credit TINYINT(1)  NOT NULL DEFAULT 0 AFTER tolerance,
+  ADD COLUMN aliases        JSON        NULL AFTER partial_credit;
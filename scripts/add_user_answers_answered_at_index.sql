@@ -0,0 +1,4 @@
+-- Index user_answers by answered_at (daily/weekly/monthly leaderboard fallback, for existing databases)
+-- Usage: mysql -u root -p brainbolt < scripts/add_user_answers_answered_at_index.sql
+
+ALTER TABLE user_answers ADD INDEX idx_user_answers_answered_at (answered_at);
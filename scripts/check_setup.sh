@@ -27,7 +27,7 @@ if mysql -u "$DB_USER" -p"${MYSQL_PASSWORD:-}" "$DB_NAME" -e "DESCRIBE users;" 2
   echo "✓ users table has 'id' column"
 else
   echo "✗ users table missing 'id' column"
-  echo "  Run: mysql -u root -p brainbolt < scripts/recreate_schema.sql"
+  echo "  Run (from the repository root, deletes all user data): mysql -u root -p brainbolt < scripts/recreate_schema.sql"
   exit 1
 fi
 
@@ -0,0 +1,104 @@
+#!/usr/bin/env bash
+# Concurrency check for POST /v1/quiz/answer: fires N answers for ONE fresh user in parallel
+# and verifies no counter update was lost (totals, history rows and score all add up), then
+# races several submissions of ONE question token and verifies exactly one is accepted.
+# Requires curl and jq. Usage: ./scripts/concurrency_test.sh [N]   (N <= 45, default 30; /next and /answer share a 100/min rate limit, the race adds 6 requests)
+
+set -e
+
+BASE_URL="${BASE_URL:-http://localhost:3001}"
+N="${1:-30}"
+
+if ! command -v jq &>/dev/null; then
+  echo "jq is required for this test"
+  exit 1
+fi
+
+echo "=========================================="
+echo "BrainBolt concurrent answer test (N=$N, BASE_URL=$BASE_URL)"
+echo "=========================================="
+
+USERNAME="concurrency_$(date +%s)"
+USER_ID=$(curl -s -X POST "$BASE_URL/v1/users" -H "Content-Type: application/json" \
+  -d "{\"username\":\"$USERNAME\"}" | jq -r '.id // empty')
+if [[ -z "$USER_ID" ]]; then
+  echo "FAIL: could not create test user"
+  exit 1
+fi
+echo "Created user $USERNAME (id=$USER_ID)"
+
+# Serve N questions first (each with its own single-use token), then answer them all at once.
+TMP_DIR=$(mktemp -d)
+trap 'rm -rf "$TMP_DIR"' EXIT
+for i in $(seq 1 "$N"); do
+  curl -s "$BASE_URL/v1/quiz/next?userId=$USER_ID" > "$TMP_DIR/served_$i"
+done
+for i in $(seq 1 "$N"); do
+  qid=$(jq -r '.questionId' "$TMP_DIR/served_$i")
+  token=$(jq -r '.questionToken' "$TMP_DIR/served_$i")
+  curl -s -o /dev/null -w "%{http_code}\n" -X POST "$BASE_URL/v1/quiz/answer" \
+    -H "Content-Type: application/json" \
+    -d "{\"userId\":$USER_ID,\"questionId\":$qid,\"answer\":\"B\",\"questionToken\":\"$token\"}" > "$TMP_DIR/code_$i" &
+done
+wait
+
+OK_COUNT=$(cat "$TMP_DIR"/code_* | grep -c '^200$' || true)
+CONFLICTS=$(cat "$TMP_DIR"/code_* | grep -c '^409$' || true)
+echo "Responses: $OK_COUNT x 200, $CONFLICTS x 409 (conflict, not applied)"
+
+# Same-token race: only one of the parallel submissions of a single issue may be applied.
+RACE=5
+served=$(curl -s "$BASE_URL/v1/quiz/next?userId=$USER_ID")
+qid=$(jq -r '.questionId' <<< "$served")
+token=$(jq -r '.questionToken' <<< "$served")
+for i in $(seq 1 "$RACE"); do
+  curl -s -o /dev/null -w "%{http_code}\n" -X POST "$BASE_URL/v1/quiz/answer" \
+    -H "Content-Type: application/json" \
+    -d "{\"userId\":$USER_ID,\"questionId\":$qid,\"answer\":\"B\",\"questionToken\":\"$token\"}" > "$TMP_DIR/race_$i" &
+done
+wait
+RACE_OK=$(cat "$TMP_DIR"/race_* | grep -c '^200$' || true)
+echo "Same-token race: $RACE_OK x 200 out of $RACE"
+ACCEPTED=$((OK_COUNT + RACE_OK))
+
+metrics=$(curl -s "$BASE_URL/v1/quiz/metrics?userId=$USER_ID")
+history=$(curl -s "$BASE_URL/v1/users/$USER_ID/answers?limit=100")
+total_answered=$(jq -r '.totalAnswered' <<< "$metrics")
+total_correct=$(jq -r '.totalCorrect' <<< "$metrics")
+total_score=$(jq -r '.totalScore' <<< "$metrics")
+history_rows=$(jq '.answers | length' <<< "$history")
+history_correct=$(jq '[.answers[] | select(.correct)] | length' <<< "$history")
+history_score=$(jq '[.answers[].scoreDelta] | add // 0' <<< "$history")
+
+echo "totalAnswered=$total_answered historyRows=$history_rows"
+echo "totalCorrect=$total_correct historyCorrect=$history_correct"
+echo "totalScore=$total_score sum(scoreDelta)=$history_score"
+
+status=0
+if [[ "$OK_COUNT" -lt 1 ]]; then
+  echo "FAIL: no answer was accepted"
+  status=1
+fi
+if [[ "$RACE_OK" -ne 1 ]]; then
+  echo "FAIL: exactly one submission of the raced token must be accepted"
+  status=1
+fi
+if [[ "$total_answered" != "$ACCEPTED" || "$history_rows" != "$ACCEPTED" ]]; then
+  echo "FAIL: totalAnswered and history rows must equal the number of accepted answers"
+  status=1
+fi
+if [[ "$total_correct" != "$history_correct" ]]; then
+  echo "FAIL: totalCorrect does not match correct answers in history"
+  status=1
+fi
+if [[ "$total_score" != "$history_score" ]]; then
+  echo "FAIL: totalScore does not match the sum of score deltas"
+  status=1
+fi
+
+curl -s -o /dev/null -X DELETE "$BASE_URL/v1/users/$USER_ID"
+
+if [[ $status -ne 0 ]]; then
+  exit $status
+fi
+echo "OK: no lost updates"
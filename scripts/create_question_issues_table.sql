@@ -0,0 +1,15 @@
+-- Create question_issues table (one-answer-per-issue ledger for question tokens, for existing databases)
+-- Usage: mysql -u root -p brainbolt < scripts/create_question_issues_table.sql
+
+CREATE TABLE IF NOT EXISTS question_issues (
+  id          BIGINT      AUTO_INCREMENT PRIMARY KEY,
+  user_id     INT         NOT NULL,
+  question_id INT         NOT NULL,
+  mode        VARCHAR(32) NOT NULL DEFAULT 'classic',
+  shuffle_seed BIGINT     NOT NULL DEFAULT 0,
+  issued_at   DATETIME(3) NOT NULL,
+  expires_at  DATETIME(3) NOT NULL,
+  answered_at DATETIME(3) NULL,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  INDEX idx_question_issues_expires_at (expires_at)
+);
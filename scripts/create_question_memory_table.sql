@@ -0,0 +1,16 @@
+-- Create question_memory table (spaced-repetition state of practice mode, for existing databases)
+-- Usage: mysql -u root -p brainbolt < scripts/create_question_memory_table.sql
+
+CREATE TABLE IF NOT EXISTS question_memory (
+  user_id       INT         NOT NULL,
+  question_id   INT         NOT NULL,
+  repetitions   INT         NOT NULL DEFAULT 0,
+  interval_days INT         NOT NULL DEFAULT 0,
+  ease_factor   DOUBLE      NOT NULL DEFAULT 2.5,
+  lapses        INT         NOT NULL DEFAULT 0,
+  due_at        DATETIME(3) NOT NULL,
+  reviewed_at   DATETIME(3) NOT NULL,
+  PRIMARY KEY (user_id, question_id),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  INDEX idx_question_memory_user_id_due_at (user_id, due_at)
+);
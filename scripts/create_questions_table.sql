@@ -2,12 +2,24 @@
 -- Run after schema.sql. Usage: mysql -u root -p brainbolt < scripts/create_questions_table.sql
 
 CREATE TABLE IF NOT EXISTS questions (
-  id         INT          PRIMARY KEY,
-  difficulty INT          NOT NULL,
-  question   TEXT         NOT NULL,
-  options    JSON         NOT NULL,
-  answer     VARCHAR(5)   NOT NULL,
-  INDEX idx_questions_difficulty (difficulty)
+  id             INT          AUTO_INCREMENT PRIMARY KEY,
+  type           VARCHAR(16)  NOT NULL DEFAULT 'single',
+  difficulty     INT          NOT NULL,
+  question       TEXT         NOT NULL,
+  options        JSON         NOT NULL,
+  answer         VARCHAR(255) NOT NULL,
+  tolerance      DOUBLE       NOT NULL DEFAULT 0,
+  partial_credit TINYINT(1)   NOT NULL DEFAULT 0,
+  aliases        JSON         NULL,
+  rating         DOUBLE       NULL,
+  category       VARCHAR(32)  NOT NULL DEFAULT 'general',
+  tags           JSON         NULL,
+  explanation    TEXT         NULL,
+  refs           JSON         NULL,
+  retired_at     DATETIME(3)  NULL,
+  INDEX idx_questions_difficulty (difficulty),
+  INDEX idx_questions_rating (rating),
+  INDEX idx_questions_category_difficulty (category, difficulty)
 );
 
 INSERT INTO questions (id, difficulty, question, options, answer) VALUES
@@ -61,3 +73,37 @@ INSERT INTO questions (id, difficulty, question, options, answer) VALUES
 (48, 10, 'What is the smallest unit of matter?', '["Molecule", "Atom", "Electron", "Quark"]', 'B'),
 (49, 10, 'Who discovered the law of gravity?', '["Galileo", "Newton", "Einstein", "Kepler"]', 'B'),
 (50, 10, 'What is the formula for energy (Einstein''s equation)?', '["E = mc", "E = mc²", "E = mv²", "E = mgh"]', 'B');
+
+-- Categories and tags (filters of /v1/quiz/next)
+UPDATE questions SET category = 'geography' WHERE id IN (1, 7, 11, 12, 13, 14, 29, 35, 36, 37, 38);
+UPDATE questions SET category = 'science' WHERE id IN (2, 4, 10, 15, 17, 23, 24, 25, 26, 47, 48, 49, 50);
+UPDATE questions SET category = 'math' WHERE id IN (3, 6, 19, 20, 21, 22, 31, 32, 33, 34);
+UPDATE questions SET category = 'history' WHERE id IN (5, 8, 27, 28, 30, 39, 40, 41, 42);
+UPDATE questions SET category = 'biology' WHERE id IN (9, 16, 18, 43, 44, 45, 46);
+UPDATE questions SET tags = '["animals"]' WHERE id IN (16, 46);
+UPDATE questions SET tags = '["arithmetic"]' WHERE id IN (3, 19, 20, 21, 22, 31, 33);
+UPDATE questions SET tags = '["art"]' WHERE id IN (5);
+UPDATE questions SET tags = '["capitals"]' WHERE id IN (1, 13);
+UPDATE questions SET tags = '["cells"]' WHERE id IN (44);
+UPDATE questions SET tags = '["chemistry"]' WHERE id IN (4, 15, 23, 24, 25);
+UPDATE questions SET tags = '["countries"]' WHERE id IN (14, 36, 37);
+UPDATE questions SET tags = '["geometry"]' WHERE id IN (6, 32, 34);
+UPDATE questions SET tags = '["human-body"]' WHERE id IN (9, 43, 45);
+UPDATE questions SET tags = '["landmarks"]' WHERE id IN (29, 30);
+UPDATE questions SET tags = '["literature"]' WHERE id IN (28);
+UPDATE questions SET tags = '["oceans"]' WHERE id IN (11);
+UPDATE questions SET tags = '["physics"]' WHERE id IN (10, 26, 47, 48, 49, 50);
+UPDATE questions SET tags = '["plants"]' WHERE id IN (18);
+UPDATE questions SET tags = '["space"]' WHERE id IN (2, 17, 39);
+UPDATE questions SET tags = '["wars"]' WHERE id IN (27, 40, 42);
+
+-- Explanations and references (shown after answering and in /v1/quiz/review)
+UPDATE questions SET explanation = 'Iron oxide (rust) in its soil and dust gives Mars its reddish colour.',
+  refs = '["https://en.wikipedia.org/wiki/Mars"]' WHERE id = 2;
+UPDATE questions SET explanation = 'The Sun is a star, about 8 light-minutes away; Proxima Centauri is the next closest at about 4.2 light-years.',
+  refs = '["https://en.wikipedia.org/wiki/Sun"]' WHERE id = 17;
+UPDATE questions SET explanation = 'Vatican City covers about 0.44 km², less than a fifth of Monaco.',
+  refs = '["https://en.wikipedia.org/wiki/Vatican_City"]' WHERE id = 37;
+UPDATE questions SET explanation = 'Mitochondria produce most of the cell''s ATP through cellular respiration.',
+  refs = '["https://en.wikipedia.org/wiki/Mitochondrion"]' WHERE id = 44;
+UPDATE questions SET explanation = 'Electrons and quarks are smaller, but the atom is the smallest unit of matter that keeps the properties of a chemical element.' WHERE id = 48;
@@ -0,0 +1,13 @@
+-- Create rank_snapshots table (periodic per-user score and rank snapshots behind /v1/users/{id}/rank-history, for existing databases)
+-- Usage: mysql -u root -p brainbolt < scripts/create_rank_snapshots_table.sql
+
+CREATE TABLE IF NOT EXISTS rank_snapshots (
+  user_id     INT         NOT NULL,
+  taken_at    DATETIME(3) NOT NULL,
+  score       BIGINT      NOT NULL,
+  score_rank  INT         NOT NULL,
+  streak_rank INT         NOT NULL,
+  PRIMARY KEY (user_id, taken_at),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  INDEX idx_rank_snapshots_taken_at (taken_at)
+);
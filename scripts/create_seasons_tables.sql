@@ -0,0 +1,26 @@
+-- Create seasons and season_standings tables (leaderboard seasons and their archived final standings, for existing databases)
+-- Usage: mysql -u root -p brainbolt < scripts/create_seasons_tables.sql
+
+CREATE TABLE IF NOT EXISTS seasons (
+  id          INT         AUTO_INCREMENT PRIMARY KEY,
+  name        VARCHAR(64) NOT NULL,
+  starts_at   DATETIME(3) NOT NULL,
+  ends_at     DATETIME(3) NOT NULL,
+  archived_at DATETIME(3) NULL,
+  INDEX idx_seasons_starts_at (starts_at),
+  INDEX idx_seasons_ends_at (ends_at)
+);
+
+CREATE TABLE IF NOT EXISTS season_standings (
+  season_id   INT         NOT NULL,
+  board       VARCHAR(16) NOT NULL,
+  season_rank INT         NOT NULL,
+  user_id     INT         NULL,
+  username    VARCHAR(255) NOT NULL,
+  value       BIGINT      NOT NULL,
+  reward      VARCHAR(32) NULL,
+  PRIMARY KEY (season_id, board, season_rank),
+  FOREIGN KEY (season_id) REFERENCES seasons(id) ON DELETE CASCADE,
+  CONSTRAINT fk_season_standings_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+  INDEX idx_season_standings_user_id (user_id)
+);
@@ -0,0 +1,21 @@
+-- Create user_answers table (per-answer history, for existing databases)
+-- Usage: mysql -u root -p brainbolt < scripts/create_user_answers_table.sql
+
+CREATE TABLE IF NOT EXISTS user_answers (
+  id            BIGINT      AUTO_INCREMENT PRIMARY KEY,
+  user_id       INT         NOT NULL,
+  question_id   INT         NOT NULL,
+  answer        VARCHAR(255) NOT NULL,
+  is_correct    TINYINT(1)  NOT NULL,
+  mode          VARCHAR(32) NOT NULL DEFAULT 'classic',
+  shuffle_seed  BIGINT      NOT NULL DEFAULT 0,
+  difficulty    INT         NOT NULL,
+  score_delta   BIGINT      NOT NULL DEFAULT 0,
+  streak_before INT         NOT NULL,
+  streak_after  INT         NOT NULL,
+  answered_at   DATETIME(3) NOT NULL,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  INDEX idx_user_answers_user_id_id (user_id, id),
+  INDEX idx_user_answers_question_id (question_id),
+  INDEX idx_user_answers_answered_at (answered_at)
+);
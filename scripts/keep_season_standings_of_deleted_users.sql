@@ -0,0 +1,8 @@
+-- Keep archived season standings when a user is deleted (user_id becomes NULL, for existing databases)
+-- Assumes the season_standings user FK has MySQL's generated name; check with SHOW CREATE TABLE season_standings.
+-- Usage: mysql -u root -p brainbolt < scripts/keep_season_standings_of_deleted_users.sql
+
+ALTER TABLE season_standings DROP FOREIGN KEY season_standings_ibfk_2;
+ALTER TABLE season_standings MODIFY user_id INT NULL;
+ALTER TABLE season_standings ADD CONSTRAINT fk_season_standings_user_id
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
@@ -63,11 +63,14 @@ run_one_request() {
     out=$(curl -s -o /dev/null -w "%{http_code} %{time_starttransfer}" "${BASE_URL}/v1/quiz/next?userId=${uid}")
   elif (( p < PCT_NEXT + PCT_ANSWER )); then
     endpoint="answer"
-    # For /answer, we first need a questionId. We'll just use a random one 1..50 to save an extra call
-    local qid=$((1 + RANDOM % 50))
+    # /answer needs a question token, so serve a question first (only the answer call is timed)
+    local served qid token
+    served=$(curl -s "${BASE_URL}/v1/quiz/next?userId=${uid}")
+    qid=$(echo "$served" | sed -n 's/.*"questionId":\([0-9]*\).*/\1/p')
+    token=$(echo "$served" | sed -n 's/.*"questionToken":"\([^"]*\)".*/\1/p')
     out=$(curl -s -o /dev/null -w "%{http_code} %{time_starttransfer}" -X POST "${BASE_URL}/v1/quiz/answer" \
       -H "Content-Type: application/json" \
-      -d "{\"userId\":${uid},\"questionId\":${qid},\"answer\":\"A\"}")
+      -d "{\"userId\":${uid},\"questionId\":${qid:-1},\"answer\":\"A\",\"questionToken\":\"${token}\"}")
   elif (( p < PCT_NEXT + PCT_ANSWER + PCT_METRICS )); then
     endpoint="metrics"
     out=$(curl -s -o /dev/null -w "%{http_code} %{time_starttransfer}" "${BASE_URL}/v1/quiz/metrics?userId=${uid}")
@@ -12,7 +12,14 @@ CREATE TABLE IF NOT EXISTS users (
   total_answered     INT          NOT NULL DEFAULT 0,
   current_difficulty INT          NULL DEFAULT 1,
   last_answer_correct TINYINT(1)  NULL,
-  last_answered_at    DATETIME(3) NULL
+  last_answered_at    DATETIME(3) NULL,
+  rating              DOUBLE      NULL,
+  difficulty_progress INT         NOT NULL DEFAULT 0,
+  score_reached_at      DATETIME(3) NULL,
+  max_streak_reached_at DATETIME(3) NULL,
+  max_difficulty        INT          NOT NULL DEFAULT 1,
+  max_difficulty_reached_at DATETIME(3) NULL,
+  streak_decayed_at     DATETIME(3) NULL
 );
 
 CREATE TABLE IF NOT EXISTS user_questions (
@@ -24,3 +31,96 @@ CREATE TABLE IF NOT EXISTS user_questions (
   INDEX idx_user_questions_user_id (user_id),
   INDEX idx_user_questions_question_id (question_id)
 );
+
+CREATE TABLE IF NOT EXISTS user_category_levels (
+  user_id             INT         NOT NULL,
+  category            VARCHAR(32) NOT NULL,
+  current_difficulty  INT         NOT NULL DEFAULT 1,
+  difficulty_progress INT         NOT NULL DEFAULT 0,
+  rating              DOUBLE      NOT NULL,
+  total_answered      INT         NOT NULL DEFAULT 0,
+  total_correct       INT         NOT NULL DEFAULT 0,
+  PRIMARY KEY (user_id, category),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS user_answers (
+  id            BIGINT      AUTO_INCREMENT PRIMARY KEY,
+  user_id       INT         NOT NULL,
+  question_id   INT         NOT NULL,
+  answer        VARCHAR(255) NOT NULL,
+  is_correct    TINYINT(1)  NOT NULL,
+  mode          VARCHAR(32) NOT NULL DEFAULT 'classic',
+  shuffle_seed  BIGINT      NOT NULL DEFAULT 0,
+  difficulty    INT         NOT NULL,
+  score_delta   BIGINT      NOT NULL DEFAULT 0,
+  streak_before INT         NOT NULL,
+  streak_after  INT         NOT NULL,
+  answered_at   DATETIME(3) NOT NULL,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  INDEX idx_user_answers_user_id_id (user_id, id),
+  INDEX idx_user_answers_question_id (question_id),
+  INDEX idx_user_answers_answered_at (answered_at)
+);
+
+CREATE TABLE IF NOT EXISTS question_issues (
+  id          BIGINT      AUTO_INCREMENT PRIMARY KEY,
+  user_id     INT         NOT NULL,
+  question_id INT         NOT NULL,
+  mode        VARCHAR(32) NOT NULL DEFAULT 'classic',
+  shuffle_seed BIGINT     NOT NULL DEFAULT 0,
+  issued_at   DATETIME(3) NOT NULL,
+  expires_at  DATETIME(3) NOT NULL,
+  answered_at DATETIME(3) NULL,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  INDEX idx_question_issues_expires_at (expires_at)
+);
+
+CREATE TABLE IF NOT EXISTS seasons (
+  id          INT         AUTO_INCREMENT PRIMARY KEY,
+  name        VARCHAR(64) NOT NULL,
+  starts_at   DATETIME(3) NOT NULL,
+  ends_at     DATETIME(3) NOT NULL,
+  archived_at DATETIME(3) NULL,
+  INDEX idx_seasons_starts_at (starts_at),
+  INDEX idx_seasons_ends_at (ends_at)
+);
+
+CREATE TABLE IF NOT EXISTS season_standings (
+  season_id   INT         NOT NULL,
+  board       VARCHAR(16) NOT NULL,
+  season_rank INT         NOT NULL,
+  user_id     INT         NULL,
+  username    VARCHAR(255) NOT NULL,
+  value       BIGINT      NOT NULL,
+  reward      VARCHAR(32) NULL,
+  PRIMARY KEY (season_id, board, season_rank),
+  FOREIGN KEY (season_id) REFERENCES seasons(id) ON DELETE CASCADE,
+  CONSTRAINT fk_season_standings_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+  INDEX idx_season_standings_user_id (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS rank_snapshots (
+  user_id     INT         NOT NULL,
+  taken_at    DATETIME(3) NOT NULL,
+  score       BIGINT      NOT NULL,
+  score_rank  INT         NOT NULL,
+  streak_rank INT         NOT NULL,
+  PRIMARY KEY (user_id, taken_at),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  INDEX idx_rank_snapshots_taken_at (taken_at)
+);
+
+CREATE TABLE IF NOT EXISTS question_memory (
+  user_id       INT         NOT NULL,
+  question_id   INT         NOT NULL,
+  repetitions   INT         NOT NULL DEFAULT 0,
+  interval_days INT         NOT NULL DEFAULT 0,
+  ease_factor   DOUBLE      NOT NULL DEFAULT 2.5,
+  lapses        INT         NOT NULL DEFAULT 0,
+  due_at        DATETIME(3) NOT NULL,
+  reviewed_at   DATETIME(3) NOT NULL,
+  PRIMARY KEY (user_id, question_id),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  INDEX idx_question_memory_user_id_due_at (user_id, due_at)
+);
@@ -5,11 +5,13 @@
 # For leaderboard tests with 3 users: seed DB first with
 #   mysql -u root -p brainbolt < scripts/seed_two_users.sql
 # Then run this script; steps [7][8] seed users 2 and 3 into Redis via one answer each.
+# Admin steps ([14], [14b]) run only when ADMIN_TOKEN is set to the server's token.
 
 set -e
 
 BASE_URL="${BASE_URL:-http://localhost:3001}"
 USER_ID="${TEST_USER_ID:-1}"
+ADMIN_TOKEN="${ADMIN_TOKEN:-}"
 
 # Optional: pretty-print JSON (no-op if jq missing)
 jq_cmd() {
@@ -40,6 +42,7 @@ echo "$body" | jq_cmd .
 # Capture questionId for answer test (optional; API returns int)
 QUESTION_ID=$(echo "$body" | jq_cmd -r '.questionId // empty')
 if [[ -z "$QUESTION_ID" ]]; then QUESTION_ID=1; fi
+QUESTION_TOKEN=$(echo "$body" | jq_cmd -r '.questionToken // empty')
 
 # --- Quiz: next question without userId (expect 400) ---
 echo ""
@@ -58,7 +61,7 @@ echo ""
 echo "[3] POST /v1/quiz/answer (correct answer for $QUESTION_ID)"
 resp=$(curl -s -w "\n%{http_code}" -X POST "$BASE_URL/v1/quiz/answer" \
   -H "Content-Type: application/json" \
-  -d "{\"userId\":$USER_ID,\"questionId\":$QUESTION_ID,\"answer\":\"B\"}")
+  -d "{\"userId\":$USER_ID,\"questionId\":$QUESTION_ID,\"answer\":\"B\",\"questionToken\":\"$QUESTION_TOKEN\"}")
 body=$(echo "$resp" | sed '$d')
 code=$(echo "$resp" | tail -n 1)
 echo "HTTP $code"
@@ -68,14 +71,18 @@ if [[ "$code" != "200" ]]; then
   exit 1
 fi
 echo "$body" | jq_cmd .
+if ! echo "$body" | grep -q '"correctAnswer"'; then
+  echo "FAIL: expected correctAnswer in response"
+  exit 1
+fi
 echo "OK"
 
 # --- Quiz: submit same answer again (duplicate — expect 204 No Content, ignored) ---
 echo ""
-echo "[4] POST /v1/quiz/answer (same question again - duplicate ignored)"
+echo "[4] POST /v1/quiz/answer (same question token again - duplicate ignored)"
 code=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$BASE_URL/v1/quiz/answer" \
   -H "Content-Type: application/json" \
-  -d "{\"userId\":$USER_ID,\"questionId\":$QUESTION_ID,\"answer\":\"B\"}")
+  -d "{\"userId\":$USER_ID,\"questionId\":$QUESTION_ID,\"answer\":\"B\",\"questionToken\":\"$QUESTION_TOKEN\"}")
 echo "HTTP $code"
 if [[ "$code" != "204" ]]; then
   echo "FAIL: expected 204 No Content"
@@ -83,6 +90,19 @@ if [[ "$code" != "204" ]]; then
 fi
 echo "OK (duplicate ignored)"
 
+# --- Quiz: answer without a served question token (expect 400 / 403) ---
+echo ""
+echo "[4b] POST /v1/quiz/answer (forged questionToken - expect 403)"
+code=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$BASE_URL/v1/quiz/answer" \
+  -H "Content-Type: application/json" \
+  -d "{\"userId\":$USER_ID,\"questionId\":$QUESTION_ID,\"answer\":\"B\",\"questionToken\":\"forged.token\"}")
+echo "HTTP $code"
+if [[ "$code" != "403" ]]; then
+  echo "FAIL: expected 403"
+  exit 1
+fi
+echo "OK"
+
 # --- Quiz: submit answer with missing fields (expect 400) ---
 echo ""
 echo "[5] POST /v1/quiz/answer (missing fields - expect 400)"
@@ -111,6 +131,43 @@ fi
 echo "$body" | jq_cmd .
 echo "OK"
 
+# --- Quiz: review of recent mistakes ---
+echo ""
+echo "[6b] GET /v1/quiz/review?userId=$USER_ID"
+resp=$(curl -s -w "\n%{http_code}" "$BASE_URL/v1/quiz/review?userId=$USER_ID")
+body=$(echo "$resp" | sed '$d')
+code=$(echo "$resp" | tail -n 1)
+echo "HTTP $code"
+if [[ "$code" != "200" ]]; then
+  echo "Response body: $body"
+  echo "FAIL: expected 200"
+  exit 1
+fi
+if ! echo "$body" | grep -q '"mistakes"'; then
+  echo "FAIL: expected mistakes in response"
+  exit 1
+fi
+echo "$body" | jq_cmd .
+echo "OK"
+
+echo ""
+echo "[6c] GET /v1/quiz/next?userId=$USER_ID&mode=practice"
+resp=$(curl -s -w "\n%{http_code}" "$BASE_URL/v1/quiz/next?userId=$USER_ID&mode=practice")
+body=$(echo "$resp" | sed '$d')
+code=$(echo "$resp" | tail -n 1)
+echo "HTTP $code"
+if [[ "$code" != "200" ]]; then
+  echo "Response body: $body"
+  echo "FAIL: expected 200"
+  exit 1
+fi
+if ! echo "$body" | grep -q '"review"'; then
+  echo "FAIL: expected review in response"
+  exit 1
+fi
+echo "$body" | jq_cmd .
+echo "OK"
+
 # --- Optional: trigger activity for users 2 and 3 so they appear in Redis leaderboard ---
 # If you seeded DB with: mysql -u root -p brainbolt < scripts/seed_two_users.sql
 # then one next+answer per user syncs them to the leaderboard.
@@ -120,9 +177,10 @@ resp=$(curl -s -w "\n%{http_code}" "$BASE_URL/v1/quiz/next?userId=2")
 body=$(echo "$resp" | sed '$d')
 code=$(echo "$resp" | tail -n 1)
 Q2=$(echo "$body" | jq_cmd -r '.questionId // 1')
+T2=$(echo "$body" | jq_cmd -r '.questionToken // empty')
 if [[ "$code" == "200" ]]; then
   curl -s -o /dev/null -X POST "$BASE_URL/v1/quiz/answer" -H "Content-Type: application/json" \
-    -d "{\"userId\":2,\"questionId\":$Q2,\"answer\":\"A\"}"
+    -d "{\"userId\":2,\"questionId\":$Q2,\"answer\":\"A\",\"questionToken\":\"$T2\"}"
 fi
 echo "HTTP $code (optional)"
 
@@ -132,9 +190,10 @@ resp=$(curl -s -w "\n%{http_code}" "$BASE_URL/v1/quiz/next?userId=3")
 body=$(echo "$resp" | sed '$d')
 code=$(echo "$resp" | tail -n 1)
 Q3=$(echo "$body" | jq_cmd -r '.questionId // 1')
+T3=$(echo "$body" | jq_cmd -r '.questionToken // empty')
 if [[ "$code" == "200" ]]; then
   curl -s -o /dev/null -X POST "$BASE_URL/v1/quiz/answer" -H "Content-Type: application/json" \
-    -d "{\"userId\":3,\"questionId\":$Q3,\"answer\":\"A\"}"
+    -d "{\"userId\":3,\"questionId\":$Q3,\"answer\":\"A\",\"questionToken\":\"$T3\"}"
 fi
 echo "HTTP $code (optional)"
 
@@ -166,6 +225,10 @@ if command -v jq &>/dev/null; then
       echo "FAIL: each leaderboard entry must have userId, score, rank"
       exit 1
     fi
+    if ! jq -e ".[$i] | has(\"username\")" <<< "$body" &>/dev/null; then
+      echo "FAIL: each leaderboard entry must carry the player's username"
+      exit 1
+    fi
     rank=$(jq -r ".[$i].rank" <<< "$body")
     want_rank=$((i + 1))
     if [[ "$rank" != "$want_rank" ]]; then
@@ -234,6 +297,123 @@ else
   echo "OK"
 fi
 
+# --- Leaderboard periods ---
+echo ""
+echo "[10b] GET /v1/leaderboard/score?period=daily|weekly|monthly (expect 200), period=yearly (expect 400)"
+for period in daily weekly monthly; do
+  code=$(curl -s -o /dev/null -w "%{http_code}" "$BASE_URL/v1/leaderboard/score?period=$period&limit=5")
+  if [[ "$code" != "200" ]]; then
+    echo "FAIL: period=$period expected 200, got $code"
+    exit 1
+  fi
+done
+code=$(curl -s -o /dev/null -w "%{http_code}" "$BASE_URL/v1/leaderboard/streak?period=yearly")
+if [[ "$code" != "400" ]]; then
+  echo "FAIL: unknown period expected 400, got $code"
+  exit 1
+fi
+echo "OK"
+
+# --- Leaderboard paging and around-me ---
+echo ""
+echo "[10c] GET /v1/leaderboard/score?aroundUserId=$USER_ID&radius=2 and ?offset=1&limit=1"
+resp=$(curl -s -w "\n%{http_code}" "$BASE_URL/v1/leaderboard/score?aroundUserId=$USER_ID&radius=2")
+body=$(echo "$resp" | sed '$d')
+code=$(echo "$resp" | tail -n 1)
+echo "HTTP $code"
+if [[ "$code" != "200" ]]; then
+  echo "Response body: $body"
+  echo "FAIL: expected 200"
+  exit 1
+fi
+echo "$body" | jq_cmd .
+if command -v jq &>/dev/null; then
+  if ! jq -e --argjson id "$USER_ID" 'any(.[]; .userId == $id)' <<< "$body" &>/dev/null; then
+    echo "FAIL: around-me page must contain user $USER_ID"
+    exit 1
+  fi
+  page=$(curl -s "$BASE_URL/v1/leaderboard/score?offset=1&limit=1")
+  if [[ "$(jq 'length' <<< "$page")" == "1" && "$(jq -r '.[0].rank' <<< "$page")" != "2" ]]; then
+    echo "FAIL: offset=1 page should start at rank 2"
+    exit 1
+  fi
+fi
+echo "OK"
+
+# --- Seasons ---
+echo ""
+echo "[10d] GET /v1/leaderboard/seasons, /seasons/{id} (expect 200) and /seasons/999999 (expect 404)"
+resp=$(curl -s -w "\n%{http_code}" "$BASE_URL/v1/leaderboard/seasons")
+body=$(echo "$resp" | sed '$d')
+code=$(echo "$resp" | tail -n 1)
+echo "HTTP $code"
+if [[ "$code" != "200" ]]; then
+  echo "Response body: $body"
+  echo "FAIL: expected 200"
+  exit 1
+fi
+echo "$body" | jq_cmd .
+if command -v jq &>/dev/null; then
+  season_id=$(jq -r '.seasons[0].id // empty' <<< "$body")
+  if [[ -n "$season_id" ]]; then
+    code=$(curl -s -o /dev/null -w "%{http_code}" "$BASE_URL/v1/leaderboard/seasons/$season_id?board=streak&limit=5")
+    if [[ "$code" != "200" ]]; then
+      echo "FAIL: season $season_id expected 200, got $code"
+      exit 1
+    fi
+  fi
+fi
+code=$(curl -s -o /dev/null -w "%{http_code}" "$BASE_URL/v1/leaderboard/seasons/999999")
+if [[ "$code" != "404" ]]; then
+  echo "FAIL: unknown season expected 404, got $code"
+  exit 1
+fi
+echo "OK"
+
+# --- Accuracy, current streak and difficulty boards ---
+echo ""
+echo "[10e] GET /v1/leaderboard/accuracy|current-streak|difficulty (expect 200), period=daily (expect 400)"
+for board in accuracy current-streak difficulty; do
+  code=$(curl -s -o /dev/null -w "%{http_code}" "$BASE_URL/v1/leaderboard/$board?limit=5")
+  if [[ "$code" != "200" ]]; then
+    echo "FAIL: $board expected 200, got $code"
+    exit 1
+  fi
+done
+code=$(curl -s -o /dev/null -w "%{http_code}" "$BASE_URL/v1/leaderboard/difficulty?period=daily")
+if [[ "$code" != "400" ]]; then
+  echo "FAIL: difficulty?period=daily expected 400, got $code"
+  exit 1
+fi
+echo "OK"
+
+# --- Live leaderboard stream (SSE) ---
+echo ""
+echo "[10f] GET /v1/leaderboard/stream?board=score&limit=3&userId=$USER_ID (expect a top event)"
+events=$(curl -s -N --max-time 3 "$BASE_URL/v1/leaderboard/stream?board=score&limit=3&userId=$USER_ID" || true)
+if ! grep -q "^event: top" <<< "$events"; then
+  echo "Stream output: $events"
+  echo "FAIL: expected a top event within 3s"
+  exit 1
+fi
+grep -m 2 "^data:" <<< "$events"
+echo "OK"
+
+# --- Rank history ---
+echo ""
+echo "[10g] GET /v1/users/$USER_ID/rank-history (expect 200), from after to (expect 400)"
+code=$(curl -s -o /dev/null -w "%{http_code}" "$BASE_URL/v1/users/$USER_ID/rank-history")
+if [[ "$code" != "200" ]]; then
+  echo "FAIL: expected 200, got $code"
+  exit 1
+fi
+code=$(curl -s -o /dev/null -w "%{http_code}" "$BASE_URL/v1/users/$USER_ID/rank-history?from=2026-02-01T00:00:00Z&to=2026-01-01T00:00:00Z")
+if [[ "$code" != "400" ]]; then
+  echo "FAIL: expected 400, got $code"
+  exit 1
+fi
+echo "OK"
+
 # --- Metrics without userId (expect 400) ---
 echo ""
 echo "[11] GET /v1/quiz/metrics (no userId - expect 400)"
@@ -245,6 +425,27 @@ if [[ "$code" != "400" ]]; then
 fi
 echo "OK"
 
+# --- Quiz: categories and category-filtered questions ---
+echo ""
+echo "[11b] GET /v1/quiz/categories, /v1/quiz/next?category=math (expect a math question), category=no such (expect 400)"
+code=$(curl -s -o /dev/null -w "%{http_code}" "$BASE_URL/v1/quiz/categories")
+if [[ "$code" != "200" ]]; then
+  echo "FAIL: categories expected 200, got $code"
+  exit 1
+fi
+body=$(curl -s "$BASE_URL/v1/quiz/next?userId=$USER_ID&category=math")
+if ! grep -q '"category":"math"' <<< "$body"; then
+  echo "Response body: $body"
+  echo "FAIL: expected a math question"
+  exit 1
+fi
+code=$(curl -s -o /dev/null -w "%{http_code}" "$BASE_URL/v1/quiz/next?userId=$USER_ID&category=no%20such")
+if [[ "$code" != "400" ]]; then
+  echo "FAIL: expected 400, got $code"
+  exit 1
+fi
+echo "OK"
+
 # --- Quiz: verify question tracking (no repeats) ---
 echo ""
 echo "[12] GET /v1/quiz/next (multiple calls - verify no immediate repeats)"
@@ -284,6 +485,166 @@ else
   echo "  OK: Some repeats detected (expected if difficulty pool exhausted)"
 fi
 
+# --- Users: create, fetch, rename, delete ---
+echo ""
+echo "[13] POST/GET/PATCH/DELETE /v1/users"
+TEST_USERNAME="apitest_$(date +%s)"
+resp=$(curl -s -w "\n%{http_code}" -X POST "$BASE_URL/v1/users" \
+  -H "Content-Type: application/json" \
+  -d "{\"username\":\"$TEST_USERNAME\"}")
+body=$(echo "$resp" | sed '$d')
+code=$(echo "$resp" | tail -n 1)
+echo "  create: HTTP $code"
+if [[ "$code" != "201" ]]; then
+  echo "Response body: $body"
+  echo "FAIL: expected 201"
+  exit 1
+fi
+NEW_USER_ID=$(echo "$body" | jq_cmd -r '.id // empty')
+if [[ -z "$NEW_USER_ID" ]]; then
+  echo "FAIL: id missing in create response"
+  exit 1
+fi
+code=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$BASE_URL/v1/users" \
+  -H "Content-Type: application/json" \
+  -d "{\"username\":\"$TEST_USERNAME\"}")
+echo "  create duplicate: HTTP $code"
+if [[ "$code" != "409" ]]; then
+  echo "FAIL: expected 409"
+  exit 1
+fi
+code=$(curl -s -o /dev/null -w "%{http_code}" "$BASE_URL/v1/users/$NEW_USER_ID")
+echo "  get: HTTP $code"
+if [[ "$code" != "200" ]]; then
+  echo "FAIL: expected 200"
+  exit 1
+fi
+code=$(curl -s -o /dev/null -w "%{http_code}" -X PATCH "$BASE_URL/v1/users/$NEW_USER_ID" \
+  -H "Content-Type: application/json" \
+  -d "{\"username\":\"${TEST_USERNAME}_r\"}")
+echo "  rename: HTTP $code"
+if [[ "$code" != "200" ]]; then
+  echo "FAIL: expected 200"
+  exit 1
+fi
+code=$(curl -s -o /dev/null -w "%{http_code}" -X DELETE "$BASE_URL/v1/users/$NEW_USER_ID")
+echo "  delete: HTTP $code"
+if [[ "$code" != "204" ]]; then
+  echo "FAIL: expected 204"
+  exit 1
+fi
+code=$(curl -s -o /dev/null -w "%{http_code}" "$BASE_URL/v1/users/$NEW_USER_ID")
+echo "  get after delete: HTTP $code"
+if [[ "$code" != "404" ]]; then
+  echo "FAIL: expected 404"
+  exit 1
+fi
+echo "OK"
+
+# --- Admin: question bank ---
+echo ""
+echo "[14] POST/GET/PUT/DELETE /v1/admin/questions"
+if [[ -z "$ADMIN_TOKEN" ]]; then
+  echo "  SKIP: ADMIN_TOKEN not set"
+else
+  AUTH="Authorization: Bearer $ADMIN_TOKEN"
+  code=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$BASE_URL/v1/admin/questions" -H "$AUTH" \
+    -H "Content-Type: application/json" \
+    -d '{"difficulty":3,"question":"What is 2 + 2?","options":["4","4","5"],"answer":"Z"}')
+  echo "  create invalid: HTTP $code"
+  if [[ "$code" != "400" ]]; then
+    echo "FAIL: expected 400"
+    exit 1
+  fi
+  code=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$BASE_URL/v1/admin/questions" -H "$AUTH" \
+    -H "Content-Type: application/json" \
+    -d '{"type":"numeric","difficulty":3,"question":"What is pi? (api test)","options":["3"],"answer":"pi"}')
+  echo "  create invalid numeric: HTTP $code"
+  if [[ "$code" != "400" ]]; then
+    echo "FAIL: expected 400"
+    exit 1
+  fi
+  resp=$(curl -s -w "\n%{http_code}" -X POST "$BASE_URL/v1/admin/questions" -H "$AUTH" \
+    -H "Content-Type: application/json" \
+    -d '{"difficulty":3,"question":"What is 2 + 2? (api test)","options":["3","4","5"],"answer":"4","category":"math"}')
+  body=$(echo "$resp" | sed '$d')
+  code=$(echo "$resp" | tail -n 1)
+  echo "  create: HTTP $code"
+  if [[ "$code" != "201" ]]; then
+    echo "Response body: $body"
+    echo "FAIL: expected 201"
+    exit 1
+  fi
+  NEW_QUESTION_ID=$(echo "$body" | jq_cmd -r '.id // empty')
+  code=$(curl -s -o /dev/null -w "%{http_code}" -X PUT "$BASE_URL/v1/admin/questions/$NEW_QUESTION_ID" -H "$AUTH" \
+    -H "Content-Type: application/json" \
+    -d '{"difficulty":4,"question":"What is 2 + 3? (api test)","options":["3","4","5"],"answer":"C","category":"math"}')
+  echo "  update: HTTP $code"
+  if [[ "$code" != "200" ]]; then
+    echo "FAIL: expected 200"
+    exit 1
+  fi
+  code=$(curl -s -o /dev/null -w "%{http_code}" -X DELETE "$BASE_URL/v1/admin/questions/$NEW_QUESTION_ID" -H "$AUTH")
+  echo "  retire: HTTP $code"
+  if [[ "$code" != "200" ]]; then
+    echo "FAIL: expected 200"
+    exit 1
+  fi
+  code=$(curl -s -o /dev/null -w "%{http_code}" "$BASE_URL/v1/admin/questions/999999" -H "$AUTH")
+  echo "  get missing: HTTP $code"
+  if [[ "$code" != "404" ]]; then
+    echo "FAIL: expected 404"
+    exit 1
+  fi
+  echo "OK"
+fi
+
+echo ""
+echo "[14b] GET /v1/admin/questions/export, POST /v1/admin/questions/import?dryRun=true (csv, gift, json)"
+if [[ -z "$ADMIN_TOKEN" ]]; then
+  echo "  SKIP: ADMIN_TOKEN not set"
+else
+  AUTH="Authorization: Bearer $ADMIN_TOKEN"
+  export_file=$(mktemp)
+  code=$(curl -s -o "$export_file" -w "%{http_code}" "$BASE_URL/v1/admin/questions/export?format=csv" -H "$AUTH")
+  echo "  export csv: HTTP $code"
+  if [[ "$code" != "200" ]] || ! head -n 1 "$export_file" | grep -q "question"; then
+    rm -f "$export_file"
+    echo "FAIL: expected 200 with a CSV header"
+    exit 1
+  fi
+  # Re-importing the bank's own export finds only duplicates.
+  resp=$(curl -s -w "\n%{http_code}" -X POST "$BASE_URL/v1/admin/questions/import?format=csv&dryRun=true" -H "$AUTH" \
+    -H "Content-Type: text/csv" --data-binary "@$export_file")
+  rm -f "$export_file"
+  body=$(echo "$resp" | sed '$d')
+  code=$(echo "$resp" | tail -n 1)
+  echo "  re-import dry run: HTTP $code"
+  if [[ "$code" != "200" ]] || ! echo "$body" | grep -q '"new":0'; then
+    echo "Response body: $body"
+    echo "FAIL: expected 200 with no new questions"
+    exit 1
+  fi
+  resp=$(curl -s -w "\n%{http_code}" -X POST "$BASE_URL/v1/admin/questions/import?format=gift&dryRun=true" -H "$AUTH" \
+    -H "Content-Type: text/plain" --data-binary $'// [tag:difficulty:2]\nIs 2 + 2 four? (api test) {T}\n\nWrite an essay (api test) {}\n')
+  body=$(echo "$resp" | sed '$d')
+  code=$(echo "$resp" | tail -n 1)
+  echo "  gift dry run: HTTP $code"
+  if [[ "$code" != "200" ]] || ! echo "$body" | grep -q 'essay questions are not supported'; then
+    echo "Response body: $body"
+    echo "FAIL: expected 200 with the essay question skipped"
+    exit 1
+  fi
+  code=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$BASE_URL/v1/admin/questions/import?dryRun=true" -H "$AUTH" \
+    -H "Content-Type: application/json" -d '[{"difficulty":3,"question":"Bad (api test)","options":["x"],"answer":"A"}]')
+  echo "  import invalid: HTTP $code"
+  if [[ "$code" != "422" ]]; then
+    echo "FAIL: expected 422"
+    exit 1
+  fi
+  echo "OK"
+fi
+
 echo ""
 echo "=========================================="
 echo "All API tests passed."